@@ -0,0 +1,125 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/JPKribs/FinGuard/wireguard"
+)
+
+// MARK: handleTunnelCapture
+func (a *APIServer) handleTunnelCapture(w http.ResponseWriter, r *http.Request, tunnelName string) {
+	if r.Method != http.MethodGet {
+		a.respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	tunnelConfig := a.cfg.GetTunnel(tunnelName)
+	if tunnelConfig == nil {
+		a.respondWithError(w, http.StatusNotFound, "Tunnel not found")
+		return
+	}
+
+	opts, err := parseCaptureOptions(r)
+	if err != nil {
+		a.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !a.tunnelManager.TunnelRunning(tunnelConfig.Name) {
+		a.respondWithError(w, http.StatusConflict, "Tunnel is not running")
+		return
+	}
+
+	filename := fmt.Sprintf("%s-%s.pcapng", tunnelConfig.Name, time.Now().UTC().Format("20060102-150405"))
+	stream := newCaptureStream(w, filename)
+
+	// Captures outlast the management server's write timeout
+	if err := stream.controller.SetWriteDeadline(time.Time{}); err != nil {
+		a.logger.Debug("Failed to clear write deadline for capture", "tunnel", tunnelConfig.Name, "error", err)
+	}
+
+	err = a.tunnelManager.CaptureTunnel(r.Context(), tunnelConfig.Name, opts, stream)
+	if err == nil || stream.wroteHeader {
+		return
+	}
+
+	switch {
+	case errors.Is(err, wireguard.ErrCaptureUnsupported):
+		a.respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, wireguard.ErrCaptureInProgress):
+		a.respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		a.respondWithError(w, http.StatusInternalServerError, "Capture failed: "+err.Error())
+	}
+}
+
+// MARK: parseCaptureOptions
+func parseCaptureOptions(r *http.Request) (wireguard.CaptureOptions, error) {
+	var opts wireguard.CaptureOptions
+	query := r.URL.Query()
+
+	if value := query.Get("duration"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			return opts, fmt.Errorf("invalid duration %q: expected a number of seconds", value)
+		}
+		opts.Duration = time.Duration(seconds) * time.Second
+	}
+
+	if value := query.Get("count"); value != "" {
+		count, err := strconv.Atoi(value)
+		if err != nil || count <= 0 {
+			return opts, fmt.Errorf("invalid count %q", value)
+		}
+		opts.MaxPackets = count
+	}
+
+	if value := query.Get("host"); value != "" {
+		host, err := netip.ParseAddr(value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid host %q: expected an IP address", value)
+		}
+		opts.Host = host.Unmap()
+	}
+
+	if value := query.Get("port"); value != "" {
+		port, err := strconv.ParseUint(value, 10, 16)
+		if err != nil || port == 0 {
+			return opts, fmt.Errorf("invalid port %q", value)
+		}
+		opts.Port = uint16(port)
+	}
+
+	return opts, nil
+}
+
+// MARK: newCaptureStream
+func newCaptureStream(w http.ResponseWriter, filename string) *captureStream {
+	return &captureStream{
+		w:          w,
+		controller: http.NewResponseController(w),
+		filename:   filename,
+	}
+}
+
+// MARK: Write
+func (s *captureStream) Write(p []byte) (int, error) {
+	// Headers are deferred to the first write so setup errors can still be answered as JSON
+	if !s.wroteHeader {
+		s.w.Header().Set("Content-Type", "application/x-pcapng")
+		s.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", s.filename))
+		s.w.WriteHeader(http.StatusOK)
+		s.wroteHeader = true
+	}
+	return s.w.Write(p)
+}
+
+// MARK: Flush
+func (s *captureStream) Flush() error {
+	return s.controller.Flush()
+}
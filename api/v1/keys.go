@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/JPKribs/FinGuard/wireguard"
+)
+
+// MARK: handleGenerateKeyPair
+func (a *APIServer) handleGenerateKeyPair(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		a.respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	privateKey, publicKey, err := wireguard.GeneratePrivateKey()
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	a.respondWithSuccess(w, "Key pair generated", KeyPairResponse{
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+	})
+}
+
+// MARK: handleGeneratePresharedKey
+func (a *APIServer) handleGeneratePresharedKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		a.respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	presharedKey, err := wireguard.GeneratePresharedKey()
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	a.respondWithSuccess(w, "Preshared key generated", PresharedKeyResponse{PresharedKey: presharedKey})
+}
+
+// MARK: handleDerivePublicKey
+func (a *APIServer) handleDerivePublicKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		a.respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req PublicKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+
+	publicKey, err := wireguard.PublicKey(req.PrivateKey)
+	if err != nil {
+		a.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	a.respondWithSuccess(w, "Public key derived", KeyPairResponse{PublicKey: publicKey})
+}
+
+// MARK: publicKeyFor
+func publicKeyFor(privateKey string) string {
+	publicKey, err := wireguard.PublicKey(privateKey)
+	if err != nil {
+		return ""
+	}
+	return publicKey
+}
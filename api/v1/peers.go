@@ -0,0 +1,294 @@
+package v1
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/JPKribs/FinGuard/config"
+	"github.com/JPKribs/FinGuard/utilities"
+	"github.com/JPKribs/FinGuard/wireguard"
+)
+
+// MARK: handleTunnelPeers
+func (a *APIServer) handleTunnelPeers(w http.ResponseWriter, r *http.Request, tunnelName, peerName string) {
+	tunnelConfig := a.cfg.GetTunnel(tunnelName)
+	if tunnelConfig == nil {
+		a.respondWithError(w, http.StatusNotFound, "Tunnel not found")
+		return
+	}
+
+	ctx := r.Context()
+
+	if peerName == "" {
+		switch r.Method {
+		case http.MethodGet:
+			a.respondWithSuccess(w, "Peers retrieved", a.peerStatuses(ctx, *tunnelConfig))
+		case http.MethodPost:
+			a.handleAddPeer(w, r, ctx, tunnelConfig.Name)
+		default:
+			a.respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.handleGetPeer(w, r, ctx, *tunnelConfig, peerName)
+	case http.MethodPut:
+		a.handleUpdatePeer(w, r, ctx, tunnelConfig.Name, peerName)
+	case http.MethodDelete:
+		a.handleRemovePeer(w, r, ctx, tunnelConfig.Name, peerName)
+	default:
+		a.respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// MARK: handleGetPeer
+func (a *APIServer) handleGetPeer(w http.ResponseWriter, r *http.Request, ctx context.Context, tunnelConfig config.TunnelConfig, peerName string) {
+	for _, status := range a.peerStatuses(ctx, tunnelConfig) {
+		if strings.EqualFold(status.Name, peerName) {
+			a.respondWithSuccess(w, "Peer retrieved", status)
+			return
+		}
+	}
+
+	a.respondWithError(w, http.StatusNotFound, "Peer not found")
+}
+
+// MARK: handleAddPeer
+func (a *APIServer) handleAddPeer(w http.ResponseWriter, r *http.Request, ctx context.Context, tunnelName string) {
+	var req PeerCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+
+	peer := a.convertPeerRequest(req)
+	tunnelConfig, ok := a.applyTunnelPeers(w, ctx, tunnelName, func(tunnel *config.TunnelConfig) error {
+		if conflict := findPeerConflict(tunnel.Peers, peer, -1); conflict != "" {
+			return &peerError{status: http.StatusConflict, message: conflict}
+		}
+		tunnel.Peers = append(tunnel.Peers, peer)
+		return nil
+	})
+	if !ok {
+		return
+	}
+
+	a.logger.Info("Peer added", "tunnel", tunnelConfig.Name, "peer", peer.Name)
+	a.respondWithSuccess(w, "Peer added", a.peerStatus(ctx, tunnelConfig, peer.Name))
+}
+
+// MARK: handleUpdatePeer
+func (a *APIServer) handleUpdatePeer(w http.ResponseWriter, r *http.Request, ctx context.Context, tunnelName, peerName string) {
+	var req PeerCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+
+	var peer config.PeerConfig
+	tunnelConfig, ok := a.applyTunnelPeers(w, ctx, tunnelName, func(tunnel *config.TunnelConfig) error {
+		index := findPeerIndex(tunnel.Peers, peerName)
+		if index < 0 {
+			return &peerError{status: http.StatusNotFound, message: "Peer not found"}
+		}
+
+		update := req
+		if update.Name == "" {
+			update.Name = tunnel.Peers[index].Name
+		}
+
+		peer = a.convertPeerRequest(update)
+		if conflict := findPeerConflict(tunnel.Peers, peer, index); conflict != "" {
+			return &peerError{status: http.StatusConflict, message: conflict}
+		}
+		tunnel.Peers[index] = peer
+		return nil
+	})
+	if !ok {
+		return
+	}
+
+	a.logger.Info("Peer updated", "tunnel", tunnelConfig.Name, "peer", peer.Name)
+	a.respondWithSuccess(w, "Peer updated", a.peerStatus(ctx, tunnelConfig, peer.Name))
+}
+
+// MARK: handleRemovePeer
+func (a *APIServer) handleRemovePeer(w http.ResponseWriter, r *http.Request, ctx context.Context, tunnelName, peerName string) {
+	tunnelConfig, ok := a.applyTunnelPeers(w, ctx, tunnelName, func(tunnel *config.TunnelConfig) error {
+		index := findPeerIndex(tunnel.Peers, peerName)
+		if index < 0 {
+			return &peerError{status: http.StatusNotFound, message: "Peer not found"}
+		}
+		tunnel.Peers = slices.Delete(tunnel.Peers, index, index+1)
+		return nil
+	})
+	if !ok {
+		return
+	}
+
+	a.logger.Info("Peer removed", "tunnel", tunnelConfig.Name, "peer", peerName)
+	a.respondWithSuccess(w, "Peer removed", nil)
+}
+
+// MARK: handlePeerProvision
+func (a *APIServer) handlePeerProvision(w http.ResponseWriter, r *http.Request, tunnelName string) {
+	if r.Method != http.MethodPost {
+		a.respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if a.cfg.GetTunnel(tunnelName) == nil {
+		a.respondWithError(w, http.StatusNotFound, "Tunnel not found")
+		return
+	}
+
+	var req PeerProvisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+
+	if req.Name == "" {
+		a.respondWithError(w, http.StatusBadRequest, "Peer name required")
+		return
+	}
+
+	// Allocating inside the locked change keeps concurrent provisions from taking the same address
+	var peer config.PeerConfig
+	var clientConfig string
+	ctx := r.Context()
+	updated, ok := a.applyTunnelPeers(w, ctx, tunnelName, func(tunnel *config.TunnelConfig) error {
+		if findPeerIndex(tunnel.Peers, req.Name) >= 0 {
+			return &peerError{status: http.StatusConflict, message: "Peer " + req.Name + " already exists"}
+		}
+
+		var err error
+		peer, clientConfig, err = wireguard.ProvisionPeer(*tunnel, req.Name)
+		if err != nil {
+			return &peerError{status: http.StatusBadRequest, message: err.Error()}
+		}
+		tunnel.Peers = append(tunnel.Peers, peer)
+		return nil
+	})
+	if !ok {
+		return
+	}
+
+	a.logger.Info("Peer provisioned", "tunnel", updated.Name, "peer", peer.Name, "address", peer.AllowedIPs[0])
+
+	qrPNG, err := utilities.QRCodePNG(clientConfig)
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Failed to render QR code: "+err.Error())
+		return
+	}
+
+	qrText, err := utilities.QRCodeText(clientConfig)
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Failed to render QR code: "+err.Error())
+		return
+	}
+
+	a.respondWithSuccess(w, "Peer provisioned", PeerProvisionResponse{
+		Peer:         a.peerStatus(ctx, updated, peer.Name),
+		ClientConfig: clientConfig,
+		QRCode:       "data:image/png;base64," + base64.StdEncoding.EncodeToString(qrPNG),
+		QRText:       qrText,
+	})
+}
+
+// MARK: applyTunnelPeers
+func (a *APIServer) applyTunnelPeers(w http.ResponseWriter, ctx context.Context, tunnelName string, change func(*config.TunnelConfig) error) (config.TunnelConfig, bool) {
+	// The change runs under the config lock so concurrent peer requests cannot drop each other's peers
+	if err := a.cfg.UpdateTunnelFunc(tunnelName, change); err != nil {
+		var reqErr *peerError
+		if errors.As(err, &reqErr) {
+			a.respondWithError(w, reqErr.status, reqErr.message)
+		} else {
+			a.respondWithError(w, http.StatusBadRequest, err.Error())
+		}
+		return config.TunnelConfig{}, false
+	}
+
+	tunnelConfig := a.cfg.GetTunnel(tunnelName)
+	if tunnelConfig == nil {
+		a.respondWithError(w, http.StatusNotFound, "Tunnel not found")
+		return config.TunnelConfig{}, false
+	}
+
+	if err := a.updateRunningTunnel(ctx, *tunnelConfig); err != nil {
+		a.logger.Error("Failed to apply peer change to running tunnel", "tunnel", tunnelConfig.Name, "error", err)
+		a.respondWithError(w, http.StatusInternalServerError, "Peer saved but not applied: "+err.Error())
+		return config.TunnelConfig{}, false
+	}
+
+	return *tunnelConfig, true
+}
+
+// MARK: Error
+func (e *peerError) Error() string {
+	return e.message
+}
+
+// MARK: peerStatuses
+func (a *APIServer) peerStatuses(ctx context.Context, tunnelConfig config.TunnelConfig) []wireguard.PeerStatus {
+	if status, err := a.tunnelManager.Status(ctx, tunnelConfig.Name); err == nil && status.PeerStats != nil {
+		return status.PeerStats
+	}
+
+	statuses := make([]wireguard.PeerStatus, 0, len(tunnelConfig.Peers))
+	for _, peer := range tunnelConfig.Peers {
+		statuses = append(statuses, wireguard.PeerStatus{
+			Name:                peer.Name,
+			PublicKey:           peer.PublicKey,
+			Endpoint:            peer.Endpoint,
+			AllowedIPs:          peer.AllowedIPs,
+			PersistentKeepalive: peer.PersistentKeepaliveInt,
+			Health:              wireguard.PeerHealthUnknown,
+		})
+	}
+
+	return statuses
+}
+
+// MARK: peerStatus
+func (a *APIServer) peerStatus(ctx context.Context, tunnelConfig config.TunnelConfig, peerName string) *wireguard.PeerStatus {
+	for _, status := range a.peerStatuses(ctx, tunnelConfig) {
+		if strings.EqualFold(status.Name, peerName) {
+			return &status
+		}
+	}
+	return nil
+}
+
+// MARK: findPeerIndex
+func findPeerIndex(peers []config.PeerConfig, name string) int {
+	for i, peer := range peers {
+		if strings.EqualFold(peer.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// MARK: findPeerConflict
+func findPeerConflict(peers []config.PeerConfig, peer config.PeerConfig, skip int) string {
+	for i, existing := range peers {
+		if i == skip {
+			continue
+		}
+		if strings.EqualFold(existing.Name, peer.Name) {
+			return "Peer " + peer.Name + " already exists"
+		}
+		if strings.TrimSpace(existing.PublicKey) == strings.TrimSpace(peer.PublicKey) {
+			return "Peer with this public key already exists as " + existing.Name
+		}
+	}
+	return ""
+}
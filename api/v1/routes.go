@@ -6,6 +6,7 @@ import (
 
 	"github.com/JPKribs/FinGuard/config"
 	"github.com/JPKribs/FinGuard/discovery"
+	"github.com/JPKribs/FinGuard/failover"
 	"github.com/JPKribs/FinGuard/internal"
 	"github.com/JPKribs/FinGuard/mdns"
 	"github.com/JPKribs/FinGuard/proxy"
@@ -19,6 +20,7 @@ func NewAPIServer(
 	cfg *config.Config,
 	proxyServer *proxy.Server,
 	tunnelManager wireguard.TunnelManager,
+	failoverController *failover.Controller,
 	discoveryManager *mdns.Discovery,
 	jellyfinBroadcaster *discovery.JellyfinBroadcaster,
 	logger *internal.Logger,
@@ -28,6 +30,7 @@ func NewAPIServer(
 		cfg:                 cfg,
 		proxyServer:         proxyServer,
 		tunnelManager:       tunnelManager,
+		failoverController:  failoverController,
 		discoveryManager:    discoveryManager,
 		jellyfinBroadcaster: jellyfinBroadcaster,
 		logger:              logger,
@@ -48,6 +51,9 @@ func (a *APIServer) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/tunnels", a.authMiddleware(a.handleTunnels))
 	mux.HandleFunc("/api/v1/tunnels/", a.authMiddleware(a.handleTunnelByName))
 	mux.HandleFunc("/api/v1/tunnels/restart/", a.authMiddleware(a.handleTunnelRestart))
+	mux.HandleFunc("/api/v1/keys/generate", a.authMiddleware(a.handleGenerateKeyPair))
+	mux.HandleFunc("/api/v1/keys/preshared", a.authMiddleware(a.handleGeneratePresharedKey))
+	mux.HandleFunc("/api/v1/keys/public", a.authMiddleware(a.handleDerivePublicKey))
 	mux.HandleFunc("/api/v1/system/restart", a.authMiddleware(a.handleSystemRestart))
 	mux.HandleFunc("/api/v1/system/shutdown", a.authMiddleware(a.handleSystemShutdown))
 	mux.HandleFunc("/api/v1/status", a.authMiddleware(a.handleStatus))
@@ -57,3 +63,23 @@ func (a *APIServer) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/update/apply", a.authMiddleware(a.handleUpdateApply))
 	mux.HandleFunc("/api/v1/update/config", a.authMiddleware(a.handleUpdateConfig))
 }
+
+// MARK: isLifecycleAction
+func isLifecycleAction(action string) bool {
+	switch action {
+	case "start", "stop", "enable", "disable":
+		return true
+	}
+	return false
+}
+
+// MARK: lifecycleActionPastTense
+func lifecycleActionPastTense(action string) string {
+	switch action {
+	case "start":
+		return "started"
+	case "stop":
+		return "stopped"
+	}
+	return action + "d"
+}
@@ -4,9 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net"
 	"net/http"
-	"net/url"
 	"strings"
 	"time"
 
@@ -33,6 +31,15 @@ func (a *APIServer) handleServiceByName(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if parts := strings.SplitN(serviceName, "/", 2); len(parts) == 2 {
+		if !isLifecycleAction(parts[1]) {
+			a.respondWithError(w, http.StatusNotFound, "Not found")
+			return
+		}
+		a.handleServiceAction(w, r, parts[0], parts[1])
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		a.handleGetService(w, r, serviceName)
@@ -46,24 +53,26 @@ func (a *APIServer) handleServiceByName(w http.ResponseWriter, r *http.Request)
 // MARK: handleListServices
 func (a *APIServer) handleListServices(w http.ResponseWriter, r *http.Request) {
 	services := a.proxyServer.ListServices()
-	statusList := make([]ServiceStatusResponse, 0, len(services))
+	configured := a.cfg.ServiceList()
+	statusList := make([]ServiceStatusResponse, 0, len(configured))
+	listed := make(map[string]bool, len(services))
 
 	for _, svc := range services {
-		status := "unknown"
-		if _, err := a.proxyServer.GetServiceStatus(svc.Name); err == nil {
-			status = "running"
+		response := a.serviceResponse(svc, "unknown")
+		if service, err := a.proxyServer.GetServiceStatus(svc.Name); err == nil {
+			response.Status = "running"
+			response.UpstreamHealth = service.UpstreamStatuses()
 		}
 
-		statusList = append(statusList, ServiceStatusResponse{
-			Name:        svc.Name,
-			Upstream:    svc.Upstream,
-			Status:      status,
-			Tunnel:      svc.Tunnel,
-			Jellyfin:    svc.Jellyfin,
-			Websocket:   svc.Websocket,
-			Default:     svc.Default,
-			PublishMDNS: svc.PublishMDNS,
-		})
+		listed[strings.ToLower(svc.Name)] = true
+		statusList = append(statusList, response)
+	}
+
+	// Stopped and disabled services are not in the proxy but keep their configuration
+	for _, svc := range configured {
+		if !listed[strings.ToLower(svc.Name)] {
+			statusList = append(statusList, a.serviceResponse(svc, inactiveServiceStatus(svc)))
+		}
 	}
 
 	a.respondWithSuccess(w, "Services retrieved", statusList)
@@ -78,13 +87,22 @@ func (a *APIServer) handleAddService(w http.ResponseWriter, r *http.Request) {
 	}
 
 	serviceConfig := config.ServiceConfig{
-		Name:        req.Name,
-		Upstream:    req.Upstream,
-		Tunnel:      req.Tunnel,
-		Jellyfin:    req.Jellyfin,
-		Websocket:   req.Websocket,
-		Default:     req.Default,
-		PublishMDNS: req.PublishMDNS,
+		Name:           req.Name,
+		Upstream:       req.Upstream,
+		Upstreams:      req.Upstreams,
+		LoadBalancing:  req.LoadBalancing,
+		StickySessions: req.StickySessions,
+		Hosts:          req.Hosts,
+		PathPrefix:     req.PathPrefix,
+		StripPrefix:    req.StripPrefix,
+		Tunnel:         req.Tunnel,
+		BackupTunnels:  req.BackupTunnels,
+		RequireTunnel:  req.RequireTunnel,
+		Jellyfin:       req.Jellyfin,
+		Websocket:      req.Websocket,
+		Default:        req.Default,
+		PublishMDNS:    req.PublishMDNS,
+		TLS:            req.TLS,
 	}
 
 	if err := a.validateServiceConfig(serviceConfig); err != nil {
@@ -103,19 +121,20 @@ func (a *APIServer) handleAddService(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var tunnelToUpdate *config.TunnelConfig
-	if serviceConfig.Tunnel != "" {
-		if err := a.addServiceRouteToTunnel(serviceConfig); err != nil {
+	activeTunnel := a.activeTunnelFor(serviceConfig)
+	if activeTunnel != "" {
+		if err := a.addServiceRouteToTunnel(serviceConfig, activeTunnel); err != nil {
 			a.cfg.RemoveService(serviceConfig.Name)
 			a.respondWithError(w, http.StatusInternalServerError, "Failed to add route to tunnel: "+err.Error())
 			return
 		}
-		tunnelToUpdate = a.cfg.GetTunnel(serviceConfig.Tunnel)
+		tunnelToUpdate = a.cfg.GetTunnel(activeTunnel)
 	}
 
 	if err := a.proxyServer.AddService(serviceConfig); err != nil {
 		a.cfg.RemoveService(serviceConfig.Name)
-		if serviceConfig.Tunnel != "" {
-			a.removeServiceRouteFromTunnel(serviceConfig)
+		if activeTunnel != "" {
+			a.removeServiceRouteFromTunnel(serviceConfig, activeTunnel)
 		}
 		a.respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -134,34 +153,45 @@ func (a *APIServer) handleAddService(w http.ResponseWriter, r *http.Request) {
 
 	if tunnelToUpdate != nil {
 		a.logger.Info("Updating tunnel with new service route",
-			"service", serviceConfig.Name, "tunnel", serviceConfig.Tunnel)
+			"service", serviceConfig.Name, "tunnel", activeTunnel)
 
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
 		if err := a.updateRunningTunnel(ctx, *tunnelToUpdate); err != nil {
 			a.logger.Warn("Failed to update tunnel configuration",
-				"service", serviceConfig.Name, "tunnel", serviceConfig.Tunnel, "error", err)
+				"service", serviceConfig.Name, "tunnel", activeTunnel, "error", err)
 		} else {
 			a.logger.Info("Successfully updated tunnel with new route",
-				"service", serviceConfig.Name, "tunnel", serviceConfig.Tunnel)
+				"service", serviceConfig.Name, "tunnel", activeTunnel)
 		}
 	}
 
 	response := ServiceStatusResponse{
-		Name:        serviceConfig.Name,
-		Upstream:    serviceConfig.Upstream,
-		Status:      "running",
-		Tunnel:      serviceConfig.Tunnel,
-		Jellyfin:    serviceConfig.Jellyfin,
-		Websocket:   serviceConfig.Websocket,
-		Default:     serviceConfig.Default,
-		PublishMDNS: serviceConfig.PublishMDNS,
+		Name:           serviceConfig.Name,
+		Upstream:       serviceConfig.Upstream,
+		Upstreams:      serviceConfig.Upstreams,
+		LoadBalancing:  serviceConfig.LoadBalancing,
+		StickySessions: serviceConfig.StickySessions,
+		Hosts:          serviceConfig.Hosts,
+		PathPrefix:     serviceConfig.PathPrefix,
+		StripPrefix:    serviceConfig.StripPrefix,
+		Status:         "running",
+		Enabled:        true,
+		Tunnel:         serviceConfig.Tunnel,
+		BackupTunnels:  serviceConfig.BackupTunnels,
+		ActiveTunnel:   activeTunnel,
+		RequireTunnel:  serviceConfig.RequireTunnel,
+		Jellyfin:       serviceConfig.Jellyfin,
+		Websocket:      serviceConfig.Websocket,
+		Default:        serviceConfig.Default,
+		PublishMDNS:    serviceConfig.PublishMDNS,
+		TLS:            serviceConfig.TLS,
 	}
 
 	successMessage := fmt.Sprintf("Service %s added successfully", serviceConfig.Name)
-	if serviceConfig.Tunnel != "" {
-		successMessage += fmt.Sprintf(" with route to tunnel %s", serviceConfig.Tunnel)
+	if activeTunnel != "" {
+		successMessage += fmt.Sprintf(" with route to tunnel %s", activeTunnel)
 	}
 
 	a.respondWithSuccess(w, successMessage, response)
@@ -178,31 +208,42 @@ func (a *APIServer) handleDeleteService(w http.ResponseWriter, r *http.Request,
 		}
 	}
 
+	running := serviceToDelete != nil
+	if !running {
+		if serviceConfig := a.cfg.GetService(serviceName); serviceConfig != nil {
+			stoppedService := *serviceConfig
+			serviceToDelete = &stoppedService
+		}
+	}
+
 	if serviceToDelete == nil {
 		a.respondWithError(w, http.StatusNotFound, "Service not found")
 		return
 	}
 
-	if serviceToDelete.Tunnel != "" {
-		if err := a.removeServiceRouteFromTunnel(*serviceToDelete); err != nil {
-			a.logger.Error("Failed to remove route from tunnel", "error", err)
+	for _, tunnelName := range a.cfg.ServiceTunnels(*serviceToDelete) {
+		if err := a.removeServiceRouteFromTunnel(*serviceToDelete, tunnelName); err != nil {
+			a.logger.Error("Failed to remove route from tunnel", "tunnel", tunnelName, "error", err)
 		}
 
-		tunnelConfig := a.cfg.GetTunnel(serviceToDelete.Tunnel)
+		tunnelConfig := a.cfg.GetTunnel(tunnelName)
 		if tunnelConfig != nil {
 			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-			defer cancel()
+			err := a.updateRunningTunnel(ctx, *tunnelConfig)
+			cancel()
 
-			if err := a.updateRunningTunnel(ctx, *tunnelConfig); err != nil {
+			if err != nil {
 				a.logger.Warn("Failed to update tunnel after service deletion",
-					"service", serviceName, "tunnel", serviceToDelete.Tunnel, "error", err)
+					"service", serviceName, "tunnel", tunnelName, "error", err)
 			}
 		}
 	}
 
-	if err := a.proxyServer.RemoveService(serviceName); err != nil {
-		a.respondWithError(w, http.StatusNotFound, "Service not found in proxy")
-		return
+	if running {
+		if err := a.proxyServer.RemoveService(serviceToDelete.Name); err != nil {
+			a.respondWithError(w, http.StatusNotFound, "Service not found in proxy")
+			return
+		}
 	}
 
 	if err := a.cfg.RemoveService(serviceName); err != nil {
@@ -223,158 +264,204 @@ func (a *APIServer) handleDeleteService(w http.ResponseWriter, r *http.Request,
 
 // MARK: handleGetService
 func (a *APIServer) handleGetService(w http.ResponseWriter, r *http.Request, serviceName string) {
-	status, err := a.proxyServer.GetServiceStatus(serviceName)
-	if err != nil {
-		a.respondWithError(w, http.StatusNotFound, "Service not found")
+	if status, err := a.proxyServer.GetServiceStatus(serviceName); err == nil {
+		response := a.serviceResponse(status.Config, "running")
+		response.UpstreamHealth = status.UpstreamStatuses()
+		a.respondWithSuccess(w, "Service retrieved", response)
 		return
 	}
 
-	response := ServiceStatusResponse{
-		Name:     status.Config.Name,
-		Upstream: status.Config.Upstream,
-		Status:   "running",
-		Tunnel:   status.Config.Tunnel,
+	serviceConfig := a.cfg.GetService(serviceName)
+	if serviceConfig == nil {
+		a.respondWithError(w, http.StatusNotFound, "Service not found")
+		return
 	}
 
-	a.respondWithSuccess(w, "Service retrieved", response)
+	a.respondWithSuccess(w, "Service retrieved", a.serviceResponse(*serviceConfig, inactiveServiceStatus(*serviceConfig)))
 }
 
-// MARK: addServiceRouteToTunnel
-func (a *APIServer) addServiceRouteToTunnel(serviceConfig config.ServiceConfig) error {
-	serviceIP, err := a.extractIPFromUpstream(serviceConfig.Upstream)
-	if err != nil {
-		return fmt.Errorf("failed to extract IP from upstream %s: %w", serviceConfig.Upstream, err)
+// MARK: handleServiceAction
+func (a *APIServer) handleServiceAction(w http.ResponseWriter, r *http.Request, serviceName, action string) {
+	if r.Method != http.MethodPost {
+		a.respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
 	}
 
-	tunnel := a.cfg.GetTunnel(serviceConfig.Tunnel)
-	if tunnel == nil {
-		return fmt.Errorf("tunnel %s not found", serviceConfig.Tunnel)
+	serviceConfig := a.cfg.GetService(serviceName)
+	if serviceConfig == nil {
+		a.respondWithError(w, http.StatusNotFound, "Service not found")
+		return
 	}
 
-	route := serviceIP + "/32"
-
-	for _, existingRoute := range tunnel.Routes {
-		if existingRoute == route {
-			a.logger.Info("Route already exists for service", "service", serviceConfig.Name, "route", route)
-			return nil
+	switch action {
+	case "enable", "disable":
+		if err := a.cfg.SetServiceEnabled(serviceConfig.Name, action == "enable"); err != nil {
+			a.respondWithError(w, http.StatusInternalServerError, "Failed to save service: "+err.Error())
+			return
+		}
+	case "start":
+		if !serviceConfig.IsEnabled() {
+			a.respondWithError(w, http.StatusConflict, "Service is disabled")
+			return
 		}
 	}
 
-	tunnel.Routes = append(tunnel.Routes, route)
-
-	if err := a.cfg.UpdateTunnel(*tunnel); err != nil {
-		return fmt.Errorf("failed to update tunnel config: %w", err)
+	status := inactiveServiceStatus(*serviceConfig)
+	if action == "start" || action == "enable" {
+		if err := a.startService(*serviceConfig); err != nil {
+			a.respondWithError(w, http.StatusInternalServerError, "Failed to start service: "+err.Error())
+			return
+		}
+		status = "running"
+	} else {
+		a.stopService(*serviceConfig)
 	}
 
-	a.logger.Info("Added service route to tunnel",
-		"service", serviceConfig.Name, "tunnel", serviceConfig.Tunnel, "route", route)
-
-	return nil
+	a.logger.Info("Service lifecycle action applied", "service", serviceConfig.Name, "action", action)
+	a.respondWithSuccess(w, fmt.Sprintf("Service %s %s", serviceConfig.Name, lifecycleActionPastTense(action)),
+		a.serviceResponse(*serviceConfig, status))
 }
 
-// MARK: removeServiceRouteFromTunnel
-func (a *APIServer) removeServiceRouteFromTunnel(serviceConfig config.ServiceConfig) error {
-	if serviceConfig.Tunnel == "" {
+// MARK: startService
+func (a *APIServer) startService(serviceConfig config.ServiceConfig) error {
+	if _, err := a.proxyServer.GetServiceStatus(serviceConfig.Name); err == nil {
 		return nil
 	}
 
-	serviceIP, err := a.extractIPFromUpstream(serviceConfig.Upstream)
-	if err != nil {
-		return fmt.Errorf("failed to extract IP from upstream %s: %w", serviceConfig.Upstream, err)
+	if err := a.proxyServer.AddService(serviceConfig); err != nil {
+		return err
 	}
 
-	tunnel := a.cfg.GetTunnel(serviceConfig.Tunnel)
-	if tunnel == nil {
-		return fmt.Errorf("tunnel %s not found", serviceConfig.Tunnel)
-	}
+	a.publishServiceMDNS(serviceConfig)
 
-	route := serviceIP + "/32"
+	if serviceConfig.Jellyfin && a.jellyfinBroadcaster != nil {
+		if err := a.jellyfinBroadcaster.AddJellyfinService(serviceConfig.Name, serviceConfig.Upstream); err != nil {
+			a.logger.Error("Failed to add Jellyfin service to broadcaster",
+				"name", serviceConfig.Name, "error", err)
+		}
+	}
 
-	newRoutes := make([]string, 0, len(tunnel.Routes))
-	routeRemoved := false
+	return nil
+}
 
-	for _, existingRoute := range tunnel.Routes {
-		if existingRoute != route {
-			newRoutes = append(newRoutes, existingRoute)
-		} else {
-			routeRemoved = true
-		}
+// MARK: stopService
+func (a *APIServer) stopService(serviceConfig config.ServiceConfig) {
+	if err := a.proxyServer.RemoveService(serviceConfig.Name); err != nil {
+		a.logger.Debug("Service was not running", "service", serviceConfig.Name)
 	}
 
-	if !routeRemoved {
-		a.logger.Debug("Route not found in tunnel", "route", route, "tunnel", serviceConfig.Tunnel)
-		return nil
+	if a.discoveryManager != nil {
+		a.discoveryManager.UnpublishService(serviceConfig.Name)
 	}
 
-	tunnel.Routes = newRoutes
+	if serviceConfig.Jellyfin && a.jellyfinBroadcaster != nil {
+		a.jellyfinBroadcaster.RemoveJellyfinService(serviceConfig.Name)
+	}
+}
 
-	if err := a.cfg.UpdateTunnel(*tunnel); err != nil {
-		return fmt.Errorf("failed to update tunnel config: %w", err)
+// MARK: serviceResponse
+func (a *APIServer) serviceResponse(svc config.ServiceConfig, status string) ServiceStatusResponse {
+	return ServiceStatusResponse{
+		Name:           svc.Name,
+		Upstream:       svc.Upstream,
+		Upstreams:      svc.Upstreams,
+		LoadBalancing:  svc.LoadBalancing,
+		StickySessions: svc.StickySessions,
+		Hosts:          svc.Hosts,
+		PathPrefix:     svc.PathPrefix,
+		StripPrefix:    svc.StripPrefix,
+		Status:         status,
+		Enabled:        svc.IsEnabled(),
+		Tunnel:         svc.Tunnel,
+		BackupTunnels:  svc.BackupTunnels,
+		ActiveTunnel:   a.activeTunnelFor(svc),
+		RequireTunnel:  svc.RequireTunnel,
+		Jellyfin:       svc.Jellyfin,
+		Websocket:      svc.Websocket,
+		Default:        svc.Default,
+		PublishMDNS:    svc.PublishMDNS,
+		TLS:            svc.TLS,
 	}
+}
 
-	a.logger.Info("Removed service route from tunnel",
-		"service", serviceConfig.Name, "tunnel", serviceConfig.Tunnel, "route", route)
+// MARK: inactiveServiceStatus
+func inactiveServiceStatus(svc config.ServiceConfig) string {
+	if !svc.IsEnabled() {
+		return "disabled"
+	}
+	return "stopped"
+}
 
-	return nil
+// MARK: activeTunnelFor
+func (a *APIServer) activeTunnelFor(serviceConfig config.ServiceConfig) string {
+	if a.failoverController != nil {
+		return a.failoverController.ActiveTunnel(serviceConfig)
+	}
+	return serviceConfig.Tunnel
 }
 
-// MARK: updateRunningTunnel
-func (a *APIServer) updateRunningTunnel(ctx context.Context, tunnelConfig config.TunnelConfig) error {
-	status, err := a.tunnelManager.Status(ctx, tunnelConfig.Name)
+// MARK: addServiceRouteToTunnel
+func (a *APIServer) addServiceRouteToTunnel(serviceConfig config.ServiceConfig, tunnelName string) error {
+	changed, err := a.cfg.AddServiceRoute(serviceConfig, tunnelName, a.skipUpstream(serviceConfig))
 	if err != nil {
-		a.logger.Debug("Tunnel not running or status unavailable", "tunnel", tunnelConfig.Name, "error", err)
-		return nil
+		return err
 	}
 
-	if status.State != "running" {
-		a.logger.Debug("Tunnel not in running state, skipping update", "tunnel", tunnelConfig.Name, "state", status.State)
+	if !changed {
+		a.logger.Info("Route already exists for service", "service", serviceConfig.Name, "tunnel", tunnelName)
 		return nil
 	}
 
-	if err := a.tunnelManager.UpdateTunnel(ctx, tunnelConfig); err != nil {
-		return fmt.Errorf("updating tunnel configuration: %w", err)
-	}
-
+	a.logger.Info("Added service route to tunnel", "service", serviceConfig.Name, "tunnel", tunnelName)
 	return nil
 }
 
-// MARK: extractIPFromUpstream
-func (a *APIServer) extractIPFromUpstream(upstream string) (string, error) {
-	parsedURL, err := url.Parse(upstream)
+// MARK: removeServiceRouteFromTunnel
+func (a *APIServer) removeServiceRouteFromTunnel(serviceConfig config.ServiceConfig, tunnelName string) error {
+	changed, err := a.cfg.RemoveServiceRoute(serviceConfig, tunnelName, a.skipUpstream(serviceConfig))
 	if err != nil {
-		return "", fmt.Errorf("invalid upstream URL: %w", err)
+		return err
 	}
 
-	host := parsedURL.Hostname()
-	if host == "" {
-		return "", fmt.Errorf("no hostname found in upstream URL")
+	if !changed {
+		a.logger.Debug("Route not found in tunnel", "service", serviceConfig.Name, "tunnel", tunnelName)
+		return nil
 	}
 
-	if net.ParseIP(host) != nil {
-		return host, nil
-	}
+	a.logger.Info("Removed service route from tunnel", "service", serviceConfig.Name, "tunnel", tunnelName)
+	return nil
+}
 
-	ips, err := net.LookupIP(host)
-	if err != nil {
-		return "", fmt.Errorf("failed to resolve hostname %s: %w", host, err)
-	}
-	if len(ips) == 0 {
-		return "", fmt.Errorf("no IP addresses found for hostname %s", host)
+// MARK: skipUpstream
+func (a *APIServer) skipUpstream(serviceConfig config.ServiceConfig) func(string, error) {
+	return func(upstream string, err error) {
+		a.logger.Warn("Skipping unresolvable service upstream", "service", serviceConfig.Name, "upstream", upstream, "error", err)
 	}
+}
 
-	for _, ip := range ips {
-		if ip.To4() != nil {
-			return ip.String(), nil
+// MARK: updateRunningTunnel
+func (a *APIServer) updateRunningTunnel(ctx context.Context, tunnelConfig config.TunnelConfig) error {
+	status, err := a.tunnelManager.Status(ctx, tunnelConfig.Name)
+	if err != nil || status.State != "running" {
+		a.logger.Debug("Tunnel not running, only applying its kill switch", "tunnel", tunnelConfig.Name, "state", status.State, "error", err)
+		// The kill switch outlives a stopped tunnel, so it must still follow its routes
+		if err := a.tunnelManager.ApplyKillSwitch(tunnelConfig); err != nil {
+			return fmt.Errorf("applying tunnel kill switch: %w", err)
 		}
+		return nil
+	}
+
+	if err := a.tunnelManager.UpdateTunnel(ctx, tunnelConfig); err != nil {
+		return fmt.Errorf("updating tunnel configuration: %w", err)
 	}
 
-	return ips[0].String(), nil
+	return nil
 }
 
 // MARK: checkServiceExists
 func (a *APIServer) checkServiceExists(serviceName string) error {
 	// Check config first (case-insensitive)
-	for _, existing := range a.cfg.Services {
+	for _, existing := range a.cfg.ServiceList() {
 		if strings.EqualFold(existing.Name, serviceName) {
 			return fmt.Errorf("service %s already exists in configuration", serviceName)
 		}
@@ -398,6 +485,40 @@ func (a *APIServer) validateServiceConfig(svc config.ServiceConfig) error {
 	if svc.Name == "" || svc.Upstream == "" {
 		return fmt.Errorf("name and upstream are required")
 	}
+
+	if svc.RequireTunnel && svc.Tunnel == "" {
+		return fmt.Errorf("require_tunnel needs a tunnel")
+	}
+
+	if svc.Tunnel != "" && a.cfg.GetTunnel(svc.Tunnel) == nil && a.cfg.GetTunnelGroup(svc.Tunnel) == nil {
+		return fmt.Errorf("unknown tunnel: %s", svc.Tunnel)
+	}
+
+	for _, backup := range svc.BackupTunnels {
+		if a.cfg.GetTunnel(backup) == nil {
+			return fmt.Errorf("unknown backup tunnel: %s", backup)
+		}
+	}
+
+	if svc.TLS != nil && (svc.TLS.CertFile == "") != (svc.TLS.KeyFile == "") {
+		return fmt.Errorf("tls needs both cert_file and key_file")
+	}
+
+	for _, host := range svc.Hosts {
+		if _, err := config.ParseHostPattern(host); err != nil {
+			return err
+		}
+	}
+
+	if svc.UsesACME() {
+		if a.cfg.Server.ACME == nil {
+			return fmt.Errorf("tls acme needs acme configured on the server")
+		}
+		if svc.TLS.CertFile != "" || len(svc.CertificateDomains(a.cfg.Server.BaseDomains)) == 0 {
+			return fmt.Errorf("tls acme needs domains or exact hosts and no cert_file")
+		}
+	}
+
 	return nil
 }
 
@@ -3,6 +3,7 @@ package v1
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
 
@@ -17,6 +18,11 @@ func (a *APIServer) handleTunnels(w http.ResponseWriter, r *http.Request) {
 	case http.MethodGet:
 		a.handleListTunnels(w, r, ctx)
 	case http.MethodPost:
+		// Imports share the collection route so no tunnel name is shadowed by an import path
+		if r.URL.Query().Get("format") == "wg-quick" {
+			a.handleTunnelImport(w, r)
+			return
+		}
 		a.handleCreateTunnel(w, r)
 	default:
 		a.respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -31,6 +37,28 @@ func (a *APIServer) handleTunnelByName(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if parts := strings.SplitN(tunnelName, "/", 3); len(parts) > 1 {
+		switch {
+		case parts[1] == "peers":
+			peerName := ""
+			if len(parts) == 3 {
+				peerName = parts[2]
+			}
+			a.handleTunnelPeers(w, r, parts[0], peerName)
+		case parts[1] == "export" && len(parts) == 2:
+			a.handleTunnelExport(w, r, parts[0])
+		case parts[1] == "capture" && len(parts) == 2:
+			a.handleTunnelCapture(w, r, parts[0])
+		case parts[1] == "provision" && len(parts) == 2:
+			a.handlePeerProvision(w, r, parts[0])
+		case isLifecycleAction(parts[1]) && len(parts) == 2:
+			a.handleTunnelAction(w, r, parts[0], parts[1])
+		default:
+			a.respondWithError(w, http.StatusNotFound, "Not found")
+		}
+		return
+	}
+
 	ctx := r.Context()
 
 	switch r.Method {
@@ -45,7 +73,7 @@ func (a *APIServer) handleTunnelByName(w http.ResponseWriter, r *http.Request) {
 
 // MARK: handleListTunnels
 func (a *APIServer) handleListTunnels(w http.ResponseWriter, r *http.Request, ctx context.Context) {
-	configuredTunnels := a.cfg.WireGuard.Tunnels
+	configuredTunnels := a.cfg.TunnelList()
 	runningTunnels, err := a.tunnelManager.ListTunnels(ctx)
 	if err != nil {
 		a.respondWithError(w, http.StatusInternalServerError, err.Error())
@@ -62,13 +90,7 @@ func (a *APIServer) handleListTunnels(w http.ResponseWriter, r *http.Request, ct
 		if runningTunnel, exists := runningMap[configTunnel.Name]; exists {
 			tunnelStatuses = append(tunnelStatuses, runningTunnel)
 		} else {
-			tunnelStatuses = append(tunnelStatuses, TunnelStatus{
-				Name:      configTunnel.Name,
-				State:     "stopped",
-				Interface: "",
-				MTU:       configTunnel.MTU,
-				Peers:     len(configTunnel.Peers),
-			})
+			tunnelStatuses = append(tunnelStatuses, inactiveTunnelStatus(configTunnel))
 		}
 	}
 
@@ -90,18 +112,23 @@ func (a *APIServer) handleCreateTunnel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := r.Context()
-	if err := a.tunnelManager.CreateTunnel(ctx, tunnelConfig); err != nil {
-		a.respondWithError(w, http.StatusInternalServerError, err.Error())
-		return
+	state := inactiveTunnelState(tunnelConfig)
+	if !tunnelConfig.OnDemand {
+		if err := a.tunnelManager.CreateTunnel(r.Context(), tunnelConfig); err != nil {
+			a.respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		state = "running"
 	}
 
 	response := TunnelStatus{
-		Name:      tunnelConfig.Name,
-		State:     "running",
-		Interface: "",
-		MTU:       tunnelConfig.MTU,
-		Peers:     len(tunnelConfig.Peers),
+		Name:       tunnelConfig.Name,
+		State:      state,
+		Interface:  "",
+		MTU:        tunnelConfig.MTU,
+		Peers:      len(tunnelConfig.Peers),
+		PublicKey:  publicKeyFor(tunnelConfig.PrivateKey),
+		ServerMode: tunnelConfig.Server != nil,
 	}
 
 	a.respondWithSuccess(w, "Tunnel created", response)
@@ -115,18 +142,7 @@ func (a *APIServer) handleGetTunnel(w http.ResponseWriter, r *http.Request, ctx
 		return
 	}
 
-	status, err := a.tunnelManager.Status(ctx, tunnelName)
-	if err != nil {
-		status = TunnelStatus{
-			Name:      configTunnel.Name,
-			State:     "stopped",
-			Interface: "",
-			MTU:       configTunnel.MTU,
-			Peers:     len(configTunnel.Peers),
-		}
-	}
-
-	a.respondWithSuccess(w, "Tunnel retrieved", status)
+	a.respondWithSuccess(w, "Tunnel retrieved", a.tunnelStatus(ctx, *configTunnel))
 }
 
 // MARK: handleDeleteTunnel
@@ -162,13 +178,18 @@ func (a *APIServer) handleTunnelRestart(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !tunnelConfig.IsEnabled() {
+		a.respondWithError(w, http.StatusConflict, "Tunnel is disabled")
+		return
+	}
+
 	ctx := r.Context()
 
-	if err := a.tunnelManager.DeleteTunnel(ctx, tunnelName); err != nil {
+	if err := a.tunnelManager.StopTunnel(ctx, tunnelName); err != nil {
 		a.logger.Warn("Failed to stop tunnel during restart", "tunnel", tunnelName, "error", err)
 	}
 
-	if err := a.tunnelManager.CreateTunnel(ctx, *tunnelConfig); err != nil {
+	if err := a.startTunnel(ctx, *tunnelConfig); err != nil {
 		a.respondWithError(w, http.StatusInternalServerError, "Failed to restart tunnel: "+err.Error())
 		return
 	}
@@ -177,6 +198,165 @@ func (a *APIServer) handleTunnelRestart(w http.ResponseWriter, r *http.Request)
 	a.respondWithSuccess(w, "Tunnel restarted", status)
 }
 
+// MARK: handleTunnelAction
+func (a *APIServer) handleTunnelAction(w http.ResponseWriter, r *http.Request, tunnelName, action string) {
+	if r.Method != http.MethodPost {
+		a.respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	tunnelConfig := a.cfg.GetTunnel(tunnelName)
+	if tunnelConfig == nil {
+		a.respondWithError(w, http.StatusNotFound, "Tunnel not found")
+		return
+	}
+
+	ctx := r.Context()
+
+	switch action {
+	case "enable", "disable":
+		if err := a.cfg.SetTunnelEnabled(tunnelConfig.Name, action == "enable"); err != nil {
+			a.respondWithError(w, http.StatusInternalServerError, "Failed to save tunnel: "+err.Error())
+			return
+		}
+	case "start":
+		if !tunnelConfig.IsEnabled() {
+			a.respondWithError(w, http.StatusConflict, "Tunnel is disabled")
+			return
+		}
+	}
+
+	if action == "start" || action == "enable" {
+		if err := a.startTunnel(ctx, *tunnelConfig); err != nil {
+			a.respondWithError(w, http.StatusInternalServerError, "Failed to start tunnel: "+err.Error())
+			return
+		}
+	} else if err := a.tunnelManager.StopTunnel(ctx, tunnelConfig.Name); err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Failed to stop tunnel: "+err.Error())
+		return
+	}
+
+	a.logger.Info("Tunnel lifecycle action applied", "tunnel", tunnelConfig.Name, "action", action)
+	a.respondWithSuccess(w, fmt.Sprintf("Tunnel %s %s", tunnelConfig.Name, lifecycleActionPastTense(action)),
+		a.tunnelStatus(ctx, *tunnelConfig))
+}
+
+// MARK: startTunnel
+func (a *APIServer) startTunnel(ctx context.Context, tunnelConfig config.TunnelConfig) error {
+	if tunnelConfig.OnDemand {
+		// Started through the on-demand path so the idle timeout still applies
+		release, err := a.tunnelManager.AcquireTunnel(ctx, tunnelConfig)
+		if err != nil {
+			return err
+		}
+		release()
+		return nil
+	}
+
+	if a.tunnelManager.TunnelRunning(tunnelConfig.Name) {
+		return nil
+	}
+
+	return a.tunnelManager.CreateTunnel(ctx, tunnelConfig)
+}
+
+// MARK: tunnelStatus
+func (a *APIServer) tunnelStatus(ctx context.Context, tunnelConfig config.TunnelConfig) TunnelStatus {
+	status, err := a.tunnelManager.Status(ctx, tunnelConfig.Name)
+	if err != nil {
+		return inactiveTunnelStatus(tunnelConfig)
+	}
+	return status
+}
+
+// MARK: handleTunnelImport
+func (a *APIServer) handleTunnelImport(w http.ResponseWriter, r *http.Request) {
+	var req TunnelImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+
+	if req.Name == "" {
+		a.respondWithError(w, http.StatusBadRequest, "Tunnel name required")
+		return
+	}
+
+	tunnelConfig, err := config.ParseWgQuickConfig(req.Name, req.Config)
+	if err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Invalid wg-quick config: "+err.Error())
+		return
+	}
+
+	if tunnelConfig.HasHooks() {
+		a.respondWithError(w, http.StatusBadRequest, "PreUp/PostUp/PreDown/PostDown hooks can only be configured in wireguard.yaml")
+		return
+	}
+
+	if tunnelConfig.MTU == 0 {
+		tunnelConfig.MTU = config.DefaultMTU
+	}
+	tunnelConfig.MonitorInterval = config.DefaultMonitorInterval
+	tunnelConfig.StaleConnectionTimeout = config.DefaultStaleTimeout
+	tunnelConfig.ReconnectionRetries = config.DefaultRetries
+
+	if err := a.cfg.AddTunnel(tunnelConfig); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	if err := a.tunnelManager.CreateTunnel(ctx, tunnelConfig); err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	status, _ := a.tunnelManager.Status(ctx, tunnelConfig.Name)
+	a.respondWithSuccess(w, "Tunnel imported", status)
+}
+
+// MARK: handleTunnelExport
+func (a *APIServer) handleTunnelExport(w http.ResponseWriter, r *http.Request, tunnelName string) {
+	if r.Method != http.MethodGet {
+		a.respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	tunnelConfig := a.cfg.GetTunnel(tunnelName)
+	if tunnelConfig == nil {
+		a.respondWithError(w, http.StatusNotFound, "Tunnel not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", tunnelConfig.Name+".conf"))
+	w.Write([]byte(config.FormatWgQuickConfig(*tunnelConfig)))
+}
+
+// MARK: inactiveTunnelStatus
+func inactiveTunnelStatus(tunnelConfig config.TunnelConfig) TunnelStatus {
+	return TunnelStatus{
+		Name:       tunnelConfig.Name,
+		State:      inactiveTunnelState(tunnelConfig),
+		Interface:  "",
+		MTU:        tunnelConfig.MTU,
+		Peers:      len(tunnelConfig.Peers),
+		PublicKey:  publicKeyFor(tunnelConfig.PrivateKey),
+		ServerMode: tunnelConfig.Server != nil,
+	}
+}
+
+// MARK: inactiveTunnelState
+func inactiveTunnelState(tunnelConfig config.TunnelConfig) string {
+	if !tunnelConfig.IsEnabled() {
+		return "disabled"
+	}
+	if tunnelConfig.OnDemand {
+		return "idle"
+	}
+	return "stopped"
+}
+
 // MARK: convertTunnelRequest
 func (a *APIServer) convertTunnelRequest(req TunnelCreateRequest) config.TunnelConfig {
 	if req.MTU == 0 {
@@ -185,6 +365,9 @@ func (a *APIServer) convertTunnelRequest(req TunnelCreateRequest) config.TunnelC
 	if req.MonitorInterval == 0 {
 		req.MonitorInterval = 25
 	}
+	if req.OnDemand && req.IdleTimeout == 0 {
+		req.IdleTimeout = config.DefaultIdleTimeout
+	}
 	if req.StaleConnectionTimeout == 0 {
 		req.StaleConnectionTimeout = 300
 	}
@@ -194,14 +377,43 @@ func (a *APIServer) convertTunnelRequest(req TunnelCreateRequest) config.TunnelC
 
 	peers := make([]config.PeerConfig, len(req.Peers))
 	for i, peerReq := range req.Peers {
-		peers[i] = config.PeerConfig{
-			Name:                   peerReq.Name,
-			PublicKey:              peerReq.PublicKey,
-			AllowedIPs:             peerReq.AllowedIPs,
-			Endpoint:               peerReq.Endpoint,
-			Preshared:              peerReq.PresharedKey,
-			Persistent:             peerReq.PersistentKeepalive > 0,
-			PersistentKeepaliveInt: peerReq.PersistentKeepalive,
+		peers[i] = a.convertPeerRequest(peerReq)
+	}
+
+	var server *config.TunnelServerConfig
+	if req.Server != nil {
+		server = &config.TunnelServerConfig{
+			Endpoint:            req.Server.Endpoint,
+			AddressPool:         req.Server.AddressPool,
+			ClientAllowedIPs:    req.Server.ClientAllowedIPs,
+			ClientDNS:           req.Server.ClientDNS,
+			PersistentKeepalive: req.Server.PersistentKeepalive,
+		}
+	}
+
+	var probes []config.ProbeConfig
+	for _, probeReq := range req.Probes {
+		probes = append(probes, config.ProbeConfig{
+			Name:             probeReq.Name,
+			Type:             probeReq.Type,
+			Target:           probeReq.Target,
+			Timeout:          probeReq.Timeout,
+			FailureThreshold: probeReq.FailureThreshold,
+		})
+	}
+
+	var acl *config.ACLConfig
+	if req.ACL != nil {
+		acl = &config.ACLConfig{Default: req.ACL.Default}
+		for _, ruleReq := range req.ACL.Rules {
+			acl.Rules = append(acl.Rules, config.ACLRule{
+				Name:        ruleReq.Name,
+				Action:      ruleReq.Action,
+				Peer:        ruleReq.Peer,
+				Destination: ruleReq.Destination,
+				Protocol:    ruleReq.Protocol,
+				Port:        ruleReq.Port,
+			})
 		}
 	}
 
@@ -212,9 +424,32 @@ func (a *APIServer) convertTunnelRequest(req TunnelCreateRequest) config.TunnelC
 		MTU:                    req.MTU,
 		Addresses:              req.Addresses,
 		Routes:                 req.Routes,
+		DNS:                    req.DNS,
+		Table:                  req.Table,
+		FwMark:                 req.FwMark,
+		SaveConfig:             req.SaveConfig,
+		KillSwitch:             req.KillSwitch,
+		OnDemand:               req.OnDemand,
+		IdleTimeout:            req.IdleTimeout,
+		Server:                 server,
+		Probes:                 probes,
+		ACL:                    acl,
 		Peers:                  peers,
 		MonitorInterval:        req.MonitorInterval,
 		StaleConnectionTimeout: req.StaleConnectionTimeout,
 		ReconnectionRetries:    req.ReconnectionRetries,
 	}
 }
+
+// MARK: convertPeerRequest
+func (a *APIServer) convertPeerRequest(req PeerCreateRequest) config.PeerConfig {
+	return config.PeerConfig{
+		Name:                   req.Name,
+		PublicKey:              req.PublicKey,
+		AllowedIPs:             req.AllowedIPs,
+		Endpoint:               req.Endpoint,
+		Preshared:              req.PresharedKey,
+		Persistent:             req.PersistentKeepalive > 0,
+		PersistentKeepaliveInt: req.PersistentKeepalive,
+	}
+}
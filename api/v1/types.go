@@ -1,10 +1,12 @@
 package v1
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/JPKribs/FinGuard/config"
 	"github.com/JPKribs/FinGuard/discovery"
+	"github.com/JPKribs/FinGuard/failover"
 	"github.com/JPKribs/FinGuard/internal"
 	"github.com/JPKribs/FinGuard/mdns"
 	"github.com/JPKribs/FinGuard/proxy"
@@ -25,12 +27,29 @@ type APIServer struct {
 	cfg                 *config.Config
 	proxyServer         *proxy.Server
 	tunnelManager       wireguard.TunnelManager
+	failoverController  *failover.Controller
 	discoveryManager    *mdns.Discovery
 	jellyfinBroadcaster *discovery.JellyfinBroadcaster
 	logger              *internal.Logger
 	updateManager       *updater.UpdateManager
 }
 
+// MARK: KeyPairResponse
+type KeyPairResponse struct {
+	PrivateKey string `json:"private_key,omitempty"`
+	PublicKey  string `json:"public_key"`
+}
+
+// MARK: PresharedKeyResponse
+type PresharedKeyResponse struct {
+	PresharedKey string `json:"preshared_key"`
+}
+
+// MARK: PublicKeyRequest
+type PublicKeyRequest struct {
+	PrivateKey string `json:"private_key"`
+}
+
 // MARK: LogEntry
 type LogEntry struct {
 	Timestamp time.Time              `json:"timestamp"`
@@ -47,6 +66,12 @@ type LogResponse struct {
 	Offset int        `json:"offset"`
 }
 
+// MARK: peerError
+type peerError struct {
+	status  int
+	message string
+}
+
 // MARK: PeerCreateRequest
 type PeerCreateRequest struct {
 	Name                string   `json:"name"`
@@ -59,39 +84,123 @@ type PeerCreateRequest struct {
 
 // MARK: ServiceCreateRequest
 type ServiceCreateRequest struct {
-	Name        string `json:"name"`
-	Upstream    string `json:"upstream"`
-	Tunnel      string `json:"tunnel,omitempty"`
-	Jellyfin    bool   `json:"jellyfin"`
-	Websocket   bool   `json:"websocket"`
-	Default     bool   `json:"default"`
-	PublishMDNS bool   `json:"publish_mdns"`
+	Name           string                   `json:"name"`
+	Upstream       string                   `json:"upstream"`
+	Upstreams      []string                 `json:"upstreams,omitempty"`
+	LoadBalancing  string                   `json:"load_balancing,omitempty"`
+	StickySessions bool                     `json:"sticky_sessions,omitempty"`
+	Hosts          []string                 `json:"hosts,omitempty"`
+	PathPrefix     string                   `json:"path_prefix,omitempty"`
+	StripPrefix    bool                     `json:"strip_prefix,omitempty"`
+	Tunnel         string                   `json:"tunnel,omitempty"`
+	BackupTunnels  []string                 `json:"backup_tunnels,omitempty"`
+	RequireTunnel  bool                     `json:"require_tunnel,omitempty"`
+	Jellyfin       bool                     `json:"jellyfin"`
+	Websocket      bool                     `json:"websocket"`
+	Default        bool                     `json:"default"`
+	PublishMDNS    bool                     `json:"publish_mdns"`
+	TLS            *config.ServiceTLSConfig `json:"tls,omitempty"`
 }
 
 // MARK: ServiceStatusResponse
 type ServiceStatusResponse struct {
-	Name        string `json:"name"`
-	Upstream    string `json:"upstream"`
-	Status      string `json:"status"`
-	Tunnel      string `json:"tunnel,omitempty"`
-	Jellyfin    bool   `json:"jellyfin"`
-	Websocket   bool   `json:"websocket"`
-	Default     bool   `json:"default"`
-	PublishMDNS bool   `json:"publish_mdns"`
+	Name           string                   `json:"name"`
+	Upstream       string                   `json:"upstream"`
+	Upstreams      []string                 `json:"upstreams,omitempty"`
+	LoadBalancing  string                   `json:"load_balancing,omitempty"`
+	StickySessions bool                     `json:"sticky_sessions,omitempty"`
+	Hosts          []string                 `json:"hosts,omitempty"`
+	PathPrefix     string                   `json:"path_prefix,omitempty"`
+	StripPrefix    bool                     `json:"strip_prefix,omitempty"`
+	Status         string                   `json:"status"`
+	Enabled        bool                     `json:"enabled"`
+	Tunnel         string                   `json:"tunnel,omitempty"`
+	BackupTunnels  []string                 `json:"backup_tunnels,omitempty"`
+	ActiveTunnel   string                   `json:"active_tunnel,omitempty"`
+	RequireTunnel  bool                     `json:"require_tunnel,omitempty"`
+	Jellyfin       bool                     `json:"jellyfin"`
+	Websocket      bool                     `json:"websocket"`
+	Default        bool                     `json:"default"`
+	PublishMDNS    bool                     `json:"publish_mdns"`
+	TLS            *config.ServiceTLSConfig `json:"tls,omitempty"`
+	UpstreamHealth []proxy.UpstreamStatus   `json:"upstream_health,omitempty"`
 }
 
 // MARK: TunnelCreateRequest
 type TunnelCreateRequest struct {
-	Name                   string              `json:"name"`
-	ListenPort             int                 `json:"listen_port"`
-	PrivateKey             string              `json:"private_key"`
-	MTU                    int                 `json:"mtu"`
-	Addresses              []string            `json:"addresses"`
-	Routes                 []string            `json:"routes"`
-	Peers                  []PeerCreateRequest `json:"peers"`
-	MonitorInterval        int                 `json:"monitor_interval"`
-	StaleConnectionTimeout int                 `json:"stale_connection_timeout"`
-	ReconnectionRetries    int                 `json:"reconnection_retries"`
+	Name                   string               `json:"name"`
+	ListenPort             int                  `json:"listen_port"`
+	PrivateKey             string               `json:"private_key"`
+	MTU                    int                  `json:"mtu"`
+	Addresses              []string             `json:"addresses"`
+	Routes                 []string             `json:"routes"`
+	DNS                    []string             `json:"dns,omitempty"`
+	Table                  string               `json:"table,omitempty"`
+	FwMark                 uint32               `json:"fwmark,omitempty"`
+	SaveConfig             bool                 `json:"save_config,omitempty"`
+	KillSwitch             bool                 `json:"kill_switch,omitempty"`
+	OnDemand               bool                 `json:"on_demand,omitempty"`
+	IdleTimeout            int                  `json:"idle_timeout,omitempty"`
+	Server                 *TunnelServerRequest `json:"server,omitempty"`
+	Probes                 []ProbeRequest       `json:"probes,omitempty"`
+	ACL                    *ACLRequest          `json:"acl,omitempty"`
+	Peers                  []PeerCreateRequest  `json:"peers"`
+	MonitorInterval        int                  `json:"monitor_interval"`
+	StaleConnectionTimeout int                  `json:"stale_connection_timeout"`
+	ReconnectionRetries    int                  `json:"reconnection_retries"`
+}
+
+// MARK: TunnelServerRequest
+type TunnelServerRequest struct {
+	Endpoint            string   `json:"endpoint"`
+	AddressPool         string   `json:"address_pool"`
+	ClientAllowedIPs    []string `json:"client_allowed_ips"`
+	ClientDNS           []string `json:"client_dns"`
+	PersistentKeepalive int      `json:"persistent_keepalive"`
+}
+
+// MARK: ProbeRequest
+type ProbeRequest struct {
+	Name             string `json:"name"`
+	Type             string `json:"type"`
+	Target           string `json:"target"`
+	Timeout          int    `json:"timeout"`
+	FailureThreshold int    `json:"failure_threshold"`
+}
+
+// MARK: ACLRequest
+type ACLRequest struct {
+	Default string           `json:"default"`
+	Rules   []ACLRuleRequest `json:"rules"`
+}
+
+// MARK: ACLRuleRequest
+type ACLRuleRequest struct {
+	Name        string `json:"name"`
+	Action      string `json:"action"`
+	Peer        string `json:"peer"`
+	Destination string `json:"destination"`
+	Protocol    string `json:"protocol"`
+	Port        string `json:"port"`
+}
+
+// MARK: PeerProvisionRequest
+type PeerProvisionRequest struct {
+	Name string `json:"name"`
+}
+
+// MARK: PeerProvisionResponse
+type PeerProvisionResponse struct {
+	Peer         *wireguard.PeerStatus `json:"peer"`
+	ClientConfig string                `json:"client_config"`
+	QRCode       string                `json:"qr_code"`
+	QRText       string                `json:"qr_text"`
+}
+
+// MARK: TunnelImportRequest
+type TunnelImportRequest struct {
+	Name   string `json:"name"`
+	Config string `json:"config"`
 }
 
 // MARK: TunnelStatus
@@ -116,3 +225,11 @@ type UpdateInfoResponse struct {
 	UpdateSchedule    string    `json:"update_schedule"`
 	AutoUpdateEnabled bool      `json:"auto_update_enabled"`
 }
+
+// MARK: captureStream
+type captureStream struct {
+	w           http.ResponseWriter
+	controller  *http.ResponseController
+	filename    string
+	wroteHeader bool
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/JPKribs/FinGuard/config"
 	"github.com/JPKribs/FinGuard/discovery"
+	"github.com/JPKribs/FinGuard/failover"
 	"github.com/JPKribs/FinGuard/internal"
 	"github.com/JPKribs/FinGuard/mdns"
 	"github.com/JPKribs/FinGuard/proxy"
@@ -33,20 +34,21 @@ func newApplication(configPath string) (*Application, error) {
 	updateManager := updater.NewUpdateManager(config, logger, version.Version)
 	jellyfinBroadcaster := discovery.NewJellyfinBroadcaster(logger)
 
-	// Convert config.WireGuardMode to wireguard.TunnelMode using string comparison
-	wireGuardMode := config.WireGuard.GetWireGuardMode()
+	// Pass the configured mode through unresolved so the manager can tell an explicit userspace request from a fallback
 	var tunnelMode wireguard.TunnelMode
-	switch string(wireGuardMode) {
+	switch string(config.WireGuard.Mode) {
 	case "wg-quick":
 		tunnelMode = wireguard.ModeWgQuick
 	case "kernel":
 		tunnelMode = wireguard.ModeKernel
 	case "userspace":
 		tunnelMode = wireguard.ModeUserspace
+	case "netstack":
+		tunnelMode = wireguard.ModeNetstack
 	case "auto":
 		tunnelMode = wireguard.ModeAuto
 	default:
-		tunnelMode = wireguard.ModeUserspace
+		tunnelMode = wireguard.TunnelMode(config.WireGuard.Mode)
 	}
 
 	tunnelManager, err := wireguard.NewManager(tunnelMode, config.WireGuard.Paths, logger)
@@ -54,16 +56,27 @@ func newApplication(configPath string) (*Application, error) {
 		return nil, fmt.Errorf("creating tunnel manager: %w", err)
 	}
 
-	return &Application{
+	proxyServer := proxy.NewServer(logger)
+	proxyServer.SetTunnelDialer(tunnelManager.DialContext)
+	proxyServer.SetTunnelStateChecker(tunnelManager.TunnelRunning)
+
+	failoverController := failover.NewController(config, tunnelManager, proxyServer, logger)
+	proxyServer.SetTunnelSelector(failoverController.ActiveTunnel)
+
+	app := &Application{
 		config:              config,
 		logger:              logger,
 		healthCheck:         healthCheck,
 		tunnelManager:       tunnelManager,
-		proxyServer:         proxy.NewServer(logger),
+		proxyServer:         proxyServer,
+		failoverController:  failoverController,
 		discoveryManager:    mdns.NewDiscovery(logger),
 		jellyfinBroadcaster: jellyfinBroadcaster,
 		updateManager:       updateManager,
-	}, nil
+	}
+	proxyServer.SetTunnelWaker(app.acquireTunnel)
+
+	return app, nil
 }
 
 // MARK: start
@@ -98,6 +111,8 @@ func (app *Application) start(ctx context.Context) error {
 		app.logger.Error("Failed to add some services", "error", err)
 	}
 
+	app.startFailover(ctx)
+
 	app.publishServices()
 	app.setupJellyfinServices()
 	app.updateReadiness()
@@ -136,8 +151,8 @@ func (app *Application) startJellyfinBroadcaster(ctx context.Context) error {
 func (app *Application) setupJellyfinServices() {
 	hasJellyfinServices := false
 
-	for _, serviceCfg := range app.config.Services {
-		if serviceCfg.Jellyfin {
+	for _, serviceCfg := range app.config.ServiceList() {
+		if serviceCfg.Jellyfin && serviceCfg.IsEnabled() {
 			hasJellyfinServices = true
 			if err := app.jellyfinBroadcaster.AddJellyfinService(serviceCfg.Name, serviceCfg.Upstream); err != nil {
 				app.logger.Error("Failed to add Jellyfin service for broadcast",
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"time"
 
 	"github.com/JPKribs/FinGuard/config"
 )
@@ -39,6 +38,8 @@ func (app *Application) startUpdateManager(ctx context.Context) error {
 // MARK: startTunnelManager
 // Initializes the WireGuard tunnel manager
 func (app *Application) startTunnelManager(ctx context.Context) error {
+	app.tunnelManager.SetPeerChangeHandler(app.saveTunnelPeers)
+
 	if err := app.tunnelManager.Start(ctx); err != nil {
 		return err
 	}
@@ -57,6 +58,19 @@ func (app *Application) startTunnelManager(ctx context.Context) error {
 	return nil
 }
 
+// MARK: startFailover
+// Starts moving services between their tunnels based on tunnel health
+func (app *Application) startFailover(ctx context.Context) {
+	app.failoverController.Start(ctx)
+
+	app.waitGroup.Add(1)
+	go func() {
+		defer app.waitGroup.Done()
+		<-ctx.Done()
+		app.failoverController.Stop()
+	}()
+}
+
 // MARK: startDiscovery
 // Starts mDNS service discovery if enabled
 func (app *Application) startDiscovery(ctx context.Context) error {
@@ -85,14 +99,25 @@ func (app *Application) startDiscovery(ctx context.Context) error {
 }
 
 // MARK: createTunnels
-// Creates all configured WireGuard tunnels with retry logic
+// Creates all configured WireGuard tunnels, leaving retries to each tunnel's supervisor
 func (app *Application) createTunnels(ctx context.Context) error {
 	var errs []error
 
-	for _, tunnelCfg := range app.config.WireGuard.Tunnels {
-		if err := app.createTunnelWithRetry(ctx, tunnelCfg); err != nil {
+	for _, tunnelCfg := range app.config.TunnelList() {
+		if !tunnelCfg.IsEnabled() {
+			app.logger.Info("Skipping disabled tunnel", "name", tunnelCfg.Name)
+			continue
+		}
+		if tunnelCfg.OnDemand {
+			app.logger.Info("Deferring on-demand tunnel until first request", "name", tunnelCfg.Name)
+			continue
+		}
+		// A failed tunnel is retried with backoff by its supervisor, so one attempt is enough here
+		if err := app.tunnelManager.CreateTunnel(ctx, tunnelCfg); err != nil {
 			errs = append(errs, fmt.Errorf("tunnel %s: %w", tunnelCfg.Name, err))
+			continue
 		}
+		app.logger.Info("Created tunnel", "name", tunnelCfg.Name)
 	}
 
 	if len(errs) > 0 {
@@ -102,35 +127,73 @@ func (app *Application) createTunnels(ctx context.Context) error {
 	return nil
 }
 
-// MARK: createTunnelWithRetry
-// Creates a single tunnel with exponential backoff retry
-func (app *Application) createTunnelWithRetry(ctx context.Context, tunnelCfg config.TunnelConfig) error {
-	var lastErr error
+// MARK: syncTunnels
+// Deletes tunnels removed from the config, stops disabled ones and creates enabled tunnels not yet known to the manager
+func (app *Application) syncTunnels(ctx context.Context) {
+	if statuses, err := app.tunnelManager.ListTunnels(ctx); err == nil {
+		for _, status := range statuses {
+			if app.config.GetTunnel(status.Name) != nil {
+				continue
+			}
+			if err := app.tunnelManager.DeleteTunnel(ctx, status.Name); err != nil {
+				app.logger.Error("Failed to delete removed tunnel", "name", status.Name, "error", err)
+			}
+		}
+	}
 
-	for attempt := 1; attempt <= MaxRetries; attempt++ {
-		if err := app.tunnelManager.CreateTunnel(ctx, tunnelCfg); err != nil {
-			lastErr = err
-			app.logger.Error("Failed to create tunnel",
-				"name", tunnelCfg.Name,
-				"attempt", attempt,
-				"max_attempts", MaxRetries,
-				"error", err)
-
-			if attempt < MaxRetries {
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				case <-time.After(RetryDelay * time.Duration(attempt)):
-				}
+	for _, tunnelCfg := range app.config.TunnelList() {
+		if !tunnelCfg.IsEnabled() {
+			if err := app.tunnelManager.StopTunnel(ctx, tunnelCfg.Name); err != nil {
+				app.logger.Error("Failed to stop disabled tunnel", "name", tunnelCfg.Name, "error", err)
+			}
+			if err := app.tunnelManager.ApplyKillSwitch(tunnelCfg); err != nil {
+				app.logger.Error("Failed to apply kill switch", "name", tunnelCfg.Name, "error", err)
 			}
 			continue
 		}
 
-		app.logger.Info("Created tunnel", "name", tunnelCfg.Name)
+		if tunnelCfg.OnDemand {
+			continue
+		}
+		if _, err := app.tunnelManager.Status(ctx, tunnelCfg.Name); err == nil {
+			continue
+		}
+
+		if err := app.tunnelManager.CreateTunnel(ctx, tunnelCfg); err != nil {
+			app.logger.Error("Failed to create tunnel", "name", tunnelCfg.Name, "error", err)
+		} else {
+			app.logger.Info("Created tunnel", "name", tunnelCfg.Name)
+		}
+	}
+}
+
+// MARK: acquireTunnel
+// Brings up an on-demand tunnel for a proxied request, leaving other tunnels untouched
+func (app *Application) acquireTunnel(ctx context.Context, tunnelName string) (func(), error) {
+	tunnelCfg := app.config.GetTunnel(tunnelName)
+	if tunnelCfg == nil || !tunnelCfg.OnDemand {
+		return func() {}, nil
+	}
+	if !tunnelCfg.IsEnabled() {
+		return nil, fmt.Errorf("tunnel %s is disabled", tunnelName)
+	}
+
+	return app.tunnelManager.AcquireTunnel(ctx, *tunnelCfg)
+}
+
+// MARK: saveTunnelPeers
+// Saves peers changed outside FinGuard, such as with wg set, so later updates do not revert them
+func (app *Application) saveTunnelPeers(tunnelName string, peers []config.PeerConfig) {
+	err := app.config.UpdateTunnelFunc(tunnelName, func(tunnel *config.TunnelConfig) error {
+		tunnel.Peers = peers
 		return nil
+	})
+	if err != nil {
+		app.logger.Error("Failed to save out-of-band peer changes", "tunnel", tunnelName, "error", err)
+		return
 	}
 
-	return lastErr
+	app.logger.Info("Saved out-of-band peer changes", "tunnel", tunnelName, "peers", len(peers))
 }
 
 // MARK: startProxy
@@ -140,6 +203,27 @@ func (app *Application) startProxy(ctx context.Context) error {
 		return err
 	}
 
+	app.proxyServer.SetHostPrefixMatch(app.config.Server.HostPrefixMatch)
+	if err := app.proxyServer.SetBaseDomains(app.config.Server.BaseDomains); err != nil {
+		return fmt.Errorf("setting base domains: %w", err)
+	}
+
+	if err := app.proxyServer.SetCertificates(app.config.Server.Certificates); err != nil {
+		app.logger.Error("Failed to load certificates", "error", err)
+	}
+
+	if app.config.Server.ACME != nil {
+		if err := app.proxyServer.EnableACME(ctx, *app.config.Server.ACME); err != nil {
+			app.logger.Error("Failed to enable ACME certificates", "error", err)
+		}
+	}
+
+	if app.config.Server.ProxyTLSAddr != "" {
+		if err := app.proxyServer.StartTLS(ctx, app.config.Server.ProxyTLSAddr); err != nil {
+			return err
+		}
+	}
+
 	app.waitGroup.Add(1)
 	go func() {
 		defer app.waitGroup.Done()
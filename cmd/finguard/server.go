@@ -20,6 +20,7 @@ func (app *Application) startManagementServer() error {
 		app.config,
 		app.proxyServer,
 		app.tunnelManager,
+		app.failoverController,
 		app.discoveryManager,
 		app.jellyfinBroadcaster,
 		app.logger,
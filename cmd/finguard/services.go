@@ -13,7 +13,12 @@ func (app *Application) addServices() error {
 	var errs []error
 	addedServices := make(map[string]bool)
 
-	for _, serviceCfg := range app.config.Services {
+	for _, serviceCfg := range app.config.ServiceList() {
+		if !serviceCfg.IsEnabled() {
+			app.logger.Info("Skipping disabled service", "name", serviceCfg.Name)
+			continue
+		}
+
 		if addedServices[serviceCfg.Name] {
 			app.logger.Warn("Skipping duplicate service", "name", serviceCfg.Name)
 			continue
@@ -44,8 +49,8 @@ func (app *Application) publishServices() {
 
 	proxyPort := config.GetPortFromAddr(app.config.Server.ProxyAddr)
 
-	for _, serviceCfg := range app.config.Services {
-		if serviceCfg.PublishMDNS {
+	for _, serviceCfg := range app.config.ServiceList() {
+		if serviceCfg.PublishMDNS && serviceCfg.IsEnabled() {
 			if err := app.discoveryManager.PublishService(serviceCfg, proxyPort); err != nil {
 				app.logger.Error("Failed to publish service via mDNS",
 					"name", serviceCfg.Name, "error", err)
@@ -56,6 +61,28 @@ func (app *Application) publishServices() {
 	}
 }
 
+// MARK: removeDisabledServices
+// Takes services that are disabled in the configuration out of the proxy and discovery
+func (app *Application) removeDisabledServices() {
+	for _, serviceCfg := range app.config.ServiceList() {
+		if serviceCfg.IsEnabled() {
+			continue
+		}
+
+		if err := app.proxyServer.RemoveService(serviceCfg.Name); err == nil {
+			app.logger.Info("Removed disabled service", "name", serviceCfg.Name)
+		}
+
+		if app.config.Discovery.Enable && app.config.Discovery.MDNS.Enabled {
+			app.discoveryManager.UnpublishService(serviceCfg.Name)
+		}
+
+		if serviceCfg.Jellyfin {
+			app.jellyfinBroadcaster.RemoveJellyfinService(serviceCfg.Name)
+		}
+	}
+}
+
 // MARK: updateReadiness
 // Updates application readiness status based on component states
 func (app *Application) updateReadiness() {
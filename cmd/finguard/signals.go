@@ -48,12 +48,26 @@ func (app *Application) handleReload() {
 	}
 
 	app.config = newCfg
+	app.failoverController.SetConfig(newCfg)
+
+	app.syncTunnels(app.context)
+	app.removeDisabledServices()
+
+	app.proxyServer.SetHostPrefixMatch(app.config.Server.HostPrefixMatch)
+	if err := app.proxyServer.SetBaseDomains(app.config.Server.BaseDomains); err != nil {
+		app.logger.Error("Failed to apply base domains", "error", err)
+	}
+
+	if err := app.proxyServer.SetCertificates(app.config.Server.Certificates); err != nil {
+		app.logger.Error("Failed to reload certificates", "error", err)
+	}
 
 	if err := app.addServices(); err != nil {
 		app.logger.Error("Failed to add services during reload", "error", err)
 	}
 
 	app.publishServices()
+	app.setupJellyfinServices()
 	app.updateReadiness()
 
 	app.logger.Info("Configuration reloaded successfully")
@@ -7,6 +7,7 @@ import (
 
 	"github.com/JPKribs/FinGuard/config"
 	"github.com/JPKribs/FinGuard/discovery"
+	"github.com/JPKribs/FinGuard/failover"
 	"github.com/JPKribs/FinGuard/internal"
 	"github.com/JPKribs/FinGuard/mdns"
 	"github.com/JPKribs/FinGuard/proxy"
@@ -21,6 +22,7 @@ type Application struct {
 	healthCheck         *internal.HealthChecker
 	tunnelManager       wireguard.TunnelManager
 	proxyServer         *proxy.Server
+	failoverController  *failover.Controller
 	discoveryManager    *mdns.Discovery
 	jellyfinBroadcaster *discovery.JellyfinBroadcaster
 	updateManager       *updater.UpdateManager
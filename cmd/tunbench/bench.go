@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	benchNamespace  = "fgbench"
+	hostVeth        = "fgbench0"
+	peerVeth        = "fgbench1"
+	hostVethAddr    = "10.201.0.1"
+	peerVethAddr    = "10.201.0.2"
+	hostTunnelAddr  = "10.202.0.1"
+	peerTunnelAddr  = "10.202.0.2"
+	tunnelSubnet    = "10.202.0.0/24"
+	benchListenPort = 51901
+	uploadPort      = 5201
+	downloadPort    = 5202
+	chunkSize       = 128 * 1024
+)
+
+// Namespace setup functions
+
+// MARK: setupNamespace
+// Creates the benchmark namespace and the veth pair joining it to the host
+func setupNamespace() error {
+	commands := [][]string{
+		{"ip", "netns", "add", benchNamespace},
+		{"ip", "link", "add", hostVeth, "type", "veth", "peer", "name", peerVeth},
+		{"ip", "link", "set", peerVeth, "netns", benchNamespace},
+		{"ip", "addr", "add", hostVethAddr + "/30", "dev", hostVeth},
+		{"ip", "link", "set", hostVeth, "up"},
+		{"ip", "netns", "exec", benchNamespace, "ip", "addr", "add", peerVethAddr + "/30", "dev", peerVeth},
+		{"ip", "netns", "exec", benchNamespace, "ip", "link", "set", peerVeth, "up"},
+		{"ip", "netns", "exec", benchNamespace, "ip", "link", "set", "lo", "up"},
+	}
+
+	for _, args := range commands {
+		if output, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	return nil
+}
+
+// MARK: teardownNamespace
+// Removes the benchmark namespace, which also deletes both ends of the veth pair
+func teardownNamespace() {
+	exec.Command("ip", "link", "del", hostVeth).Run()
+	exec.Command("ip", "netns", "del", benchNamespace).Run()
+}
+
+// Throughput functions
+
+// MARK: serveThroughput
+// Listens for upload and download runs on every address of the peer
+func serveThroughput(ctx context.Context) error {
+	sink, err := net.Listen("tcp", fmt.Sprintf(":%d", uploadPort))
+	if err != nil {
+		return fmt.Errorf("listening for uploads: %w", err)
+	}
+	source, err := net.Listen("tcp", fmt.Sprintf(":%d", downloadPort))
+	if err != nil {
+		sink.Close()
+		return fmt.Errorf("listening for downloads: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		sink.Close()
+		source.Close()
+	}()
+
+	go acceptLoop(sink, receiveAll)
+	go acceptLoop(source, sendUntilDeadline)
+
+	return nil
+}
+
+// MARK: acceptLoop
+// Handles each connection on a listener until it is closed
+func acceptLoop(listener net.Listener, handle func(net.Conn)) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			handle(conn)
+		}()
+	}
+}
+
+// MARK: receiveAll
+// Drains an upload and replies with the number of bytes received
+func receiveAll(conn net.Conn) {
+	received, _ := io.Copy(io.Discard, conn)
+	binary.Write(conn, binary.BigEndian, received)
+}
+
+// MARK: sendUntilDeadline
+// Sends data for the duration requested by the client
+func sendUntilDeadline(conn net.Conn) {
+	var duration int64
+	if err := binary.Read(conn, binary.BigEndian, &duration); err != nil {
+		return
+	}
+
+	chunk := make([]byte, chunkSize)
+	deadline := time.Now().Add(time.Duration(duration))
+	for time.Now().Before(deadline) {
+		if _, err := conn.Write(chunk); err != nil {
+			return
+		}
+	}
+}
+
+// MARK: measureUpload
+// Sends to the peer for the duration and returns the rate the peer received at, in bits per second
+func measureUpload(ctx context.Context, host string, duration time.Duration) (float64, error) {
+	conn, err := dialBench(ctx, host, uploadPort)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	chunk := make([]byte, chunkSize)
+	start := time.Now()
+	deadline := start.Add(duration)
+	for time.Now().Before(deadline) {
+		if _, err := conn.Write(chunk); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := conn.(*net.TCPConn).CloseWrite(); err != nil {
+		return 0, err
+	}
+
+	var received int64
+	if err := binary.Read(conn, binary.BigEndian, &received); err != nil {
+		return 0, fmt.Errorf("reading byte count: %w", err)
+	}
+
+	return float64(received*8) / time.Since(start).Seconds(), nil
+}
+
+// MARK: measureDownload
+// Receives from the peer for the duration and returns the rate, in bits per second
+func measureDownload(ctx context.Context, host string, duration time.Duration) (float64, error) {
+	conn, err := dialBench(ctx, host, downloadPort)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	if err := binary.Write(conn, binary.BigEndian, int64(duration)); err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	received, err := io.Copy(io.Discard, conn)
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		return 0, err
+	}
+
+	return float64(received*8) / time.Since(start).Seconds(), nil
+}
+
+// MARK: dialBench
+// Connects to a benchmark endpoint, retrying while the tunnel completes its first handshake
+func dialBench(ctx context.Context, host string, port int) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: 2 * time.Second}
+	address := net.JoinHostPort(host, fmt.Sprint(port))
+
+	var lastErr error
+	for attempt := 0; attempt < 10; attempt++ {
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	return nil, fmt.Errorf("connecting to %s: %w", address, lastErr)
+}
+
+// MARK: formatRate
+// Formats a bit rate in the largest whole unit
+func formatRate(bitsPerSecond float64) string {
+	switch {
+	case bitsPerSecond >= 1e9:
+		return fmt.Sprintf("%.2f Gbit/s", bitsPerSecond/1e9)
+	case bitsPerSecond >= 1e6:
+		return fmt.Sprintf("%.2f Mbit/s", bitsPerSecond/1e6)
+	}
+	return fmt.Sprintf("%.2f Kbit/s", bitsPerSecond/1e3)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/JPKribs/FinGuard/config"
+	"github.com/JPKribs/FinGuard/internal"
+	"github.com/JPKribs/FinGuard/wireguard"
+)
+
+// MARK: main
+// Measures userspace tunnel throughput between the host and a network namespace joined by a veth pair
+func main() {
+	var (
+		role       = flag.String("role", "host", "Benchmark side to run (host or peer)")
+		duration   = flag.Duration("duration", 10*time.Second, "Length of each throughput run")
+		mtu        = flag.Int("mtu", config.DefaultMTU, "Tunnel MTU")
+		privateKey = flag.String("private-key", "", "Private key of this side (peer role only)")
+		peerKey    = flag.String("peer-key", "", "Public key of the other side (peer role only)")
+	)
+	flag.Parse()
+
+	if runtime.GOOS != "linux" || os.Geteuid() != 0 {
+		log.Fatal("tunbench must run as root on Linux")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var err error
+	if *role == "peer" {
+		err = runPeer(ctx, *privateKey, *peerKey, *mtu)
+	} else {
+		err = runHost(ctx, *duration, *mtu)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// MARK: runHost
+// Builds the namespace, starts both tunnel ends and reports throughput over the veth pair and through the tunnel
+func runHost(ctx context.Context, duration time.Duration, mtu int) error {
+	if err := setupNamespace(); err != nil {
+		teardownNamespace()
+		return fmt.Errorf("setting up namespace: %w", err)
+	}
+	defer teardownNamespace()
+
+	hostPrivate, hostPublic, err := wireguard.GeneratePrivateKey()
+	if err != nil {
+		return err
+	}
+	peerPrivate, peerPublic, err := wireguard.GeneratePrivateKey()
+	if err != nil {
+		return err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating benchmark binary: %w", err)
+	}
+
+	peer := exec.CommandContext(ctx, "ip", "netns", "exec", benchNamespace, self,
+		"-role", "peer", "-mtu", fmt.Sprint(mtu), "-private-key", peerPrivate, "-peer-key", hostPublic)
+	peer.Stderr = os.Stderr
+	stdout, err := peer.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := peer.Start(); err != nil {
+		return fmt.Errorf("starting peer: %w", err)
+	}
+	defer func() {
+		peer.Process.Signal(syscall.SIGTERM)
+		peer.Wait()
+	}()
+
+	tunnel, err := startTunnel(ctx, "fgbench-host", hostTunnelAddr, hostPrivate, peerPublic, peerVethAddr, mtu)
+	if err != nil {
+		return err
+	}
+	defer tunnel.Stop(context.Background())
+
+	if line, err := bufio.NewReader(stdout).ReadString('\n'); err != nil || strings.TrimSpace(line) != "ready" {
+		return fmt.Errorf("peer did not become ready: %q %v", line, err)
+	}
+
+	fmt.Printf("Userspace tunnel throughput, MTU %d, %s per run\n\n", mtu, duration)
+	fmt.Printf("%-24s %12s %12s\n", "path", "upload", "download")
+
+	for _, path := range []struct{ label, addr string }{
+		{"veth (no tunnel)", peerVethAddr},
+		{"finguard userspace", peerTunnelAddr},
+	} {
+		upload, err := measureUpload(ctx, path.addr, duration)
+		if err != nil {
+			return fmt.Errorf("%s upload: %w", path.label, err)
+		}
+		download, err := measureDownload(ctx, path.addr, duration)
+		if err != nil {
+			return fmt.Errorf("%s download: %w", path.label, err)
+		}
+		fmt.Printf("%-24s %12s %12s\n", path.label, formatRate(upload), formatRate(download))
+	}
+
+	return nil
+}
+
+// MARK: runPeer
+// Runs the namespace end of the tunnel and serves the throughput endpoints until stopped
+func runPeer(ctx context.Context, privateKey, peerKey string, mtu int) error {
+	tunnel, err := startTunnel(ctx, "fgbench-peer", peerTunnelAddr, privateKey, peerKey, hostVethAddr, mtu)
+	if err != nil {
+		return err
+	}
+	defer tunnel.Stop(context.Background())
+
+	if err := serveThroughput(ctx); err != nil {
+		return err
+	}
+
+	fmt.Println("ready")
+	<-ctx.Done()
+	return nil
+}
+
+// MARK: startTunnel
+// Starts one end of the benchmark tunnel with the other end as its only peer
+func startTunnel(ctx context.Context, name, address, privateKey, peerKey, peerEndpoint string, mtu int) (*wireguard.Tunnel, error) {
+	cfg := config.TunnelConfig{
+		Name:       name,
+		ListenPort: benchListenPort,
+		PrivateKey: privateKey,
+		MTU:        mtu,
+		Addresses:  []string{address + "/24"},
+		Peers: []config.PeerConfig{{
+			Name:                   "bench",
+			PublicKey:              peerKey,
+			AllowedIPs:             []string{tunnelSubnet},
+			Endpoint:               fmt.Sprintf("%s:%d", peerEndpoint, benchListenPort),
+			Persistent:             true,
+			PersistentKeepaliveInt: 5,
+		}},
+	}
+
+	tunnel, err := wireguard.NewTunnel(cfg, internal.NewLogger("error"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tunnel.Start(ctx); err != nil {
+		return nil, fmt.Errorf("starting tunnel %s: %w", name, err)
+	}
+
+	return tunnel, nil
+}
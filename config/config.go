@@ -2,7 +2,10 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"slices"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -56,6 +59,30 @@ func (c *Config) validate() error {
 		return fmt.Errorf("admin_token must be set to a secure value")
 	}
 
+	if c.Server.ProxyTLSAddr != "" {
+		if _, _, err := net.SplitHostPort(c.Server.ProxyTLSAddr); err != nil {
+			return fmt.Errorf("invalid proxy_tls_addr %s: %w", c.Server.ProxyTLSAddr, err)
+		}
+	}
+
+	for i, cert := range c.Server.Certificates {
+		if cert.CertFile == "" || cert.KeyFile == "" {
+			return fmt.Errorf("certificate %d must set both cert_file and key_file", i+1)
+		}
+	}
+
+	for _, domain := range c.Server.BaseDomains {
+		if !validHostname(NormalizeHost(domain)) {
+			return fmt.Errorf("invalid base domain: %q", domain)
+		}
+	}
+
+	if c.Server.ACME != nil {
+		if err := c.validateACMEConfig(*c.Server.ACME); err != nil {
+			return err
+		}
+	}
+
 	tunnelNames := make(map[string]bool, len(c.WireGuard.Tunnels))
 	for _, tunnel := range c.WireGuard.Tunnels {
 		if err := c.validateTunnelConfig(tunnel); err != nil {
@@ -64,21 +91,91 @@ func (c *Config) validate() error {
 		tunnelNames[strings.ToLower(tunnel.Name)] = true
 	}
 
+	groupNames := make(map[string]bool, len(c.WireGuard.Groups))
+	for _, group := range c.WireGuard.Groups {
+		if err := c.validateTunnelGroup(group, tunnelNames); err != nil {
+			return err
+		}
+		groupNames[strings.ToLower(group.Name)] = true
+	}
+
 	for _, service := range c.Services {
 		if err := c.validateServiceConfig(service); err != nil {
 			return err
 		}
-		if service.Tunnel != "" && !tunnelNames[strings.ToLower(service.Tunnel)] {
+		if service.Tunnel != "" && !tunnelNames[strings.ToLower(service.Tunnel)] && !groupNames[strings.ToLower(service.Tunnel)] {
 			return fmt.Errorf("service %s references unknown tunnel: %s", service.Name, service.Tunnel)
 		}
+		for _, backup := range service.BackupTunnels {
+			if !tunnelNames[strings.ToLower(backup)] {
+				return fmt.Errorf("service %s references unknown backup tunnel: %s", service.Name, backup)
+			}
+		}
+	}
+
+	return nil
+}
+
+// MARK: validateACMEConfig
+// Validates the ACME directory and account settings used to issue service certificates.
+func (c *Config) validateACMEConfig(acme ACMEConfig) error {
+	directory, err := url.Parse(acme.DirectoryURL)
+	if err != nil || directory.Host == "" || (directory.Scheme != "https" && directory.Scheme != "http") {
+		return fmt.Errorf("invalid acme directory_url: %q", acme.DirectoryURL)
+	}
+	if !acme.AcceptTOS {
+		return fmt.Errorf("acme requires accept_tos to agree to the CA's terms of service")
+	}
+	if c.Server.ProxyTLSAddr == "" {
+		return fmt.Errorf("acme requires proxy_tls_addr to serve the issued certificates")
+	}
+	if acme.RenewBefore < 0 {
+		return fmt.Errorf("invalid acme renew_before: %d", acme.RenewBefore)
+	}
+	return nil
+}
+
+// MARK: validateTunnelGroup
+// Validates a tunnel failover group against the configured tunnels.
+func (c *Config) validateTunnelGroup(group TunnelGroupConfig, tunnelNames map[string]bool) error {
+	if group.Name == "" {
+		return fmt.Errorf("tunnel group name cannot be empty")
+	}
+	if tunnelNames[strings.ToLower(group.Name)] {
+		return fmt.Errorf("tunnel group %s has the same name as a tunnel", group.Name)
+	}
+	if len(group.Tunnels) == 0 {
+		return fmt.Errorf("tunnel group %s has no tunnels", group.Name)
+	}
+	if group.FailbackDelay < 0 {
+		return fmt.Errorf("tunnel group %s has invalid failback_delay: %d", group.Name, group.FailbackDelay)
+	}
+
+	for _, tunnel := range group.Tunnels {
+		if !tunnelNames[strings.ToLower(tunnel)] {
+			return fmt.Errorf("tunnel group %s references unknown tunnel: %s", group.Name, tunnel)
+		}
 	}
 
 	return nil
 }
 
 // MARK: GetTunnel
-// Returns a WireGuard tunnel configuration by name.
+// Returns a copy of a WireGuard tunnel configuration by name.
 func (c *Config) GetTunnel(name string) *TunnelConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if tunnel := c.getTunnel(name); tunnel != nil {
+		copied := *tunnel
+		return &copied
+	}
+	return nil
+}
+
+// MARK: getTunnel
+// Returns the stored WireGuard tunnel configuration by name. Callers must hold the lock.
+func (c *Config) getTunnel(name string) *TunnelConfig {
 	for i := range c.WireGuard.Tunnels {
 		if strings.EqualFold(c.WireGuard.Tunnels[i].Name, name) {
 			return &c.WireGuard.Tunnels[i]
@@ -90,11 +187,57 @@ func (c *Config) GetTunnel(name string) *TunnelConfig {
 // MARK: GetServicesByTunnel
 // Returns a services assigned to a specified tunnel.
 func (c *Config) GetServicesByTunnel(tunnelName string) []ServiceConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	services := make([]ServiceConfig, 0)
 	for _, svc := range c.Services {
-		if strings.EqualFold(svc.Tunnel, tunnelName) {
-			services = append(services, svc)
+		for _, name := range c.serviceTunnels(svc) {
+			if strings.EqualFold(name, tunnelName) {
+				services = append(services, svc)
+				break
+			}
 		}
 	}
 	return services
 }
+
+// MARK: GetTunnelGroup
+// Returns a copy of a tunnel failover group by name.
+func (c *Config) GetTunnelGroup(name string) *TunnelGroupConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if group := c.getTunnelGroup(name); group != nil {
+		copied := *group
+		return &copied
+	}
+	return nil
+}
+
+// MARK: getTunnelGroup
+// Returns the stored tunnel failover group by name. Callers must hold the lock.
+func (c *Config) getTunnelGroup(name string) *TunnelGroupConfig {
+	for i := range c.WireGuard.Groups {
+		if strings.EqualFold(c.WireGuard.Groups[i].Name, name) {
+			return &c.WireGuard.Groups[i]
+		}
+	}
+	return nil
+}
+
+// MARK: ServiceList
+// Returns a copy of the configured services that is safe to use while the configuration changes.
+func (c *Config) ServiceList() []ServiceConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.Services)
+}
+
+// MARK: TunnelList
+// Returns a copy of the configured tunnels that is safe to use while the configuration changes.
+func (c *Config) TunnelList() []TunnelConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.WireGuard.Tunnels)
+}
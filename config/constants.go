@@ -14,4 +14,38 @@ const (
 	WireGuardFileName      = "wireguard.yaml"
 	UpdateFileName         = "update.yaml"
 	DefaultUpdateSchedule  = "0 3 * * *"
+	DefaultStateDir        = "/var/lib/finguard/wireguard"
+	DefaultFailbackDelay   = 60
+	DefaultIdleTimeout     = 300
+	DefaultACMEStateDir    = "/var/lib/finguard/acme"
+	DefaultACMERenewBefore = 30
+)
+
+const (
+	ProbeTypeICMP                = "icmp"
+	ProbeTypeTCP                 = "tcp"
+	ProbeTypeHTTP                = "http"
+	DefaultProbeTimeout          = 5
+	DefaultProbeFailureThreshold = 3
+)
+
+const (
+	ACLActionAllow  = "allow"
+	ACLActionDeny   = "deny"
+	ACLProtocolAny  = "any"
+	ACLProtocolTCP  = "tcp"
+	ACLProtocolUDP  = "udp"
+	ACLProtocolICMP = "icmp"
+)
+
+const (
+	LoadBalanceRoundRobin       = "round_robin"
+	LoadBalanceLeastConnections = "least_connections"
+	LoadBalanceFailover         = "failover"
+)
+
+const (
+	HostPatternExact HostPatternKind = iota
+	HostPatternWildcard
+	HostPatternRegex
 )
@@ -12,6 +12,14 @@ func (c *Config) setDefaults() {
 	if c.Server.WebRoot == "" {
 		c.Server.WebRoot = DefaultWebRoot
 	}
+	if c.Server.ACME != nil {
+		if c.Server.ACME.StateDir == "" {
+			c.Server.ACME.StateDir = DefaultACMEStateDir
+		}
+		if c.Server.ACME.RenewBefore == 0 {
+			c.Server.ACME.RenewBefore = DefaultACMERenewBefore
+		}
+	}
 	if c.Log.Level == "" {
 		c.Log.Level = DefaultLogLevel
 	}
@@ -46,6 +54,9 @@ func (c *Config) setDefaults() {
 		if tunnel.ReconnectionRetries == 0 {
 			tunnel.ReconnectionRetries = DefaultRetries
 		}
+		if tunnel.OnDemand && tunnel.IdleTimeout == 0 {
+			tunnel.IdleTimeout = DefaultIdleTimeout
+		}
 
 		for j := range tunnel.Peers {
 			peer := &tunnel.Peers[j]
@@ -99,6 +99,14 @@ func (c *Config) saveToFile(filename string, data interface{}) error {
 // MARK: SaveServices
 // Persists current service configurations to external file.
 func (c *Config) SaveServices() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.saveServices()
+}
+
+// MARK: saveServices
+// Writes the services file. Callers must hold the lock.
+func (c *Config) saveServices() error {
 	return c.saveToFile(c.ServicesFile, struct {
 		Services []ServiceConfig `yaml:"services"`
 	}{Services: c.Services})
@@ -107,11 +115,21 @@ func (c *Config) SaveServices() error {
 // MARK: SaveWireGuard
 // Persists the current WireGuard configuration to file.
 func (c *Config) SaveWireGuard() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.saveWireGuard()
+}
+
+// MARK: saveWireGuard
+// Writes the WireGuard file. Callers must hold the lock.
+func (c *Config) saveWireGuard() error {
 	return c.saveToFile(c.WireGuardFile, c.WireGuard)
 }
 
 // MARK: SaveUpdate
 // Persists the update configuration to file.
 func (c *Config) SaveUpdate() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.saveToFile(c.UpdateFile, c.Update)
 }
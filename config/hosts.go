@@ -0,0 +1,223 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// MARK: ParseHostPattern
+// Parses a service host: an exact name, a *.example.com wildcard, or a regular expression prefixed with ~.
+func ParseHostPattern(raw string) (HostPattern, error) {
+	raw = strings.TrimSpace(raw)
+
+	if expr, ok := strings.CutPrefix(raw, "~"); ok {
+		// Anchored so a pattern cannot accidentally match a longer foreign name
+		regex, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return HostPattern{}, fmt.Errorf("invalid host regex %q: %w", expr, err)
+		}
+		return HostPattern{Kind: HostPatternRegex, Value: expr, regex: regex}, nil
+	}
+
+	host := NormalizeHost(raw)
+	if suffix, ok := strings.CutPrefix(host, "*."); ok {
+		if !validHostname(suffix) {
+			return HostPattern{}, fmt.Errorf("invalid wildcard host %q", raw)
+		}
+		return HostPattern{Kind: HostPatternWildcard, Value: suffix}, nil
+	}
+
+	if !validHostname(host) {
+		return HostPattern{}, fmt.Errorf("invalid host %q", raw)
+	}
+	return HostPattern{Kind: HostPatternExact, Value: host}, nil
+}
+
+// MARK: Matches
+// Checks if a normalized host matches the pattern. Wildcards match names at any depth below their suffix.
+func (p HostPattern) Matches(host string) bool {
+	switch p.Kind {
+	case HostPatternExact:
+		return host == p.Value
+	case HostPatternWildcard:
+		return len(host) > len(p.Value)+1 && strings.HasSuffix(host, "."+p.Value)
+	case HostPatternRegex:
+		return p.regex != nil && p.regex.MatchString(host)
+	}
+	return false
+}
+
+// MARK: String
+// Returns the pattern in the form it is written in the configuration.
+func (p HostPattern) String() string {
+	switch p.Kind {
+	case HostPatternWildcard:
+		return "*." + p.Value
+	case HostPatternRegex:
+		return "~" + p.Value
+	}
+	return p.Value
+}
+
+// MARK: NormalizeHost
+// Lowercases a host and strips any port and trailing dot.
+func NormalizeHost(host string) string {
+	host = strings.TrimSpace(host)
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.TrimPrefix(strings.TrimSuffix(host, "]"), "[")
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// MARK: HostPatterns
+// Returns the hosts a service answers on: its own patterns plus its name under each base domain.
+func (s ServiceConfig) HostPatterns(baseDomains []string) ([]HostPattern, error) {
+	patterns := make([]HostPattern, 0, len(s.Hosts)+len(baseDomains))
+	seen := make(map[string]bool, cap(patterns))
+
+	add := func(raw string) error {
+		pattern, err := ParseHostPattern(raw)
+		if err != nil {
+			return fmt.Errorf("service %s: %w", s.Name, err)
+		}
+		if !seen[pattern.String()] {
+			seen[pattern.String()] = true
+			patterns = append(patterns, pattern)
+		}
+		return nil
+	}
+
+	for _, host := range s.Hosts {
+		if err := add(host); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, domain := range baseDomains {
+		if err := add(s.Name + "." + NormalizeHost(domain)); err != nil {
+			return nil, err
+		}
+	}
+
+	return patterns, nil
+}
+
+// MARK: CertificateDomains
+// Returns the names to request a certificate for: the explicit tls domains, or else every exact host of the service.
+func (s ServiceConfig) CertificateDomains(baseDomains []string) []string {
+	if s.TLS != nil && len(s.TLS.Domains) > 0 {
+		return s.TLS.Domains
+	}
+
+	patterns, err := s.HostPatterns(baseDomains)
+	if err != nil {
+		return nil
+	}
+
+	var domains []string
+	for _, pattern := range patterns {
+		if pattern.Kind == HostPatternExact {
+			domains = append(domains, pattern.Value)
+		}
+	}
+	return domains
+}
+
+// MARK: validateServiceHosts
+// Validates the host patterns of a service and rejects any claimed by another service.
+func (c *Config) validateServiceHosts(svc ServiceConfig) error {
+	patterns, err := svc.HostPatterns(c.Server.BaseDomains)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range c.Services {
+		if strings.EqualFold(other.Name, svc.Name) {
+			continue
+		}
+
+		// Services may share a host as long as they are mounted under different paths
+		if svc.RoutePrefix() != other.RoutePrefix() {
+			continue
+		}
+
+		otherPatterns, err := other.HostPatterns(c.Server.BaseDomains)
+		if err != nil {
+			continue
+		}
+
+		for _, pattern := range patterns {
+			for _, otherPattern := range otherPatterns {
+				if pattern.String() == otherPattern.String() {
+					return fmt.Errorf("service %s host %s%s is already used by service %s", svc.Name, pattern, svc.RoutePrefix(), other.Name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// MARK: RoutePrefix
+// Returns the path the service is mounted under without a trailing slash, or an empty string when it serves the whole host.
+func (s ServiceConfig) RoutePrefix() string {
+	return strings.TrimRight(strings.TrimSpace(s.PathPrefix), "/")
+}
+
+// MARK: StripsPrefix
+// Checks if the mount path is removed before requests reach the upstream and added back to its responses.
+func (s ServiceConfig) StripsPrefix() bool {
+	return s.StripPrefix && s.RoutePrefix() != ""
+}
+
+// MARK: MatchesPath
+// Checks if a request path falls under the service mount path.
+func (s ServiceConfig) MatchesPath(path string) bool {
+	prefix := s.RoutePrefix()
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// MARK: validateServicePath
+// Validates the mount path of a service.
+func validateServicePath(svc ServiceConfig) error {
+	prefix := strings.TrimSpace(svc.PathPrefix)
+	if prefix == "" {
+		if svc.StripPrefix {
+			return fmt.Errorf("service %s sets strip_prefix without a path_prefix", svc.Name)
+		}
+		return nil
+	}
+
+	if !strings.HasPrefix(prefix, "/") || strings.ContainsAny(prefix, "?#* ") || strings.Contains(prefix, "//") {
+		return fmt.Errorf("service %s has invalid path_prefix: %q", svc.Name, svc.PathPrefix)
+	}
+	if svc.Default && svc.RoutePrefix() != "" {
+		return fmt.Errorf("service %s is the default and cannot set a path_prefix", svc.Name)
+	}
+
+	return nil
+}
+
+// MARK: validHostname
+// Checks that a host is made of non-empty labels of letters, digits, hyphens and underscores.
+func validHostname(host string) bool {
+	if host == "" || len(host) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseHostPattern(t *testing.T) {
+	tests := []struct {
+		raw     string
+		kind    HostPatternKind
+		value   string
+		wantErr bool
+	}{
+		{raw: "Jellyfin.Example.com.", kind: HostPatternExact, value: "jellyfin.example.com"},
+		{raw: "media.example.com:8443", kind: HostPatternExact, value: "media.example.com"},
+		{raw: "*.Example.com", kind: HostPatternWildcard, value: "example.com"},
+		{raw: `~node[0-9]+\.lan`, kind: HostPatternRegex, value: `node[0-9]+\.lan`},
+		{raw: "~node[", wantErr: true},
+		{raw: "*.", wantErr: true},
+		{raw: "bad..host", wantErr: true},
+		{raw: "spaced host", wantErr: true},
+		{raw: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			pattern, err := ParseHostPattern(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", pattern)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if pattern.Kind != tt.kind || pattern.Value != tt.value {
+				t.Errorf("got kind %d value %q, want kind %d value %q", pattern.Kind, pattern.Value, tt.kind, tt.value)
+			}
+		})
+	}
+}
+
+func TestHostPatternMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		host    string
+		want    bool
+	}{
+		{"jf.example.com", "jf.example.com", true},
+		{"jf.example.com", "jf.example.com.evil.com", false},
+		{"jf.example.com", "example.com", false},
+		{"*.example.com", "jf.example.com", true},
+		{"*.example.com", "a.b.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "badexample.com", false},
+		{`~node[0-9]+\.lan`, "node12.lan", true},
+		{`~node[0-9]+\.lan`, "node12.lan.evil.com", false},
+		{`~node[0-9]+\.lan`, "xnode1.lan", false},
+		{"~a|b", "ab", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.host, func(t *testing.T) {
+			pattern, err := ParseHostPattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("ParseHostPattern: %v", err)
+			}
+			if got := pattern.Matches(tt.host); got != tt.want {
+				t.Errorf("Matches(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeHost(t *testing.T) {
+	tests := map[string]string{
+		"Example.COM":             "example.com",
+		"example.com.":            "example.com",
+		"example.com:8080":        "example.com",
+		" example.com ":           "example.com",
+		"[fd00::1]:443":           "fd00::1",
+		"jellyfin.finguard.local": "jellyfin.finguard.local",
+	}
+
+	for input, want := range tests {
+		if got := NormalizeHost(input); got != want {
+			t.Errorf("NormalizeHost(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestServiceHostPatterns(t *testing.T) {
+	svc := ServiceConfig{Name: "jellyfin", Hosts: []string{"media.example.com", "*.media.example.com", "Media.Example.com"}}
+
+	patterns, err := svc.HostPatterns([]string{"Example.com.", "finguard.local"})
+	if err != nil {
+		t.Fatalf("HostPatterns: %v", err)
+	}
+
+	var got []string
+	for _, pattern := range patterns {
+		got = append(got, pattern.String())
+	}
+	want := []string{"media.example.com", "*.media.example.com", "jellyfin.example.com", "jellyfin.finguard.local"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if domains := svc.CertificateDomains([]string{"example.com"}); !reflect.DeepEqual(domains, []string{"media.example.com", "jellyfin.example.com"}) {
+		t.Errorf("CertificateDomains = %v", domains)
+	}
+}
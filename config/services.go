@@ -2,13 +2,19 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"net/url"
+	"slices"
 	"strings"
+	"time"
 )
 
 // MARK: AddService
 // Adds a new service configuration and saves to file.
 func (c *Config) AddService(svc ServiceConfig) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if err := c.validateServiceConfig(svc); err != nil {
 		return err
 	}
@@ -20,34 +26,375 @@ func (c *Config) AddService(svc ServiceConfig) error {
 	}
 
 	c.Services = append(c.Services, svc)
-	return c.SaveServices()
+	return c.saveServices()
 }
 
 // MARK: RemoveService
 // Removes a service configuration by name and saves to file.
 func (c *Config) RemoveService(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	for i, svc := range c.Services {
 		if strings.EqualFold(svc.Name, name) {
-			c.Services = append(c.Services[:i], c.Services[i+1:]...)
-			return c.SaveServices()
+			c.Services = slices.Delete(slices.Clone(c.Services), i, i+1)
+			return c.saveServices()
 		}
 	}
 	return fmt.Errorf("service %s not found", name)
 }
 
+// MARK: GetService
+// Returns a copy of a service configuration by name.
+func (c *Config) GetService(name string) *ServiceConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if svc := c.getService(name); svc != nil {
+		copied := *svc
+		return &copied
+	}
+	return nil
+}
+
+// MARK: getService
+// Returns the stored service configuration by name. Callers must hold the lock.
+func (c *Config) getService(name string) *ServiceConfig {
+	for i := range c.Services {
+		if strings.EqualFold(c.Services[i].Name, name) {
+			return &c.Services[i]
+		}
+	}
+	return nil
+}
+
+// MARK: SetServiceEnabled
+// Enables or disables a service by name and saves to file.
+func (c *Config) SetServiceEnabled(name string, enabled bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	svc := c.getService(name)
+	if svc == nil {
+		return fmt.Errorf("service %s not found", name)
+	}
+
+	svc.Enabled = &enabled
+	return c.saveServices()
+}
+
+// MARK: IsEnabled
+// Checks if the service should be served, treating an unset flag as enabled.
+func (s ServiceConfig) IsEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
+}
+
 // MARK: validateServiceConfig
 // Validates a single service configuration.
 func (c *Config) validateServiceConfig(svc ServiceConfig) error {
 	if svc.Name == "" {
 		return fmt.Errorf("service name cannot be empty")
 	}
+	if strings.ContainsAny(svc.Name, `/\`) || svc.Name == "." || svc.Name == ".." {
+		return fmt.Errorf("service name %q cannot be a path", svc.Name)
+	}
 	if svc.Upstream == "" {
 		return fmt.Errorf("service %s missing upstream URL", svc.Name)
 	}
 
-	if _, err := url.Parse(svc.Upstream); err != nil {
-		return fmt.Errorf("invalid upstream URL %s for service %s: %w", svc.Upstream, svc.Name, err)
+	for _, upstream := range svc.UpstreamTargets() {
+		if _, err := url.Parse(upstream); err != nil {
+			return fmt.Errorf("invalid upstream URL %s for service %s: %w", upstream, svc.Name, err)
+		}
+	}
+
+	switch svc.LoadBalancingPolicy() {
+	case LoadBalanceRoundRobin, LoadBalanceLeastConnections, LoadBalanceFailover:
+	default:
+		return fmt.Errorf("service %s has unknown load_balancing policy: %s", svc.Name, svc.LoadBalancing)
+	}
+
+	if svc.RequireTunnel && svc.Tunnel == "" {
+		return fmt.Errorf("service %s requires a tunnel but none is configured", svc.Name)
+	}
+
+	if svc.TLS != nil && (svc.TLS.CertFile == "") != (svc.TLS.KeyFile == "") {
+		return fmt.Errorf("service %s must set both tls cert_file and key_file", svc.Name)
+	}
+
+	if err := validateServicePath(svc); err != nil {
+		return err
+	}
+
+	if err := c.validateServiceHosts(svc); err != nil {
+		return err
+	}
+
+	if svc.UsesACME() {
+		if err := c.validateServiceACME(svc); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// MARK: validateServiceACME
+// Validates the domains a service requests from the ACME directory.
+func (c *Config) validateServiceACME(svc ServiceConfig) error {
+	if c.Server.ACME == nil {
+		return fmt.Errorf("service %s uses acme but server acme is not configured", svc.Name)
+	}
+	if svc.TLS.CertFile != "" {
+		return fmt.Errorf("service %s cannot set both tls acme and cert_file", svc.Name)
+	}
+	domains := svc.CertificateDomains(c.Server.BaseDomains)
+	if len(domains) == 0 {
+		return fmt.Errorf("service %s uses acme but has no tls domains or exact hosts", svc.Name)
+	}
+
+	for _, domain := range domains {
+		// HTTP-01 challenges cannot prove control of a wildcard name
+		if strings.Contains(domain, "*") {
+			return fmt.Errorf("service %s cannot request wildcard domain %s over acme", svc.Name, domain)
+		}
+		if net.ParseIP(domain) != nil || !strings.Contains(domain, ".") {
+			return fmt.Errorf("service %s has invalid acme domain: %s", svc.Name, domain)
+		}
+	}
+
+	return nil
+}
+
+// MARK: UpstreamTargets
+// Returns every upstream of the service in priority order, starting with the primary upstream.
+func (s ServiceConfig) UpstreamTargets() []string {
+	targets := make([]string, 0, 1+len(s.Upstreams))
+	for _, upstream := range append([]string{s.Upstream}, s.Upstreams...) {
+		upstream = strings.TrimSpace(upstream)
+		if upstream != "" && !slices.Contains(targets, upstream) {
+			targets = append(targets, upstream)
+		}
+	}
+	return targets
+}
+
+// MARK: LoadBalancingPolicy
+// Returns how requests are spread across upstreams, defaulting to round robin.
+func (s ServiceConfig) LoadBalancingPolicy() string {
+	if s.LoadBalancing == "" {
+		return LoadBalanceRoundRobin
+	}
+	return strings.ToLower(s.LoadBalancing)
+}
+
+// MARK: HasCertificate
+// Checks if the service has its own certificate files configured.
+func (s ServiceConfig) HasCertificate() bool {
+	return s.TLS != nil && s.TLS.CertFile != "" && s.TLS.KeyFile != ""
+}
+
+// MARK: UsesACME
+// Checks if the service certificate is issued and renewed through ACME.
+func (s ServiceConfig) UsesACME() bool {
+	return s.TLS != nil && s.TLS.ACME
+}
+
+// MARK: RedirectsHTTPS
+// Checks if plain HTTP requests for the service should be redirected to HTTPS.
+func (s ServiceConfig) RedirectsHTTPS() bool {
+	return s.TLS != nil && s.TLS.RedirectHTTPS
+}
+
+// MARK: ServiceTunnels
+// Returns the ordered tunnels a service may use, primary first, expanding tunnel groups.
+func (c *Config) ServiceTunnels(svc ServiceConfig) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.serviceTunnels(svc)
+}
+
+// MARK: serviceTunnels
+// Expands the tunnel chain of a service. Callers must hold the lock.
+func (c *Config) serviceTunnels(svc ServiceConfig) []string {
+	if svc.Tunnel == "" {
+		return nil
+	}
+
+	if group := c.getTunnelGroup(svc.Tunnel); group != nil {
+		return append([]string(nil), group.Tunnels...)
+	}
+
+	return append([]string{svc.Tunnel}, svc.BackupTunnels...)
+}
+
+// MARK: ServiceFailbackDelay
+// Returns how long a higher priority tunnel must stay healthy before a service fails back to it.
+func (c *Config) ServiceFailbackDelay(svc ServiceConfig) time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if group := c.getTunnelGroup(svc.Tunnel); group != nil && group.FailbackDelay > 0 {
+		return time.Duration(group.FailbackDelay) * time.Second
+	}
+	return DefaultFailbackDelay * time.Second
+}
+
+// MARK: ServiceRoute
+// Returns the host route for a service upstream, resolving hostnames when needed.
+func ServiceRoute(upstream string) (string, error) {
+	parsedURL, err := url.Parse(upstream)
+	if err != nil {
+		return "", fmt.Errorf("invalid upstream URL: %w", err)
+	}
+
+	host := parsedURL.Hostname()
+	if host == "" {
+		return "", fmt.Errorf("no hostname found in upstream URL")
+	}
+
+	if net.ParseIP(host) != nil {
+		return host + "/32", nil
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve hostname %s: %w", host, err)
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("no IP addresses found for hostname %s", host)
+	}
+
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip.String() + "/32", nil
+		}
+	}
+
+	return ips[0].String() + "/32", nil
+}
+
+// MARK: AddServiceRoute
+// Adds the host routes of every resolvable service upstream to a tunnel and saves the tunnel, reporting whether it changed.
+func (c *Config) AddServiceRoute(svc ServiceConfig, tunnelName string, skip func(upstream string, err error)) (bool, error) {
+	routes, err := serviceRoutes(svc, skip)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tunnel := c.getTunnel(tunnelName)
+	if tunnel == nil {
+		return false, fmt.Errorf("tunnel %s not found", tunnelName)
+	}
+
+	updatedRoutes := append([]string(nil), tunnel.Routes...)
+	for _, route := range routes {
+		if !slices.Contains(updatedRoutes, route) {
+			updatedRoutes = append(updatedRoutes, route)
+		}
+	}
+
+	if len(updatedRoutes) == len(tunnel.Routes) {
+		return false, nil
+	}
+
+	updated := *tunnel
+	updated.Routes = updatedRoutes
+
+	if err := c.updateTunnel(updated); err != nil {
+		return false, fmt.Errorf("failed to update tunnel config: %w", err)
+	}
+
+	return true, nil
+}
+
+// MARK: RemoveServiceRoute
+// Removes the host routes of every resolvable service upstream from a tunnel and saves the tunnel, reporting whether it changed.
+func (c *Config) RemoveServiceRoute(svc ServiceConfig, tunnelName string, skip func(upstream string, err error)) (bool, error) {
+	routes, err := serviceRoutes(svc, skip)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tunnel := c.getTunnel(tunnelName)
+	if tunnel == nil {
+		return false, fmt.Errorf("tunnel %s not found", tunnelName)
+	}
+
+	newRoutes := make([]string, 0, len(tunnel.Routes))
+	for _, existingRoute := range tunnel.Routes {
+		if !slices.Contains(routes, existingRoute) {
+			newRoutes = append(newRoutes, existingRoute)
+		}
+	}
+
+	if len(newRoutes) == len(tunnel.Routes) {
+		return false, nil
+	}
+
+	updated := *tunnel
+	updated.Routes = newRoutes
+
+	if err := c.updateTunnel(updated); err != nil {
+		return false, fmt.Errorf("failed to update tunnel config: %w", err)
+	}
+
+	return true, nil
+}
+
+// MARK: serviceRoutes
+// Returns the host routes of every upstream of a service, passing unresolvable upstreams to skip and failing only when none resolve.
+func serviceRoutes(svc ServiceConfig, skip func(upstream string, err error)) ([]string, error) {
+	var routes []string
+	var lastErr error
+	for _, upstream := range svc.UpstreamTargets() {
+		route, err := ServiceRoute(upstream)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to extract IP from upstream %s: %w", upstream, err)
+			if skip != nil {
+				skip(upstream, err)
+			}
+			continue
+		}
+		if !slices.Contains(routes, route) {
+			routes = append(routes, route)
+		}
+	}
+
+	if len(routes) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return routes, nil
+}
+
+// MARK: ServiceRouteHolder
+// Returns the tunnel in a service's chain that currently carries its host route.
+func (c *Config) ServiceRouteHolder(svc ServiceConfig) string {
+	routes, err := serviceRoutes(svc, nil)
+	if err != nil || len(routes) == 0 {
+		return ""
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, name := range c.serviceTunnels(svc) {
+		tunnel := c.getTunnel(name)
+		if tunnel == nil {
+			continue
+		}
+		for _, existingRoute := range tunnel.Routes {
+			if slices.Contains(routes, existingRoute) {
+				return tunnel.Name
+			}
+		}
+	}
+
+	return ""
+}
@@ -1,5 +1,10 @@
 package config
 
+import (
+	"regexp"
+	"sync"
+)
+
 // MARK: WireGuardMode
 type WireGuardMode string
 
@@ -11,6 +16,8 @@ type WireGuardPaths struct {
 	ModProbe  string `yaml:"modprobe"`
 	SysCtl    string `yaml:"sysctl"`
 	SystemCtl string `yaml:"systemctl"`
+	Nft       string `yaml:"nft,omitempty"`
+	StateDir  string `yaml:"state_dir,omitempty"`
 }
 
 // MARK: Config
@@ -24,6 +31,7 @@ type Config struct {
 	ServicesFile  string          `yaml:"services_file"`
 	WireGuardFile string          `yaml:"wireguard_file"`
 	UpdateFile    string          `yaml:"update_file"`
+	mu            sync.RWMutex
 }
 
 // MARK: UpdateConfig
@@ -36,10 +44,32 @@ type UpdateConfig struct {
 
 // MARK: ServerConfig
 type ServerConfig struct {
-	HTTPAddr   string `yaml:"http_addr"`
-	ProxyAddr  string `yaml:"proxy_addr"`
-	AdminToken string `yaml:"admin_token"`
-	WebRoot    string `yaml:"web_root"`
+	HTTPAddr        string              `yaml:"http_addr"`
+	ProxyAddr       string              `yaml:"proxy_addr"`
+	ProxyTLSAddr    string              `yaml:"proxy_tls_addr,omitempty"`
+	Certificates    []CertificateConfig `yaml:"certificates,omitempty"`
+	BaseDomains     []string            `yaml:"base_domains,omitempty"`
+	HostPrefixMatch bool                `yaml:"host_prefix_match,omitempty"`
+	ACME            *ACMEConfig         `yaml:"acme,omitempty"`
+	AdminToken      string              `yaml:"admin_token"`
+	WebRoot         string              `yaml:"web_root"`
+}
+
+// MARK: ACMEConfig
+type ACMEConfig struct {
+	DirectoryURL string `yaml:"directory_url"`
+	Email        string `yaml:"email,omitempty"`
+	AcceptTOS    bool   `yaml:"accept_tos"`
+	CABundle     string `yaml:"ca_bundle,omitempty"`
+	StateDir     string `yaml:"state_dir,omitempty"`
+	RenewBefore  int    `yaml:"renew_before,omitempty"`
+}
+
+// MARK: CertificateConfig
+type CertificateConfig struct {
+	Hosts    []string `yaml:"hosts,omitempty"`
+	CertFile string   `yaml:"cert_file"`
+	KeyFile  string   `yaml:"key_file"`
 }
 
 // MARK: LogConfig
@@ -49,23 +79,80 @@ type LogConfig struct {
 
 // MARK: WireGuardConfig
 type WireGuardConfig struct {
-	Mode    WireGuardMode  `yaml:"mode"`
-	Paths   WireGuardPaths `yaml:"paths"`
-	Tunnels []TunnelConfig `yaml:"tunnels"`
+	Mode    WireGuardMode       `yaml:"mode"`
+	Paths   WireGuardPaths      `yaml:"paths"`
+	Tunnels []TunnelConfig      `yaml:"tunnels"`
+	Groups  []TunnelGroupConfig `yaml:"groups,omitempty"`
+}
+
+// MARK: TunnelGroupConfig
+type TunnelGroupConfig struct {
+	Name          string   `yaml:"name"`
+	Tunnels       []string `yaml:"tunnels"`
+	FailbackDelay int      `yaml:"failback_delay,omitempty"`
 }
 
 // MARK: TunnelConfig
 type TunnelConfig struct {
-	Name                   string       `yaml:"name"`
-	ListenPort             int          `yaml:"listen_port"`
-	PrivateKey             string       `yaml:"private_key"`
-	MTU                    int          `yaml:"mtu"`
-	Addresses              []string     `yaml:"addresses"`
-	Routes                 []string     `yaml:"routes"`
-	Peers                  []PeerConfig `yaml:"peers"`
-	MonitorInterval        int          `yaml:"monitor_interval"`
-	StaleConnectionTimeout int          `yaml:"stale_connection_timeout"`
-	ReconnectionRetries    int          `yaml:"reconnection_retries"`
+	Name                   string              `yaml:"name"`
+	Enabled                *bool               `yaml:"enabled,omitempty"`
+	ListenPort             int                 `yaml:"listen_port"`
+	PrivateKey             string              `yaml:"private_key"`
+	MTU                    int                 `yaml:"mtu"`
+	Addresses              []string            `yaml:"addresses"`
+	Routes                 []string            `yaml:"routes"`
+	DNS                    []string            `yaml:"dns,omitempty"`
+	Table                  string              `yaml:"table,omitempty"`
+	FwMark                 uint32              `yaml:"fwmark,omitempty"`
+	SaveConfig             bool                `yaml:"save_config,omitempty"`
+	KillSwitch             bool                `yaml:"kill_switch,omitempty"`
+	OnDemand               bool                `yaml:"on_demand,omitempty"`
+	IdleTimeout            int                 `yaml:"idle_timeout,omitempty"`
+	PreUp                  []string            `yaml:"pre_up,omitempty"`
+	PostUp                 []string            `yaml:"post_up,omitempty"`
+	PreDown                []string            `yaml:"pre_down,omitempty"`
+	PostDown               []string            `yaml:"post_down,omitempty"`
+	Server                 *TunnelServerConfig `yaml:"server,omitempty"`
+	Probes                 []ProbeConfig       `yaml:"probes,omitempty"`
+	ACL                    *ACLConfig          `yaml:"acl,omitempty"`
+	Peers                  []PeerConfig        `yaml:"peers"`
+	MonitorInterval        int                 `yaml:"monitor_interval"`
+	StaleConnectionTimeout int                 `yaml:"stale_connection_timeout"`
+	ReconnectionRetries    int                 `yaml:"reconnection_retries"`
+}
+
+// MARK: TunnelServerConfig
+type TunnelServerConfig struct {
+	Endpoint            string   `yaml:"endpoint"`
+	AddressPool         string   `yaml:"address_pool"`
+	ClientAllowedIPs    []string `yaml:"client_allowed_ips,omitempty"`
+	ClientDNS           []string `yaml:"client_dns,omitempty"`
+	PersistentKeepalive int      `yaml:"persistent_keepalive,omitempty"`
+}
+
+// MARK: ProbeConfig
+type ProbeConfig struct {
+	Name             string `yaml:"name,omitempty"`
+	Type             string `yaml:"type"`
+	Target           string `yaml:"target"`
+	Timeout          int    `yaml:"timeout,omitempty"`
+	FailureThreshold int    `yaml:"failure_threshold,omitempty"`
+}
+
+// MARK: ACLConfig
+type ACLConfig struct {
+	Default string    `yaml:"default,omitempty"`
+	Rules   []ACLRule `yaml:"rules"`
+}
+
+// MARK: ACLRule
+type ACLRule struct {
+	Name        string `yaml:"name,omitempty"`
+	Action      string `yaml:"action"`
+	Peer        string `yaml:"peer,omitempty"`
+	Destination string `yaml:"destination,omitempty"`
+	Protocol    string `yaml:"protocol,omitempty"`
+	Port        string `yaml:"port,omitempty"`
 }
 
 // MARK: PeerConfig
@@ -79,15 +166,50 @@ type PeerConfig struct {
 	PersistentKeepaliveInt int      `yaml:"persistent_keepalive_interval"`
 }
 
+// MARK: WgQuickParseError
+type WgQuickParseError struct {
+	Line    int
+	Message string
+}
+
 // MARK: ServiceConfig
 type ServiceConfig struct {
-	Name        string `yaml:"name" json:"name"`
-	Upstream    string `yaml:"upstream" json:"upstream"`
-	Jellyfin    bool   `yaml:"jellyfin" json:"jellyfin"`
-	Websocket   bool   `yaml:"websocket" json:"websocket"`
-	PublishMDNS bool   `yaml:"publish_mdns" json:"publish_mdns"`
-	Default     bool   `yaml:"default" json:"default"`
-	Tunnel      string `yaml:"tunnel" json:"tunnel"`
+	Name           string            `yaml:"name" json:"name"`
+	Upstream       string            `yaml:"upstream" json:"upstream"`
+	Upstreams      []string          `yaml:"upstreams,omitempty" json:"upstreams,omitempty"`
+	LoadBalancing  string            `yaml:"load_balancing,omitempty" json:"load_balancing,omitempty"`
+	StickySessions bool              `yaml:"sticky_sessions,omitempty" json:"sticky_sessions,omitempty"`
+	Hosts          []string          `yaml:"hosts,omitempty" json:"hosts,omitempty"`
+	PathPrefix     string            `yaml:"path_prefix,omitempty" json:"path_prefix,omitempty"`
+	StripPrefix    bool              `yaml:"strip_prefix,omitempty" json:"strip_prefix,omitempty"`
+	Jellyfin       bool              `yaml:"jellyfin" json:"jellyfin"`
+	Websocket      bool              `yaml:"websocket" json:"websocket"`
+	PublishMDNS    bool              `yaml:"publish_mdns" json:"publish_mdns"`
+	Default        bool              `yaml:"default" json:"default"`
+	Tunnel         string            `yaml:"tunnel" json:"tunnel"`
+	BackupTunnels  []string          `yaml:"backup_tunnels,omitempty" json:"backup_tunnels,omitempty"`
+	RequireTunnel  bool              `yaml:"require_tunnel,omitempty" json:"require_tunnel,omitempty"`
+	Enabled        *bool             `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	TLS            *ServiceTLSConfig `yaml:"tls,omitempty" json:"tls,omitempty"`
+}
+
+// MARK: ServiceTLSConfig
+type ServiceTLSConfig struct {
+	CertFile      string   `yaml:"cert_file,omitempty" json:"cert_file,omitempty"`
+	KeyFile       string   `yaml:"key_file,omitempty" json:"key_file,omitempty"`
+	ACME          bool     `yaml:"acme,omitempty" json:"acme,omitempty"`
+	Domains       []string `yaml:"domains,omitempty" json:"domains,omitempty"`
+	RedirectHTTPS bool     `yaml:"redirect_https,omitempty" json:"redirect_https,omitempty"`
+}
+
+// MARK: HostPatternKind
+type HostPatternKind int
+
+// MARK: HostPattern
+type HostPattern struct {
+	Kind  HostPatternKind
+	Value string
+	regex *regexp.Regexp
 }
 
 // MARK: DiscoveryConfig
@@ -17,6 +17,9 @@ func (c *Config) UpdateUpdateConfig(cfg UpdateConfig) error {
 		return fmt.Errorf("invalid cron format, expected 5 fields")
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.Update = cfg
-	return c.SaveUpdate()
+	return c.saveToFile(c.UpdateFile, c.Update)
 }
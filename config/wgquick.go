@@ -0,0 +1,410 @@
+package config
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// MARK: Error
+// Formats the parse error with the offending line number.
+func (e *WgQuickParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// MARK: ParseWgQuickConfig
+// Parses a standard wg-quick .conf file into a tunnel configuration.
+func ParseWgQuickConfig(name string, data string) (TunnelConfig, error) {
+	tunnel := TunnelConfig{Name: name}
+	section := ""
+	interfaceLine := 0
+
+	var peer *PeerConfig
+	var peerName string
+	var peerLine int
+
+	// Missing keys are reported at the header of the section that lacks them
+	finishPeer := func() error {
+		if peer == nil {
+			return nil
+		}
+		if peer.PublicKey == "" {
+			return &WgQuickParseError{Line: peerLine, Message: "[Peer] section missing PublicKey"}
+		}
+		peer.Name = peerName
+		if peer.Name == "" {
+			peer.Name = fmt.Sprintf("peer%d", len(tunnel.Peers)+1)
+		}
+		tunnel.Peers = append(tunnel.Peers, *peer)
+		peer = nil
+		peerName = ""
+		return nil
+	}
+
+	lineNum := 0
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			if section == "peer" {
+				if key, value, ok := splitWgQuickLine(strings.TrimLeft(line, "#; ")); ok && strings.EqualFold(key, "Name") {
+					peerName = value
+				}
+			}
+			continue
+		}
+
+		if comment := strings.Index(line, "#"); comment >= 0 {
+			line = strings.TrimSpace(line[:comment])
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if err := finishPeer(); err != nil {
+				return tunnel, err
+			}
+
+			switch strings.ToLower(line) {
+			case "[interface]":
+				if interfaceLine != 0 {
+					return tunnel, &WgQuickParseError{Line: lineNum, Message: "duplicate [Interface] section"}
+				}
+				interfaceLine = lineNum
+				section = "interface"
+			case "[peer]":
+				section = "peer"
+				peer = &PeerConfig{}
+				peerLine = lineNum
+			default:
+				return tunnel, &WgQuickParseError{Line: lineNum, Message: fmt.Sprintf("unknown section %s", line)}
+			}
+			continue
+		}
+
+		key, value, ok := splitWgQuickLine(line)
+		if !ok {
+			return tunnel, &WgQuickParseError{Line: lineNum, Message: "expected 'Key = Value'"}
+		}
+
+		var err error
+		switch section {
+		case "interface":
+			err = parseWgQuickInterfaceKey(&tunnel, key, value)
+		case "peer":
+			err = parseWgQuickPeerKey(peer, key, value)
+		default:
+			err = fmt.Errorf("%s is outside of a section", key)
+		}
+
+		if err != nil {
+			return tunnel, &WgQuickParseError{Line: lineNum, Message: err.Error()}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return tunnel, fmt.Errorf("reading config: %w", err)
+	}
+
+	if err := finishPeer(); err != nil {
+		return tunnel, err
+	}
+
+	if interfaceLine == 0 {
+		return tunnel, &WgQuickParseError{Line: lineNum, Message: "missing [Interface] section"}
+	}
+	if tunnel.PrivateKey == "" {
+		return tunnel, &WgQuickParseError{Line: interfaceLine, Message: "[Interface] section missing PrivateKey"}
+	}
+
+	if _, manageRoutes, _ := tunnel.RouteTable(); manageRoutes {
+		tunnel.Routes = routesFromAllowedIPs(tunnel.Peers)
+	}
+
+	return tunnel, nil
+}
+
+// MARK: parseWgQuickInterfaceKey
+// Applies a single [Interface] key to the tunnel configuration.
+func parseWgQuickInterfaceKey(tunnel *TunnelConfig, key, value string) error {
+	switch strings.ToLower(key) {
+	case "privatekey":
+		if err := validateWgKey(value); err != nil {
+			return fmt.Errorf("invalid PrivateKey: %w", err)
+		}
+		tunnel.PrivateKey = value
+	case "address":
+		for _, addr := range splitWgQuickList(value) {
+			if _, _, err := net.ParseCIDR(addr); err != nil {
+				if net.ParseIP(addr) == nil {
+					return fmt.Errorf("invalid Address %s", addr)
+				}
+				addr = hostCIDR(addr)
+			}
+			tunnel.Addresses = append(tunnel.Addresses, addr)
+		}
+	case "listenport":
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 0 || port > 65535 {
+			return fmt.Errorf("invalid ListenPort %s", value)
+		}
+		tunnel.ListenPort = port
+	case "mtu":
+		mtu, err := strconv.Atoi(value)
+		if err != nil || mtu < 576 || mtu > 65535 {
+			return fmt.Errorf("invalid MTU %s", value)
+		}
+		tunnel.MTU = mtu
+	case "dns":
+		tunnel.DNS = append(tunnel.DNS, splitWgQuickList(value)...)
+	case "table":
+		tunnel.Table = strings.ToLower(value)
+		if _, _, err := tunnel.RouteTable(); err != nil {
+			return fmt.Errorf("invalid Table: %w", err)
+		}
+	case "fwmark":
+		if strings.EqualFold(value, "off") {
+			tunnel.FwMark = 0
+			return nil
+		}
+		mark, err := strconv.ParseUint(value, 0, 32)
+		if err != nil {
+			return fmt.Errorf("invalid FwMark %s", value)
+		}
+		tunnel.FwMark = uint32(mark)
+	case "saveconfig":
+		save, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid SaveConfig %s", value)
+		}
+		tunnel.SaveConfig = save
+	case "preup":
+		tunnel.PreUp = append(tunnel.PreUp, value)
+	case "postup":
+		tunnel.PostUp = append(tunnel.PostUp, value)
+	case "predown":
+		tunnel.PreDown = append(tunnel.PreDown, value)
+	case "postdown":
+		tunnel.PostDown = append(tunnel.PostDown, value)
+	default:
+		return fmt.Errorf("unsupported [Interface] key %s", key)
+	}
+
+	return nil
+}
+
+// MARK: parseWgQuickPeerKey
+// Applies a single [Peer] key to the peer configuration.
+func parseWgQuickPeerKey(peer *PeerConfig, key, value string) error {
+	switch strings.ToLower(key) {
+	case "publickey":
+		if err := validateWgKey(value); err != nil {
+			return fmt.Errorf("invalid PublicKey: %w", err)
+		}
+		peer.PublicKey = value
+	case "presharedkey":
+		if err := validateWgKey(value); err != nil {
+			return fmt.Errorf("invalid PresharedKey: %w", err)
+		}
+		peer.Preshared = value
+	case "allowedips":
+		for _, ip := range splitWgQuickList(value) {
+			if _, _, err := net.ParseCIDR(ip); err != nil {
+				if net.ParseIP(ip) == nil {
+					return fmt.Errorf("invalid AllowedIPs entry %s", ip)
+				}
+				ip = hostCIDR(ip)
+			}
+			peer.AllowedIPs = append(peer.AllowedIPs, ip)
+		}
+	case "endpoint":
+		host, port, err := net.SplitHostPort(value)
+		if err != nil || host == "" {
+			return fmt.Errorf("invalid Endpoint %s", value)
+		}
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return fmt.Errorf("invalid Endpoint port %s", port)
+		}
+		peer.Endpoint = value
+	case "persistentkeepalive":
+		if strings.EqualFold(value, "off") {
+			return nil
+		}
+		keepalive, err := strconv.Atoi(value)
+		if err != nil || keepalive < 0 || keepalive > 65535 {
+			return fmt.Errorf("invalid PersistentKeepalive %s", value)
+		}
+		peer.Persistent = keepalive > 0
+		peer.PersistentKeepaliveInt = keepalive
+	default:
+		return fmt.Errorf("unsupported [Peer] key %s", key)
+	}
+
+	return nil
+}
+
+// MARK: FormatWgQuickConfig
+// Renders a tunnel configuration as a standard wg-quick .conf file.
+func FormatWgQuickConfig(tunnel TunnelConfig) string {
+	var conf strings.Builder
+
+	conf.WriteString("[Interface]\n")
+	conf.WriteString(fmt.Sprintf("PrivateKey = %s\n", tunnel.PrivateKey))
+
+	for _, addr := range tunnel.Addresses {
+		conf.WriteString(fmt.Sprintf("Address = %s\n", addr))
+	}
+
+	if tunnel.ListenPort > 0 {
+		conf.WriteString(fmt.Sprintf("ListenPort = %d\n", tunnel.ListenPort))
+	}
+
+	if tunnel.MTU > 0 {
+		conf.WriteString(fmt.Sprintf("MTU = %d\n", tunnel.MTU))
+	}
+
+	if len(tunnel.DNS) > 0 {
+		conf.WriteString(fmt.Sprintf("DNS = %s\n", strings.Join(tunnel.DNS, ", ")))
+	}
+
+	if tunnel.Table != "" {
+		conf.WriteString(fmt.Sprintf("Table = %s\n", tunnel.Table))
+	}
+
+	if tunnel.FwMark != 0 {
+		conf.WriteString(fmt.Sprintf("FwMark = 0x%x\n", tunnel.FwMark))
+	}
+
+	if tunnel.SaveConfig {
+		conf.WriteString("SaveConfig = true\n")
+	}
+
+	writeWgQuickHooks(&conf, tunnel)
+
+	for _, peer := range tunnel.Peers {
+		conf.WriteString("\n[Peer]\n")
+		if peer.Name != "" {
+			conf.WriteString(fmt.Sprintf("# Name = %s\n", peer.Name))
+		}
+		conf.WriteString(fmt.Sprintf("PublicKey = %s\n", peer.PublicKey))
+
+		if peer.Preshared != "" {
+			conf.WriteString(fmt.Sprintf("PresharedKey = %s\n", peer.Preshared))
+		}
+
+		if len(peer.AllowedIPs) > 0 {
+			conf.WriteString(fmt.Sprintf("AllowedIPs = %s\n", strings.Join(peer.AllowedIPs, ", ")))
+		}
+
+		if peer.Endpoint != "" {
+			conf.WriteString(fmt.Sprintf("Endpoint = %s\n", peer.Endpoint))
+		}
+
+		if peer.Persistent || peer.PersistentKeepaliveInt > 0 {
+			keepalive := peer.PersistentKeepaliveInt
+			if keepalive <= 0 {
+				keepalive = DefaultKeepalive
+			}
+			conf.WriteString(fmt.Sprintf("PersistentKeepalive = %d\n", keepalive))
+		}
+	}
+
+	return conf.String()
+}
+
+// MARK: writeWgQuickHooks
+// Writes the tunnel's PreUp, PostUp, PreDown and PostDown commands, one line each.
+func writeWgQuickHooks(conf *strings.Builder, tunnel TunnelConfig) {
+	hooks := []struct {
+		key      string
+		commands []string
+	}{
+		{"PreUp", tunnel.PreUp},
+		{"PostUp", tunnel.PostUp},
+		{"PreDown", tunnel.PreDown},
+		{"PostDown", tunnel.PostDown},
+	}
+
+	for _, hook := range hooks {
+		for _, command := range hook.commands {
+			conf.WriteString(fmt.Sprintf("%s = %s\n", hook.key, command))
+		}
+	}
+}
+
+// MARK: splitWgQuickLine
+// Splits a 'Key = Value' line into trimmed key and value.
+func splitWgQuickLine(line string) (string, string, bool) {
+	key, value, found := strings.Cut(line, "=")
+	if !found {
+		return "", "", false
+	}
+
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+	if key == "" {
+		return "", "", false
+	}
+
+	return key, value, true
+}
+
+// MARK: splitWgQuickList
+// Splits a comma separated wg-quick value into trimmed entries.
+func splitWgQuickList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// MARK: validateWgKey
+// Validates that a value is a base64 encoded 32 byte WireGuard key.
+func validateWgKey(key string) error {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return fmt.Errorf("not valid base64")
+	}
+	if len(decoded) != 32 {
+		return fmt.Errorf("must decode to 32 bytes, got %d", len(decoded))
+	}
+	return nil
+}
+
+// MARK: hostCIDR
+// Converts a bare IP address into a single host CIDR.
+func hostCIDR(ip string) string {
+	if strings.Contains(ip, ":") {
+		return ip + "/128"
+	}
+	return ip + "/32"
+}
+
+// MARK: routesFromAllowedIPs
+// Derives tunnel routes from peer allowed IPs, skipping default routes.
+func routesFromAllowedIPs(peers []PeerConfig) []string {
+	seen := make(map[string]bool)
+	var routes []string
+
+	for _, peer := range peers {
+		for _, allowedIP := range peer.AllowedIPs {
+			if allowedIP == "0.0.0.0/0" || allowedIP == "::/0" || seen[allowedIP] {
+				continue
+			}
+			seen[allowedIP] = true
+			routes = append(routes, allowedIP)
+		}
+	}
+
+	return routes
+}
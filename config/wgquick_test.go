@@ -0,0 +1,234 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const (
+	testPrivateKey   = "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE="
+	testPublicKey    = "AgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgI="
+	testPresharedKey = "AwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwM="
+)
+
+func TestParseWgQuickConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    TunnelConfig
+		errLine int
+	}{
+		{
+			name: "full config",
+			data: `[Interface]
+PrivateKey = ` + testPrivateKey + `
+Address = 10.0.0.2/24, fd00::2
+ListenPort = 51820
+MTU = 1420
+DNS = 1.1.1.1, 9.9.9.9
+FwMark = 0x10
+PostUp = iptables -A FORWARD -i %i -j ACCEPT
+
+[Peer]
+# Name = office
+PublicKey = ` + testPublicKey + `
+PresharedKey = ` + testPresharedKey + `
+AllowedIPs = 192.168.1.0/24, 0.0.0.0/0
+Endpoint = vpn.example.com:51820 # trailing comment
+PersistentKeepalive = 25
+`,
+			want: TunnelConfig{
+				Name:       "wg0",
+				PrivateKey: testPrivateKey,
+				Addresses:  []string{"10.0.0.2/24", "fd00::2/128"},
+				ListenPort: 51820,
+				MTU:        1420,
+				DNS:        []string{"1.1.1.1", "9.9.9.9"},
+				FwMark:     0x10,
+				PostUp:     []string{"iptables -A FORWARD -i %i -j ACCEPT"},
+				Routes:     []string{"192.168.1.0/24"},
+				Peers: []PeerConfig{{
+					Name:                   "office",
+					PublicKey:              testPublicKey,
+					Preshared:              testPresharedKey,
+					AllowedIPs:             []string{"192.168.1.0/24", "0.0.0.0/0"},
+					Endpoint:               "vpn.example.com:51820",
+					Persistent:             true,
+					PersistentKeepaliveInt: 25,
+				}},
+			},
+		},
+		{
+			name: "unnamed peer and table off",
+			data: `[Interface]
+PrivateKey = ` + testPrivateKey + `
+Table = off
+
+[Peer]
+PublicKey = ` + testPublicKey + `
+AllowedIPs = 10.1.0.1
+`,
+			want: TunnelConfig{
+				Name:       "wg0",
+				PrivateKey: testPrivateKey,
+				Table:      "off",
+				Peers: []PeerConfig{{
+					Name:       "peer1",
+					PublicKey:  testPublicKey,
+					AllowedIPs: []string{"10.1.0.1/32"},
+				}},
+			},
+		},
+		{
+			name:    "missing interface",
+			data:    "[Peer]\nPublicKey = " + testPublicKey + "\n",
+			errLine: 2,
+		},
+		{
+			name:    "missing private key",
+			data:    "[Interface]\nListenPort = 51820\n",
+			errLine: 1,
+		},
+		{
+			name:    "duplicate interface",
+			data:    "[Interface]\nPrivateKey = " + testPrivateKey + "\n[Interface]\n",
+			errLine: 3,
+		},
+		{
+			name:    "unknown section",
+			data:    "[Interface]\nPrivateKey = " + testPrivateKey + "\n[Other]\n",
+			errLine: 3,
+		},
+		{
+			name:    "key outside section",
+			data:    "PrivateKey = " + testPrivateKey + "\n",
+			errLine: 1,
+		},
+		{
+			name:    "invalid key",
+			data:    "[Interface]\nPrivateKey = not-a-key\n",
+			errLine: 2,
+		},
+		{
+			name:    "invalid endpoint port",
+			data:    "[Interface]\nPrivateKey = " + testPrivateKey + "\n[Peer]\nPublicKey = " + testPublicKey + "\nEndpoint = host:0\n",
+			errLine: 5,
+		},
+		{
+			name:    "peer without public key",
+			data:    "[Interface]\nPrivateKey = " + testPrivateKey + "\n[Peer]\nAllowedIPs = 10.0.0.0/8\n",
+			errLine: 3,
+		},
+		{
+			name:    "peer without public key before the next peer",
+			data:    "[Interface]\nPrivateKey = " + testPrivateKey + "\n[Peer]\nAllowedIPs = 10.0.0.0/8\n\n[Peer]\nPublicKey = " + testPublicKey + "\n",
+			errLine: 3,
+		},
+		{
+			name:    "line without equals",
+			data:    "[Interface]\nPrivateKey\n",
+			errLine: 2,
+		},
+		{
+			name:    "unsupported key",
+			data:    "[Interface]\nPrivateKey = " + testPrivateKey + "\nFoo = bar\n",
+			errLine: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWgQuickConfig("wg0", tt.data)
+			if tt.errLine != 0 {
+				var parseErr *WgQuickParseError
+				if !errors.As(err, &parseErr) {
+					t.Fatalf("expected WgQuickParseError, got %v", err)
+				}
+				if parseErr.Line != tt.errLine {
+					t.Errorf("error line = %d, want %d (%v)", parseErr.Line, tt.errLine, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatWgQuickConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		tunnel TunnelConfig
+		want   string
+	}{
+		{
+			name: "interface only",
+			tunnel: TunnelConfig{
+				PrivateKey: testPrivateKey,
+				Addresses:  []string{"10.0.0.1/24"},
+			},
+			want: "[Interface]\nPrivateKey = " + testPrivateKey + "\nAddress = 10.0.0.1/24\n",
+		},
+		{
+			name: "peer with default keepalive",
+			tunnel: TunnelConfig{
+				PrivateKey: testPrivateKey,
+				ListenPort: 51820,
+				Table:      "off",
+				FwMark:     0x20,
+				PreDown:    []string{"echo down"},
+				Peers: []PeerConfig{{
+					Name:       "laptop",
+					PublicKey:  testPublicKey,
+					AllowedIPs: []string{"10.0.0.2/32", "fd00::2/128"},
+					Endpoint:   "203.0.113.1:51820",
+					Persistent: true,
+				}},
+			},
+			want: "[Interface]\nPrivateKey = " + testPrivateKey + "\nListenPort = 51820\nTable = off\nFwMark = 0x20\nPreDown = echo down\n" +
+				"\n[Peer]\n# Name = laptop\nPublicKey = " + testPublicKey + "\nAllowedIPs = 10.0.0.2/32, fd00::2/128\nEndpoint = 203.0.113.1:51820\nPersistentKeepalive = 25\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatWgQuickConfig(tt.tunnel); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWgQuickRoundTrip(t *testing.T) {
+	tunnel := TunnelConfig{
+		Name:       "wg1",
+		PrivateKey: testPrivateKey,
+		Addresses:  []string{"10.9.0.1/24"},
+		ListenPort: 51821,
+		MTU:        1380,
+		DNS:        []string{"10.9.0.53"},
+		PostUp:     []string{"echo up"},
+		Routes:     []string{"10.9.0.0/24"},
+		Peers: []PeerConfig{{
+			Name:                   "phone",
+			PublicKey:              testPublicKey,
+			Preshared:              testPresharedKey,
+			AllowedIPs:             []string{"10.9.0.0/24"},
+			Persistent:             true,
+			PersistentKeepaliveInt: 15,
+		}},
+	}
+
+	parsed, err := ParseWgQuickConfig("wg1", FormatWgQuickConfig(tunnel))
+	if err != nil {
+		t.Fatalf("parsing formatted config: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, tunnel) {
+		t.Errorf("round trip changed the tunnel:\ngot  %+v\nwant %+v", parsed, tunnel)
+	}
+}
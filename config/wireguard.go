@@ -3,9 +3,12 @@ package config
 import (
 	"fmt"
 	"net"
+	"net/netip"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -14,6 +17,7 @@ const (
 	WireGuardModeWgQuick   WireGuardMode = "wg-quick"
 	WireGuardModeKernel    WireGuardMode = "kernel"
 	WireGuardModeUserspace WireGuardMode = "userspace"
+	WireGuardModeNetstack  WireGuardMode = "netstack"
 	WireGuardModeAuto      WireGuardMode = "auto"
 )
 
@@ -31,6 +35,8 @@ func GetDefaultPaths() WireGuardPaths {
 		ModProbe:  findExecutable("modprobe", []string{"/sbin/modprobe", "/usr/sbin/modprobe"}),
 		SysCtl:    findExecutable("sysctl", []string{"/sbin/sysctl", "/usr/sbin/sysctl"}),
 		SystemCtl: findExecutable("systemctl", []string{"/bin/systemctl", "/usr/bin/systemctl"}),
+		Nft:       findExecutable("nft", []string{"/usr/sbin/nft", "/sbin/nft", "/usr/bin/nft"}),
+		StateDir:  DefaultStateDir,
 	}
 }
 
@@ -107,6 +113,12 @@ func (p *WireGuardPaths) SetDefaults() {
 	if p.SystemCtl == "" {
 		p.SystemCtl = defaults.SystemCtl
 	}
+	if p.Nft == "" {
+		p.Nft = defaults.Nft
+	}
+	if p.StateDir == "" {
+		p.StateDir = defaults.StateDir
+	}
 }
 
 // MARK: GetWireGuardMode
@@ -122,6 +134,8 @@ func (w *WireGuardConfig) GetWireGuardMode() WireGuardMode {
 			return WireGuardModeKernel
 		}
 		return WireGuardModeUserspace
+	case WireGuardModeNetstack:
+		return WireGuardModeNetstack
 	case WireGuardModeAuto:
 		if w.isWgQuickAvailable() {
 			return WireGuardModeWgQuick
@@ -168,17 +182,20 @@ func (w *WireGuardConfig) isKernelWireGuardAvailable() bool {
 		}
 	}
 
-	return w.Paths.ValidatePaths() == nil
+	return true
 }
 
 // MARK: SetWireGuardMode
 func (c *Config) SetWireGuardMode(mode WireGuardMode) error {
 	if mode == WireGuardModeKernel && !c.WireGuard.isKernelWireGuardAvailable() {
-		return fmt.Errorf("kernel WireGuard module not available or tools missing")
+		return fmt.Errorf("kernel WireGuard module not available")
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.WireGuard.Mode = mode
-	return c.SaveWireGuard()
+	return c.saveWireGuard()
 }
 
 // MARK: LoadWireGuardWithDefaults
@@ -210,8 +227,11 @@ func (c *Config) UpdateToolPaths(paths WireGuardPaths) error {
 		return fmt.Errorf("invalid tool paths: %w", err)
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.WireGuard.Paths = paths
-	return c.SaveWireGuard()
+	return c.saveWireGuard()
 }
 
 // MARK: GetToolPath
@@ -282,6 +302,9 @@ func runCommand(name string, args ...string) error {
 // MARK: AddTunnel
 // Adds a new WireGuard tunnel configuration and persists it.
 func (c *Config) AddTunnel(tunnel TunnelConfig) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if err := c.validateTunnelConfig(tunnel); err != nil {
 		return err
 	}
@@ -293,12 +316,41 @@ func (c *Config) AddTunnel(tunnel TunnelConfig) error {
 	}
 
 	c.WireGuard.Tunnels = append(c.WireGuard.Tunnels, tunnel)
-	return c.SaveWireGuard()
+	return c.saveWireGuard()
 }
 
 // MARK: UpdateTunnel
 // Updates an existing WireGuard tunnel configuration and persists it.
 func (c *Config) UpdateTunnel(tunnel TunnelConfig) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.updateTunnel(tunnel)
+}
+
+// MARK: UpdateTunnelFunc
+// Applies a change to a tunnel configuration under the lock so concurrent read-modify-write updates cannot drop each other's changes.
+func (c *Config) UpdateTunnelFunc(name string, change func(*TunnelConfig) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored := c.getTunnel(name)
+	if stored == nil {
+		return fmt.Errorf("tunnel %s not found", name)
+	}
+
+	// The change works on a copy so a rejected update leaves the stored tunnel untouched
+	tunnel := *stored
+	tunnel.Peers = slices.Clone(stored.Peers)
+	if err := change(&tunnel); err != nil {
+		return err
+	}
+
+	return c.updateTunnel(tunnel)
+}
+
+// MARK: updateTunnel
+// Replaces a stored tunnel configuration and persists it. Callers must hold the lock.
+func (c *Config) updateTunnel(tunnel TunnelConfig) error {
 	if err := c.validateTunnelConfig(tunnel); err != nil {
 		return err
 	}
@@ -306,7 +358,7 @@ func (c *Config) UpdateTunnel(tunnel TunnelConfig) error {
 	for i, existing := range c.WireGuard.Tunnels {
 		if strings.EqualFold(existing.Name, tunnel.Name) {
 			c.WireGuard.Tunnels[i] = tunnel
-			return c.SaveWireGuard()
+			return c.saveWireGuard()
 		}
 	}
 
@@ -316,15 +368,33 @@ func (c *Config) UpdateTunnel(tunnel TunnelConfig) error {
 // MARK: RemoveTunnel
 // Removes a WireGuard tunnel configuration by name and persists it.
 func (c *Config) RemoveTunnel(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	for i, tunnel := range c.WireGuard.Tunnels {
 		if strings.EqualFold(tunnel.Name, name) {
-			c.WireGuard.Tunnels = append(c.WireGuard.Tunnels[:i], c.WireGuard.Tunnels[i+1:]...)
-			return c.SaveWireGuard()
+			c.WireGuard.Tunnels = slices.Delete(slices.Clone(c.WireGuard.Tunnels), i, i+1)
+			return c.saveWireGuard()
 		}
 	}
 	return fmt.Errorf("tunnel %s not found", name)
 }
 
+// MARK: SetTunnelEnabled
+// Enables or disables a WireGuard tunnel by name and persists it.
+func (c *Config) SetTunnelEnabled(name string, enabled bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tunnel := c.getTunnel(name)
+	if tunnel == nil {
+		return fmt.Errorf("tunnel %s not found", name)
+	}
+
+	tunnel.Enabled = &enabled
+	return c.saveWireGuard()
+}
+
 // MARK: validateTunnelConfig
 // Validates a single WireGuard tunnel configuration.
 func (c *Config) validateTunnelConfig(tunnel TunnelConfig) error {
@@ -356,6 +426,245 @@ func (c *Config) validateTunnelConfig(tunnel TunnelConfig) error {
 		}
 	}
 
+	if tunnel.Server != nil {
+		if err := c.validateTunnelServerConfig(tunnel); err != nil {
+			return err
+		}
+	}
+
+	if _, _, err := tunnel.RouteTable(); err != nil {
+		return fmt.Errorf("tunnel %s has invalid table: %w", tunnel.Name, err)
+	}
+
+	if tunnel.IdleTimeout < 0 {
+		return fmt.Errorf("tunnel %s idle_timeout cannot be negative", tunnel.Name)
+	}
+
+	for _, probe := range tunnel.Probes {
+		if err := c.validateProbeConfig(probe); err != nil {
+			return fmt.Errorf("invalid probe %s in tunnel %s: %w", probe.Label(), tunnel.Name, err)
+		}
+	}
+
+	if tunnel.ACL != nil {
+		if err := c.validateACLConfig(*tunnel.ACL); err != nil {
+			return fmt.Errorf("invalid acl in tunnel %s: %w", tunnel.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// MARK: validateACLConfig
+// Validates the default action and each rule of a tunnel ACL.
+func (c *Config) validateACLConfig(acl ACLConfig) error {
+	switch strings.ToLower(acl.Default) {
+	case "", ACLActionAllow, ACLActionDeny:
+	default:
+		return fmt.Errorf("default must be allow or deny, got %q", acl.Default)
+	}
+
+	for i, rule := range acl.Rules {
+		if err := validateACLRule(rule); err != nil {
+			return fmt.Errorf("rule %d (%s): %w", i+1, rule.Label(), err)
+		}
+	}
+
+	return nil
+}
+
+// MARK: validateACLRule
+// Validates the action, destination, protocol and port of an ACL rule.
+func validateACLRule(rule ACLRule) error {
+	switch strings.ToLower(rule.Action) {
+	case ACLActionAllow, ACLActionDeny:
+	default:
+		return fmt.Errorf("action must be allow or deny, got %q", rule.Action)
+	}
+
+	if rule.Destination != "" {
+		if _, err := rule.DestinationPrefix(); err != nil {
+			return err
+		}
+	}
+
+	switch rule.ProtocolName() {
+	case ACLProtocolAny, ACLProtocolTCP, ACLProtocolUDP, ACLProtocolICMP:
+	default:
+		return fmt.Errorf("protocol must be any, tcp, udp or icmp, got %q", rule.Protocol)
+	}
+
+	if rule.Port != "" {
+		if proto := rule.ProtocolName(); proto != ACLProtocolTCP && proto != ACLProtocolUDP {
+			return fmt.Errorf("port requires protocol tcp or udp")
+		}
+		if _, _, err := rule.PortRange(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MARK: Label
+// Returns the rule name, falling back to its action and peer.
+func (r ACLRule) Label() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	if r.Peer != "" {
+		return strings.ToLower(r.Action) + " " + r.Peer
+	}
+	return strings.ToLower(r.Action)
+}
+
+// MARK: ProtocolName
+// Returns the lowercased protocol, treating an empty protocol as any.
+func (r ACLRule) ProtocolName() string {
+	if r.Protocol == "" {
+		return ACLProtocolAny
+	}
+	return strings.ToLower(r.Protocol)
+}
+
+// MARK: DestinationPrefix
+// Parses the rule destination as a CIDR, accepting a bare address as a single host.
+func (r ACLRule) DestinationPrefix() (netip.Prefix, error) {
+	if strings.Contains(r.Destination, "/") {
+		prefix, err := netip.ParsePrefix(r.Destination)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid destination %q: %w", r.Destination, err)
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(r.Destination)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid destination %q: %w", r.Destination, err)
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// MARK: PortRange
+// Parses the rule port as a single port or an inclusive low-high range.
+func (r ACLRule) PortRange() (uint16, uint16, error) {
+	lowText, highText, isRange := strings.Cut(r.Port, "-")
+	if !isRange {
+		highText = lowText
+	}
+
+	low, lowErr := strconv.ParseUint(strings.TrimSpace(lowText), 10, 16)
+	high, highErr := strconv.ParseUint(strings.TrimSpace(highText), 10, 16)
+	if lowErr != nil || highErr != nil || low == 0 || high < low {
+		return 0, 0, fmt.Errorf("port must be a port number or a low-high range, got %q", r.Port)
+	}
+
+	return uint16(low), uint16(high), nil
+}
+
+// MARK: DefaultAllows
+// Checks if traffic matching no rule is allowed, which is the default when unset.
+func (a ACLConfig) DefaultAllows() bool {
+	return !strings.EqualFold(a.Default, ACLActionDeny)
+}
+
+// MARK: validateProbeConfig
+// Validates a reachability probe target for its probe type.
+func (c *Config) validateProbeConfig(probe ProbeConfig) error {
+	if probe.Timeout < 0 || probe.FailureThreshold < 0 {
+		return fmt.Errorf("timeout and failure_threshold cannot be negative")
+	}
+
+	switch strings.ToLower(probe.Type) {
+	case ProbeTypeICMP:
+		if net.ParseIP(probe.Target) == nil {
+			return fmt.Errorf("icmp target must be an IP address")
+		}
+	case ProbeTypeTCP:
+		host, port, err := net.SplitHostPort(probe.Target)
+		if err != nil || host == "" {
+			return fmt.Errorf("tcp target must be in format 'host:port'")
+		}
+		if portNum, err := strconv.Atoi(port); err != nil || portNum < 1 || portNum > 65535 {
+			return fmt.Errorf("invalid port number")
+		}
+	case ProbeTypeHTTP:
+		parsed, err := url.Parse(probe.Target)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("http target must be an http:// or https:// URL")
+		}
+	default:
+		return fmt.Errorf("type must be icmp, tcp or http, got %q", probe.Type)
+	}
+
+	return nil
+}
+
+// MARK: Label
+// Returns the probe name, falling back to its type and target.
+func (p ProbeConfig) Label() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return strings.ToLower(p.Type) + ":" + p.Target
+}
+
+// MARK: RouteTable
+// Returns the routing table id for tunnel routes and whether routes should be installed at all.
+func (t TunnelConfig) RouteTable() (int, bool, error) {
+	switch strings.ToLower(t.Table) {
+	case "", "auto", "main":
+		return 0, true, nil
+	case "off":
+		return 0, false, nil
+	}
+
+	table, err := strconv.ParseUint(t.Table, 10, 32)
+	if err != nil || table == 0 {
+		return 0, false, fmt.Errorf("table must be auto, off or a table number, got %q", t.Table)
+	}
+
+	return int(table), true, nil
+}
+
+// MARK: HasHooks
+// Checks if the tunnel defines any PreUp, PostUp, PreDown or PostDown commands.
+func (t TunnelConfig) HasHooks() bool {
+	return len(t.PreUp)+len(t.PostUp)+len(t.PreDown)+len(t.PostDown) > 0
+}
+
+// MARK: IsEnabled
+// Checks if the tunnel should be started, treating an unset flag as enabled.
+func (t TunnelConfig) IsEnabled() bool {
+	return t.Enabled == nil || *t.Enabled
+}
+
+// MARK: validateTunnelServerConfig
+// Validates the server mode settings of a tunnel.
+func (c *Config) validateTunnelServerConfig(tunnel TunnelConfig) error {
+	server := tunnel.Server
+
+	if tunnel.ListenPort == 0 {
+		return fmt.Errorf("tunnel %s in server mode requires a listen_port", tunnel.Name)
+	}
+
+	if server.Endpoint == "" {
+		return fmt.Errorf("tunnel %s in server mode requires an endpoint", tunnel.Name)
+	}
+	if err := c.validateEndpoint(server.Endpoint); err != nil {
+		return fmt.Errorf("invalid server endpoint %s in tunnel %s: %w", server.Endpoint, tunnel.Name, err)
+	}
+
+	if _, _, err := net.ParseCIDR(server.AddressPool); err != nil {
+		return fmt.Errorf("invalid address_pool %s in tunnel %s: %w", server.AddressPool, tunnel.Name, err)
+	}
+
+	for _, ip := range server.ClientAllowedIPs {
+		if _, _, err := net.ParseCIDR(ip); err != nil {
+			return fmt.Errorf("invalid client_allowed_ip %s in tunnel %s: %w", ip, tunnel.Name, err)
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,331 @@
+package failover
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/JPKribs/FinGuard/config"
+	"github.com/JPKribs/FinGuard/internal"
+	"github.com/JPKribs/FinGuard/proxy"
+	"github.com/JPKribs/FinGuard/wireguard"
+)
+
+const (
+	checkInterval = 10 * time.Second
+	updateTimeout = 30 * time.Second
+)
+
+// Controller lifecycle functions
+
+// MARK: NewController
+// Creates a failover controller that moves services between tunnels based on tunnel health
+func NewController(cfg *config.Config, tunnels wireguard.TunnelManager, proxyServer *proxy.Server, logger *internal.Logger) *Controller {
+	if logger == nil {
+		logger = &internal.Logger{}
+	}
+
+	return &Controller{
+		cfg:          cfg,
+		tunnels:      tunnels,
+		proxyServer:  proxyServer,
+		logger:       logger,
+		services:     make(map[string]*serviceState),
+		healthySince: make(map[string]time.Time),
+	}
+}
+
+// MARK: Start
+// Starts periodic health evaluation of every service with backup tunnels
+func (c *Controller) Start(ctx context.Context) {
+	ctx, c.cancel = context.WithCancel(ctx)
+
+	// Settle every service on a tunnel right away so dials find it cached instead of waiting for the first tick
+	c.evaluate(ctx)
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			c.evaluate(ctx)
+		}
+	}()
+}
+
+// MARK: SetConfig
+// Points the controller at a reloaded configuration
+func (c *Controller) SetConfig(cfg *config.Config) {
+	c.mu.Lock()
+	c.cfg = cfg
+	c.mu.Unlock()
+}
+
+// MARK: currentConfig
+// Returns the configuration the controller is working from
+func (c *Controller) currentConfig() *config.Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cfg
+}
+
+// MARK: Stop
+// Stops health evaluation and waits for any in-flight switch to finish
+func (c *Controller) Stop() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.wg.Wait()
+}
+
+// Selection functions
+
+// MARK: ActiveTunnel
+// Returns the tunnel a service should currently dial through, without blocking on DNS once a service is known
+func (c *Controller) ActiveTunnel(svc config.ServiceConfig) string {
+	key := strings.ToLower(svc.Name)
+
+	c.mu.RLock()
+	state, exists := c.services[key]
+	c.mu.RUnlock()
+
+	if exists {
+		return state.active
+	}
+
+	cfg := c.currentConfig()
+	chain := cfg.ServiceTunnels(svc)
+	if len(chain) == 0 {
+		return svc.Tunnel
+	}
+	if len(chain) == 1 {
+		return chain[0]
+	}
+
+	// Resume on whichever tunnel kept the route across restarts. The lookup may resolve the
+	// upstream, so the answer is cached until the next evaluation takes over.
+	active := chain[0]
+	if holder := cfg.ServiceRouteHolder(svc); holder != "" {
+		active = holder
+	}
+
+	c.mu.Lock()
+	if state, exists := c.services[key]; exists {
+		active = state.active
+	} else {
+		c.services[key] = &serviceState{active: active, since: time.Now()}
+	}
+	c.mu.Unlock()
+
+	return active
+}
+
+// MARK: Status
+// Returns the failover state of a service
+func (c *Controller) Status(svc config.ServiceConfig) ServiceStatus {
+	chain := c.currentConfig().ServiceTunnels(svc)
+	status := ServiceStatus{
+		Service: svc.Name,
+		Active:  c.ActiveTunnel(svc),
+		Tunnels: chain,
+	}
+
+	c.mu.RLock()
+	if state, exists := c.services[strings.ToLower(svc.Name)]; exists {
+		status.Since = state.since
+	}
+	c.mu.RUnlock()
+
+	status.FailedOver = len(chain) > 0 && !strings.EqualFold(status.Active, chain[0])
+	return status
+}
+
+// MARK: evaluate
+// Checks tunnel health and switches services whose active tunnel should change
+func (c *Controller) evaluate(ctx context.Context) {
+	statuses, err := c.tunnels.ListTunnels(ctx)
+	if err != nil {
+		c.logger.Debug("Failed to list tunnels for failover", "error", err)
+		return
+	}
+
+	cfg := c.currentConfig()
+	now := time.Now()
+	healthy := make(map[string]bool, len(statuses))
+
+	listed := make(map[string]bool, len(statuses))
+
+	c.mu.Lock()
+	for _, status := range statuses {
+		name := strings.ToLower(status.Name)
+		listed[name] = true
+		if status.Healthy() {
+			healthy[name] = true
+			if _, exists := c.healthySince[name]; !exists {
+				c.healthySince[name] = now
+			}
+		} else {
+			delete(c.healthySince, name)
+		}
+	}
+	// Idle on-demand tunnels come up when a request needs them, so they stay eligible unless disabled
+	for _, tunnel := range cfg.TunnelList() {
+		name := strings.ToLower(tunnel.Name)
+		if tunnel.OnDemand && tunnel.IsEnabled() && !listed[name] {
+			healthy[name] = true
+			if _, exists := c.healthySince[name]; !exists {
+				c.healthySince[name] = now
+			}
+		}
+	}
+	healthySince := make(map[string]time.Time, len(c.healthySince))
+	for name, since := range c.healthySince {
+		healthySince[name] = since
+	}
+	c.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, svc := range cfg.ServiceList() {
+		chain := cfg.ServiceTunnels(svc)
+		if len(chain) < 2 {
+			continue
+		}
+
+		key := strings.ToLower(svc.Name)
+		seen[key] = true
+
+		current := c.ActiveTunnel(svc)
+		target := selectTunnel(chain, current, healthy, healthySince, cfg.ServiceFailbackDelay(svc), now)
+
+		if !strings.EqualFold(target, current) {
+			c.switchTunnel(ctx, cfg, svc, chain, current, target)
+			continue
+		}
+
+		c.mu.Lock()
+		if _, exists := c.services[key]; !exists {
+			c.services[key] = &serviceState{active: current, since: now}
+		}
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	for key := range c.services {
+		if !seen[key] {
+			delete(c.services, key)
+		}
+	}
+	c.mu.Unlock()
+}
+
+// MARK: selectTunnel
+// Picks the tunnel a service should use, failing over immediately and failing back only after the delay
+func selectTunnel(chain []string, current string, healthy map[string]bool, healthySince map[string]time.Time, failbackDelay time.Duration, now time.Time) string {
+	currentIndex := len(chain)
+	for i, name := range chain {
+		if strings.EqualFold(name, current) {
+			currentIndex = i
+			break
+		}
+	}
+
+	// Prefer a higher priority tunnel once it has been healthy for the whole failback delay
+	for i := 0; i < currentIndex; i++ {
+		name := strings.ToLower(chain[i])
+		if healthy[name] && now.Sub(healthySince[name]) >= failbackDelay {
+			return chain[i]
+		}
+	}
+
+	if currentIndex < len(chain) && healthy[strings.ToLower(current)] {
+		return current
+	}
+
+	for _, name := range chain {
+		if healthy[strings.ToLower(name)] {
+			return name
+		}
+	}
+
+	// Nothing is healthy, stay put rather than flapping between dead tunnels
+	if currentIndex < len(chain) {
+		return current
+	}
+	return chain[0]
+}
+
+// Switching functions
+
+// MARK: switchTunnel
+// Moves a service's route and dialing from one tunnel to another
+func (c *Controller) switchTunnel(ctx context.Context, cfg *config.Config, svc config.ServiceConfig, chain []string, from, to string) {
+	if strings.EqualFold(to, chain[0]) {
+		c.logger.Info("Failing service back to primary tunnel", "service", svc.Name, "from", from, "to", to)
+	} else {
+		c.logger.Warn("Failing service over to backup tunnel", "service", svc.Name, "from", from, "to", to)
+	}
+
+	if from != "" {
+		if changed, err := cfg.RemoveServiceRoute(svc, from, c.skipUpstream(svc)); err != nil {
+			c.logger.Error("Failed to remove service route", "service", svc.Name, "tunnel", from, "error", err)
+		} else if changed {
+			c.applyTunnel(ctx, cfg, from)
+		}
+	}
+
+	if changed, err := cfg.AddServiceRoute(svc, to, c.skipUpstream(svc)); err != nil {
+		c.logger.Error("Failed to add service route", "service", svc.Name, "tunnel", to, "error", err)
+	} else if changed {
+		c.applyTunnel(ctx, cfg, to)
+	}
+
+	c.mu.Lock()
+	c.services[strings.ToLower(svc.Name)] = &serviceState{active: to, since: time.Now()}
+	c.mu.Unlock()
+
+	if c.proxyServer != nil {
+		c.proxyServer.ResetServiceConnections(svc.Name)
+	}
+}
+
+// MARK: skipUpstream
+// Returns a callback that logs service upstreams whose host route could not be resolved
+func (c *Controller) skipUpstream(svc config.ServiceConfig) func(string, error) {
+	return func(upstream string, err error) {
+		c.logger.Warn("Skipping unresolvable service upstream", "service", svc.Name, "upstream", upstream, "error", err)
+	}
+}
+
+// MARK: applyTunnel
+// Pushes a tunnel's saved configuration to the running tunnel, or just its kill switch when it is down
+func (c *Controller) applyTunnel(ctx context.Context, cfg *config.Config, name string) {
+	tunnelConfig := cfg.GetTunnel(name)
+	if tunnelConfig == nil {
+		return
+	}
+
+	status, err := c.tunnels.Status(ctx, tunnelConfig.Name)
+	if err != nil || status.State != "running" {
+		// A tunnel that is down keeps its kill switch, which would otherwise still reject the routes that just left it
+		if err := c.tunnels.ApplyKillSwitch(*tunnelConfig); err != nil {
+			c.logger.Error("Failed to apply tunnel kill switch", "tunnel", tunnelConfig.Name, "error", err)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	if err := c.tunnels.UpdateTunnel(ctx, *tunnelConfig); err != nil {
+		c.logger.Error("Failed to apply tunnel routes", "tunnel", tunnelConfig.Name, "error", err)
+	}
+}
@@ -0,0 +1,40 @@
+package failover
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/JPKribs/FinGuard/config"
+	"github.com/JPKribs/FinGuard/internal"
+	"github.com/JPKribs/FinGuard/proxy"
+	"github.com/JPKribs/FinGuard/wireguard"
+)
+
+// MARK: Controller
+type Controller struct {
+	cfg          *config.Config
+	tunnels      wireguard.TunnelManager
+	proxyServer  *proxy.Server
+	logger       *internal.Logger
+	services     map[string]*serviceState
+	healthySince map[string]time.Time
+	mu           sync.RWMutex
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+// MARK: serviceState
+type serviceState struct {
+	active string
+	since  time.Time
+}
+
+// MARK: ServiceStatus
+type ServiceStatus struct {
+	Service    string    `json:"service"`
+	Active     string    `json:"active"`
+	Tunnels    []string  `json:"tunnels"`
+	FailedOver bool      `json:"failed_over"`
+	Since      time.Time `json:"since"`
+}
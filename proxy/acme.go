@@ -0,0 +1,569 @@
+package proxy
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/JPKribs/FinGuard/config"
+	"github.com/JPKribs/FinGuard/internal"
+	"golang.org/x/crypto/acme"
+)
+
+const (
+	acmeChallengePrefix = "/.well-known/acme-challenge/"
+	acmeAccountKeyFile  = "account.key"
+	acmeCertificateDir  = "certificates"
+	acmeStateMode       = 0700
+	acmeKeyMode         = 0600
+	acmeCertMode        = 0644
+	acmeOrderTimeout    = 5 * time.Minute
+	acmeCheckInterval   = 12 * time.Hour
+	acmeRetryMin        = 5 * time.Minute
+	acmeRetryMax        = 6 * time.Hour
+)
+
+// ACME issuer functions
+
+// MARK: EnableACME
+// Starts issuing and renewing certificates for services that request them from the ACME directory
+func (s *Server) EnableACME(ctx context.Context, cfg config.ACMEConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.acme != nil {
+		return fmt.Errorf("acme already enabled")
+	}
+
+	issuer, err := newACMEIssuer(s.logger, cfg, s.certificates)
+	if err != nil {
+		return err
+	}
+	s.acme = issuer
+
+	// Services added before ACME was enabled are picked up now
+	for _, service := range s.services {
+		if service.Config.UsesACME() {
+			s.updateServiceCertificate(service.Config)
+		}
+	}
+
+	go issuer.run(ctx)
+
+	s.logger.Info("ACME certificate issuance enabled", "directory", cfg.DirectoryURL, "state_dir", cfg.StateDir)
+	return nil
+}
+
+// MARK: serveACMEChallenge
+// Answers HTTP-01 validation requests for tokens of orders in progress, reporting whether the request was handled
+func (s *Server) serveACMEChallenge(w http.ResponseWriter, r *http.Request) bool {
+	if !strings.HasPrefix(r.URL.Path, acmeChallengePrefix) {
+		return false
+	}
+
+	s.mu.RLock()
+	issuer := s.acme
+	s.mu.RUnlock()
+
+	if issuer == nil {
+		return false
+	}
+
+	// Unknown tokens fall through so upstreams can still answer their own challenges
+	keyAuth, ok := issuer.tokens.Load(strings.TrimPrefix(r.URL.Path, acmeChallengePrefix))
+	if !ok {
+		return false
+	}
+
+	s.logger.Debug("Answering ACME challenge", "host", r.Host, "remote", s.getClientIP(r))
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(keyAuth.(string)))
+	return true
+}
+
+// MARK: newACMEIssuer
+// Prepares the state directory, account key and directory client for issuing certificates
+func newACMEIssuer(logger *internal.Logger, cfg config.ACMEConfig, certificates *certificateStore) (*acmeIssuer, error) {
+	if err := os.MkdirAll(filepath.Join(cfg.StateDir, acmeCertificateDir), acmeStateMode); err != nil {
+		return nil, fmt.Errorf("creating acme state directory: %w", err)
+	}
+
+	key, err := loadACMEAccountKey(filepath.Join(cfg.StateDir, acmeAccountKeyFile))
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient, err := acmeHTTPClient(cfg.CABundle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &acmeIssuer{
+		logger:       logger,
+		config:       cfg,
+		certificates: certificates,
+		client: &acme.Client{
+			Key:          key,
+			DirectoryURL: cfg.DirectoryURL,
+			HTTPClient:   httpClient,
+			UserAgent:    "FinGuard",
+		},
+		managed: make(map[string]*acmeCertificate),
+		wake:    make(chan struct{}, 1),
+	}, nil
+}
+
+// MARK: loadACMEAccountKey
+// Reads the account key from the state directory, creating one on first use
+func loadACMEAccountKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM data in acme account key %s", path)
+		}
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing acme account key %s: %w", path, err)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading acme account key: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating acme account key: %w", err)
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("encoding acme account key: %w", err)
+	}
+
+	if err := writeStateFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), acmeKeyMode); err != nil {
+		return nil, fmt.Errorf("writing acme account key: %w", err)
+	}
+
+	return key, nil
+}
+
+// MARK: acmeHTTPClient
+// Builds the client used to talk to the ACME directory, trusting the extra CA bundle if one is set
+func acmeHTTPClient(caBundle string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if caBundle != "" {
+		data, err := os.ReadFile(caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("reading acme ca_bundle: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in acme ca_bundle %s", caBundle)
+		}
+
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: pool}
+	}
+
+	return &http.Client{Transport: transport, Timeout: 30 * time.Second}, nil
+}
+
+// MARK: manage
+// Tracks the domains a service wants a certificate for and returns where that certificate is stored
+func (i *acmeIssuer) manage(name string, requested []string) (string, string) {
+	domains := make([]string, 0, len(requested))
+	for _, domain := range requested {
+		domain = config.NormalizeHost(domain)
+		if domain != "" && !slices.Contains(domains, domain) {
+			domains = append(domains, domain)
+		}
+	}
+
+	base := filepath.Join(i.config.StateDir, acmeCertificateDir, acmeFileName(name))
+	cert := &acmeCertificate{
+		name:     name,
+		domains:  domains,
+		certFile: base + ".crt",
+		keyFile:  base + ".key",
+	}
+
+	i.mu.Lock()
+	// Keep the backoff of an unchanged service so re-adding it does not hammer the CA
+	if existing, ok := i.managed[name]; ok && slices.Equal(existing.domains, domains) {
+		cert.failures = existing.failures
+		cert.retryAt = existing.retryAt
+	}
+	i.managed[name] = cert
+	i.mu.Unlock()
+
+	select {
+	case i.wake <- struct{}{}:
+	default:
+	}
+
+	return cert.certFile, cert.keyFile
+}
+
+// MARK: acmeFileName
+// Turns a service name into a file name that stays inside the certificate directory
+func acmeFileName(name string) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '-'
+	}, name)
+
+	if slug == name {
+		return name
+	}
+
+	// A hash of the original keeps names that slug the same apart
+	sum := sha256.Sum256([]byte(name))
+	return slug + "-" + hex.EncodeToString(sum[:4])
+}
+
+// MARK: release
+// Stops renewing the certificate of a removed service, leaving its files for reuse
+func (i *acmeIssuer) release(name string) {
+	i.mu.Lock()
+	delete(i.managed, name)
+	i.mu.Unlock()
+}
+
+// MARK: run
+// Issues and renews certificates as they come due until the context ends
+func (i *acmeIssuer) run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-i.wake:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		}
+
+		next := i.renewDue(ctx)
+		timer.Reset(time.Until(next))
+	}
+}
+
+// MARK: renewDue
+// Issues every certificate that is missing, outdated or inside its renewal window, returning when to check again
+func (i *acmeIssuer) renewDue(ctx context.Context) time.Time {
+	i.mu.Lock()
+	certs := make([]*acmeCertificate, 0, len(i.managed))
+	for _, cert := range i.managed {
+		certs = append(certs, cert)
+	}
+	i.mu.Unlock()
+
+	now := time.Now()
+	next := now.Add(acmeCheckInterval)
+
+	for _, cert := range certs {
+		if ctx.Err() != nil {
+			return next
+		}
+
+		i.mu.Lock()
+		retryAt := cert.retryAt
+		i.mu.Unlock()
+
+		if now.Before(retryAt) {
+			next = earliest(next, retryAt)
+			continue
+		}
+
+		renewAt, reason := cert.renewAt(i.renewBefore())
+		if now.Before(renewAt) {
+			next = earliest(next, renewAt)
+			continue
+		}
+
+		i.logger.Info("Requesting ACME certificate", "service", cert.name, "domains", cert.domains, "reason", reason)
+
+		leaf, err := i.obtain(ctx, cert)
+
+		i.mu.Lock()
+		if err != nil {
+			cert.failures++
+			cert.retryAt = time.Now().Add(min(acmeRetryMin<<(cert.failures-1), acmeRetryMax))
+		} else {
+			cert.failures = 0
+			cert.retryAt = time.Time{}
+		}
+		retryAt = cert.retryAt
+		i.mu.Unlock()
+
+		if err != nil {
+			i.logger.Error("Failed to obtain ACME certificate", "service", cert.name, "domains", cert.domains, "retry_at", retryAt, "error", err)
+			next = earliest(next, retryAt)
+			continue
+		}
+
+		i.certificates.reloadChanged()
+
+		renewAt, _ = cert.renewAt(i.renewBefore())
+		i.logger.Info("Issued ACME certificate", "service", cert.name, "domains", cert.domains, "expires", leaf.NotAfter, "renew_at", renewAt)
+		next = earliest(next, renewAt)
+	}
+
+	return next
+}
+
+// MARK: renewBefore
+// Returns how long before expiry certificates are renewed
+func (i *acmeIssuer) renewBefore() time.Duration {
+	return time.Duration(i.config.RenewBefore) * 24 * time.Hour
+}
+
+// MARK: register
+// Creates the ACME account for the account key, or looks up the existing one
+func (i *acmeIssuer) register(ctx context.Context) error {
+	if i.registered {
+		return nil
+	}
+
+	account := &acme.Account{}
+	if i.config.Email != "" {
+		account.Contact = []string{"mailto:" + i.config.Email}
+	}
+
+	if _, err := i.client.Register(ctx, account, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return fmt.Errorf("registering acme account: %w", err)
+	}
+
+	i.registered = true
+	return nil
+}
+
+// MARK: obtain
+// Orders a certificate for the service domains, proving control of each through HTTP-01
+func (i *acmeIssuer) obtain(ctx context.Context, cert *acmeCertificate) (*x509.Certificate, error) {
+	ctx, cancel := context.WithTimeout(ctx, acmeOrderTimeout)
+	defer cancel()
+
+	if err := i.register(ctx); err != nil {
+		return nil, err
+	}
+
+	order, err := i.client.AuthorizeOrder(ctx, acme.DomainIDs(cert.domains...))
+	if err != nil {
+		return nil, fmt.Errorf("creating order: %w", err)
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		if err := i.authorize(ctx, authzURL); err != nil {
+			return nil, err
+		}
+	}
+
+	order, err = i.client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return nil, fmt.Errorf("waiting for order: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating certificate key: %w", err)
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: cert.domains[0]},
+		DNSNames: cert.domains,
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("creating certificate request: %w", err)
+	}
+
+	chain, _, err := i.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, fmt.Errorf("finalizing order: %w", err)
+	}
+
+	return cert.store(chain, key)
+}
+
+// MARK: authorize
+// Completes an HTTP-01 challenge for one authorization of an order
+func (i *acmeIssuer) authorize(ctx context.Context, authzURL string) error {
+	authz, err := i.client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("fetching authorization: %w", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var challenge *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == "http-01" {
+			challenge = c
+			break
+		}
+	}
+	if challenge == nil {
+		return fmt.Errorf("no http-01 challenge offered for %s", authz.Identifier.Value)
+	}
+
+	keyAuth, err := i.client.HTTP01ChallengeResponse(challenge.Token)
+	if err != nil {
+		return fmt.Errorf("computing challenge response for %s: %w", authz.Identifier.Value, err)
+	}
+
+	i.tokens.Store(challenge.Token, keyAuth)
+	defer i.tokens.Delete(challenge.Token)
+
+	if _, err := i.client.Accept(ctx, challenge); err != nil {
+		return fmt.Errorf("accepting challenge for %s: %w", authz.Identifier.Value, err)
+	}
+
+	if _, err := i.client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("validating %s: %w", authz.Identifier.Value, err)
+	}
+
+	return nil
+}
+
+// MARK: renewAt
+// Returns when the stored certificate should be renewed and why, which is now if it is missing or covers other domains
+func (c *acmeCertificate) renewAt(renewBefore time.Duration) (time.Time, string) {
+	data, err := os.ReadFile(c.certFile)
+	if err != nil {
+		return time.Time{}, "missing"
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return time.Time{}, "unreadable"
+	}
+
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, "unreadable"
+	}
+
+	if _, err := os.Stat(c.keyFile); err != nil {
+		return time.Time{}, "missing key"
+	}
+
+	names := slices.Clone(leaf.DNSNames)
+	slices.Sort(names)
+	wanted := slices.Clone(c.domains)
+	slices.Sort(wanted)
+	if !slices.Equal(names, wanted) {
+		return time.Time{}, "domains changed"
+	}
+
+	// Short-lived certificates renew a third of the way before expiry instead of the configured window
+	window := min(renewBefore, leaf.NotAfter.Sub(leaf.NotBefore)/3)
+	return leaf.NotAfter.Add(-window), "expiring"
+}
+
+// MARK: store
+// Writes the issued chain and its key to the state directory, key first so the pair is never left mismatched for long
+func (c *acmeCertificate) store(chain [][]byte, key *ecdsa.PrivateKey) (*x509.Certificate, error) {
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("acme directory returned an empty certificate chain")
+	}
+
+	leaf, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return nil, fmt.Errorf("parsing issued certificate: %w", err)
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("encoding certificate key: %w", err)
+	}
+
+	var certPEM []byte
+	for _, cert := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})...)
+	}
+
+	if err := writeStateFile(c.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), acmeKeyMode); err != nil {
+		return nil, fmt.Errorf("writing certificate key: %w", err)
+	}
+	if err := writeStateFile(c.certFile, certPEM, acmeCertMode); err != nil {
+		return nil, fmt.Errorf("writing certificate: %w", err)
+	}
+
+	return leaf, nil
+}
+
+// MARK: writeStateFile
+// Atomically replaces a file in the ACME state directory
+func writeStateFile(path string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
+
+// MARK: earliest
+// Returns the earlier of two times
+func earliest(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
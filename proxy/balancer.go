@@ -0,0 +1,230 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/JPKribs/FinGuard/config"
+)
+
+const (
+	maxPassiveFailures = 3
+	ejectionDuration   = 30 * time.Second
+	stickyCookiePrefix = "finguard_"
+)
+
+// Load balancing functions
+
+// MARK: newUpstreamTargets
+// Parses every upstream of a service into a balancing target, keeping the configured priority order
+func newUpstreamTargets(svc config.ServiceConfig) ([]*upstreamTarget, error) {
+	upstreams := svc.UpstreamTargets()
+	targets := make([]*upstreamTarget, 0, len(upstreams))
+
+	for _, upstream := range upstreams {
+		parsed, err := url.Parse(upstream)
+		if err != nil {
+			return nil, fmt.Errorf("parsing upstream URL %s: %w", upstream, err)
+		}
+
+		sum := sha256.Sum256([]byte(parsed.String()))
+		targets = append(targets, &upstreamTarget{
+			url:    parsed,
+			id:     hex.EncodeToString(sum[:6]),
+			health: ServiceHealth{Healthy: true, LastCheck: time.Now()},
+		})
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("service %s has no upstreams", svc.Name)
+	}
+
+	return targets, nil
+}
+
+// MARK: selectTarget
+// Picks the upstream for a request, honouring a sticky session cookie and setting a new one when the choice changes
+func (p *ProxyService) selectTarget(w http.ResponseWriter, r *http.Request) *upstreamTarget {
+	now := time.Now()
+
+	if !p.Config.StickySessions {
+		return p.pickTarget(now)
+	}
+
+	cookieName := stickyCookieName(p.Config)
+	if cookie, err := r.Cookie(cookieName); err == nil {
+		for _, target := range p.Targets {
+			if target.id == cookie.Value && target.available(now) {
+				return target
+			}
+		}
+	}
+
+	target := p.pickTarget(now)
+	path := p.Config.RoutePrefix()
+	if path == "" {
+		path = "/"
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    target.id,
+		Path:     path,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return target
+}
+
+// MARK: pickTarget
+// Applies the service load balancing policy to the available upstreams, falling back to all of them when none are available
+func (p *ProxyService) pickTarget(now time.Time) *upstreamTarget {
+	if len(p.Targets) == 1 {
+		return p.Targets[0]
+	}
+
+	available := make([]*upstreamTarget, 0, len(p.Targets))
+	for _, target := range p.Targets {
+		if target.available(now) {
+			available = append(available, target)
+		}
+	}
+
+	// Failing open keeps the service reachable when health checks are wrong about every upstream
+	if len(available) == 0 {
+		available = p.Targets
+	}
+
+	switch p.Config.LoadBalancingPolicy() {
+	case config.LoadBalanceFailover:
+		return available[0]
+
+	case config.LoadBalanceLeastConnections:
+		// Starting from a rotating offset spreads ties instead of always favouring the first upstream
+		start := int((p.next.Add(1) - 1) % uint64(len(available)))
+		best := available[start]
+		for i := 1; i < len(available); i++ {
+			target := available[(start+i)%len(available)]
+			if target.active.Load() < best.active.Load() {
+				best = target
+			}
+		}
+		return best
+
+	default:
+		return available[int((p.next.Add(1)-1)%uint64(len(available)))]
+	}
+}
+
+// MARK: targetFor
+// Returns the upstream chosen for a request, or the primary upstream when none was recorded
+func (p *ProxyService) targetFor(r *http.Request) *upstreamTarget {
+	if r != nil {
+		if target, ok := r.Context().Value(upstreamContextKey{}).(*upstreamTarget); ok {
+			return target
+		}
+	}
+	return p.Targets[0]
+}
+
+// MARK: UpstreamStatuses
+// Returns the health, ejection state and open requests of every upstream of the service
+func (p *ProxyService) UpstreamStatuses() []UpstreamStatus {
+	now := time.Now()
+	statuses := make([]UpstreamStatus, 0, len(p.Targets))
+
+	for _, target := range p.Targets {
+		target.mu.Lock()
+		statuses = append(statuses, UpstreamStatus{
+			URL:               target.url.String(),
+			Ejected:           now.Before(target.ejectedUntil),
+			ActiveConnections: target.active.Load(),
+			ServiceHealth:     target.health,
+		})
+		target.mu.Unlock()
+	}
+
+	return statuses
+}
+
+// MARK: withTarget
+// Stores the chosen upstream in the request context so the proxy hooks can find it
+func withTarget(r *http.Request, target *upstreamTarget) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), upstreamContextKey{}, target))
+}
+
+// MARK: stickyCookieName
+// Returns the session affinity cookie name for a service
+func stickyCookieName(svc config.ServiceConfig) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(svc.Name))
+	return stickyCookiePrefix + name
+}
+
+// MARK: available
+// Checks if an upstream passed its last health check and is not ejected after passive failures
+func (t *upstreamTarget) available(now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.health.Healthy && !now.Before(t.ejectedUntil)
+}
+
+// MARK: recordSuccess
+// Resets the passive failure count of an upstream after it answered a request
+func (t *upstreamTarget) recordSuccess() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.failures = 0
+}
+
+// MARK: recordFailure
+// Counts a failed request against an upstream and ejects it for a while once too many fail in a row, reporting whether it was ejected
+func (t *upstreamTarget) recordFailure() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.failures++
+	if t.failures < maxPassiveFailures {
+		return false
+	}
+
+	t.failures = 0
+	t.ejectedUntil = time.Now().Add(ejectionDuration)
+	return true
+}
+
+// MARK: recordCheck
+// Stores the result of an active health check, clearing any ejection once the upstream answers again, and reports whether it recovered
+func (t *upstreamTarget) recordCheck(checkErr string, now time.Time) (healthy bool, recovered bool, consecutive int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.health.LastCheck = now
+
+	if checkErr != "" {
+		t.health.Healthy = false
+		t.health.Consecutive++
+		t.health.LastError = checkErr
+		return false, false, t.health.Consecutive
+	}
+
+	recovered = !t.health.Healthy
+	t.health.Healthy = true
+	t.health.Consecutive = 0
+	t.health.LastError = ""
+	t.failures = 0
+	t.ejectedUntil = time.Time{}
+	return true, recovered, 0
+}
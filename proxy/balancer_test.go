@@ -0,0 +1,161 @@
+package proxy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/JPKribs/FinGuard/config"
+)
+
+func TestPickTarget(t *testing.T) {
+	now := time.Now()
+	upstreams := []string{"http://10.0.0.1:8096", "http://10.0.0.2:8096", "http://10.0.0.3:8096"}
+
+	tests := []struct {
+		name      string
+		policy    string
+		upstreams []string
+		unhealthy []int
+		ejected   []int
+		active    []int64
+		want      []int
+	}{
+		{
+			name:      "single upstream is always used",
+			upstreams: upstreams[:1],
+			unhealthy: []int{0},
+			want:      []int{0, 0},
+		},
+		{
+			name:      "round robin",
+			upstreams: upstreams,
+			want:      []int{0, 1, 2, 0},
+		},
+		{
+			name:      "round robin skips unhealthy upstreams",
+			upstreams: upstreams,
+			unhealthy: []int{1},
+			want:      []int{0, 2, 0, 2},
+		},
+		{
+			name:      "round robin skips ejected upstreams",
+			upstreams: upstreams,
+			ejected:   []int{0},
+			want:      []int{1, 2, 1},
+		},
+		{
+			name:      "all unavailable fails open",
+			upstreams: upstreams,
+			unhealthy: []int{0, 1},
+			ejected:   []int{2},
+			want:      []int{0, 1, 2},
+		},
+		{
+			name:      "failover uses the first available",
+			policy:    config.LoadBalanceFailover,
+			upstreams: upstreams,
+			want:      []int{0, 0, 0},
+		},
+		{
+			name:      "failover moves to the next upstream",
+			policy:    config.LoadBalanceFailover,
+			upstreams: upstreams,
+			unhealthy: []int{0},
+			ejected:   []int{1},
+			want:      []int{2, 2},
+		},
+		{
+			name:      "least connections",
+			policy:    config.LoadBalanceLeastConnections,
+			upstreams: upstreams,
+			active:    []int64{5, 1, 3},
+			want:      []int{1, 1, 1},
+		},
+		{
+			name:      "least connections spreads ties",
+			policy:    config.LoadBalanceLeastConnections,
+			upstreams: upstreams,
+			active:    []int64{2, 2, 2},
+			want:      []int{0, 1, 2},
+		},
+		{
+			name:      "least connections ignores unhealthy upstreams",
+			policy:    config.LoadBalanceLeastConnections,
+			upstreams: upstreams,
+			active:    []int64{5, 0, 3},
+			unhealthy: []int{1},
+			want:      []int{2, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := config.ServiceConfig{Name: "media", Upstream: tt.upstreams[0], Upstreams: tt.upstreams[1:], LoadBalancing: tt.policy}
+			targets, err := newUpstreamTargets(svc)
+			if err != nil {
+				t.Fatalf("newUpstreamTargets: %v", err)
+			}
+			service := &ProxyService{Config: svc, Targets: targets}
+
+			for _, i := range tt.unhealthy {
+				service.Targets[i].recordCheck("connection refused", now)
+			}
+			for _, i := range tt.ejected {
+				service.Targets[i].ejectedUntil = now.Add(time.Minute)
+			}
+			for i, active := range tt.active {
+				service.Targets[i].active.Store(active)
+			}
+
+			index := make(map[*upstreamTarget]int, len(service.Targets))
+			for i, target := range service.Targets {
+				index[target] = i
+			}
+
+			var got []int
+			for range tt.want {
+				got = append(got, index[service.pickTarget(now)])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("picked %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpstreamTargetEjection(t *testing.T) {
+	now := time.Now()
+	targets, err := newUpstreamTargets(config.ServiceConfig{Name: "media", Upstream: "http://10.0.0.1:8096"})
+	if err != nil {
+		t.Fatalf("newUpstreamTargets: %v", err)
+	}
+	target := targets[0]
+
+	for i := 1; i < maxPassiveFailures; i++ {
+		if target.recordFailure() {
+			t.Fatalf("ejected after %d failures", i)
+		}
+	}
+	if !target.recordFailure() {
+		t.Fatalf("not ejected after %d failures", maxPassiveFailures)
+	}
+	if target.available(now) {
+		t.Fatal("ejected target reported available")
+	}
+	if !target.available(now.Add(ejectionDuration + time.Second)) {
+		t.Fatal("target still unavailable after the ejection period")
+	}
+
+	if _, recovered, _ := target.recordCheck("", now); recovered {
+		t.Fatal("a healthy target cannot recover")
+	}
+	if !target.available(now) {
+		t.Fatal("a passing health check did not clear the ejection")
+	}
+
+	target.recordCheck("timeout", now)
+	if _, recovered, _ := target.recordCheck("", now); !recovered {
+		t.Fatal("an unhealthy target that passed a check did not recover")
+	}
+}
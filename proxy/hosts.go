@@ -0,0 +1,173 @@
+package proxy
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/JPKribs/FinGuard/config"
+)
+
+// Host routing functions
+
+// MARK: SetBaseDomains
+// Replaces the base domains every service answers under as <name>.<domain>, rejecting them if a host would be claimed twice
+func (s *Server) SetBaseDomains(domains []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	routes, err := buildHostRoutes(s.services, domains, s.hostPrefixMatch)
+	if err != nil {
+		return err
+	}
+
+	s.baseDomains = slices.Clone(domains)
+	s.routes = routes
+
+	// ACME certificates without explicit domains follow the service hosts
+	for _, service := range s.services {
+		if service.Config.UsesACME() {
+			s.updateServiceCertificate(service.Config)
+		}
+	}
+
+	return nil
+}
+
+// MARK: SetHostPrefixMatch
+// Lets services without hosts also answer on any hostname starting with <name>., as before base domains existed
+func (s *Server) SetHostPrefixMatch(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hostPrefixMatch = enabled
+	if routes, err := buildHostRoutes(s.services, s.baseDomains, enabled); err == nil {
+		s.routes = routes
+	}
+}
+
+// MARK: buildHostRoutes
+// Compiles the host patterns of all services into a routing table, failing if two services claim the same host and path or the default
+func buildHostRoutes(services map[string]*ProxyService, baseDomains []string, prefixMatch bool) (*hostRoutes, error) {
+	routes := &hostRoutes{exact: make(map[string]*hostRoute), legacyPrefix: prefixMatch}
+	byPattern := make(map[string]*hostRoute)
+
+	// Sorted so regex order and conflict errors do not depend on map iteration
+	for _, name := range slices.Sorted(maps.Keys(services)) {
+		service := services[name]
+		if service.Config.Default {
+			if routes.fallback != nil {
+				return nil, fmt.Errorf("services %s and %s are both marked default", routes.fallback.Config.Name, name)
+			}
+			routes.fallback = service
+		}
+
+		patterns, err := service.Config.HostPatterns(baseDomains)
+		if err != nil {
+			return nil, err
+		}
+
+		// Services without hosts answer on their bare name until base domains are configured
+		if len(baseDomains) == 0 && len(service.Config.Hosts) == 0 {
+			routes.legacy = append(routes.legacy, service)
+		}
+
+		for _, pattern := range patterns {
+			key := pattern.String()
+			route, exists := byPattern[key]
+			if !exists {
+				route = &hostRoute{pattern: pattern}
+				byPattern[key] = route
+
+				switch pattern.Kind {
+				case config.HostPatternExact:
+					routes.exact[pattern.Value] = route
+				case config.HostPatternWildcard:
+					routes.wildcards = append(routes.wildcards, route)
+				case config.HostPatternRegex:
+					routes.regexes = append(routes.regexes, route)
+				}
+			}
+
+			for _, existing := range route.services {
+				if existing.Config.RoutePrefix() == service.Config.RoutePrefix() {
+					return nil, fmt.Errorf("services %s and %s both claim host %s%s", existing.Config.Name, name, key, service.Config.RoutePrefix())
+				}
+			}
+			route.services = append(route.services, service)
+		}
+	}
+
+	for _, route := range byPattern {
+		sortByPrefix(route.services)
+	}
+
+	// The longest wildcard suffix is the most specific
+	sort.SliceStable(routes.wildcards, func(i, j int) bool {
+		return len(routes.wildcards[i].pattern.Value) > len(routes.wildcards[j].pattern.Value)
+	})
+	sortByPrefix(routes.legacy)
+	sort.SliceStable(routes.legacy, func(i, j int) bool {
+		return len(routes.legacy[i].Config.Name) > len(routes.legacy[j].Config.Name)
+	})
+
+	return routes, nil
+}
+
+// MARK: sortByPrefix
+// Orders services so the longest mount path is tried first
+func sortByPrefix(services []*ProxyService) {
+	sort.SliceStable(services, func(i, j int) bool {
+		return len(services[i].Config.RoutePrefix()) > len(services[j].Config.RoutePrefix())
+	})
+}
+
+// MARK: match
+// Returns the service for a host and path: exact hosts first, then the longest wildcard, regexes, bare service names and the default
+func (r *hostRoutes) match(host, path string) *ProxyService {
+	host = config.NormalizeHost(host)
+
+	if route, ok := r.exact[host]; ok {
+		if service := route.service(path); service != nil {
+			return service
+		}
+	}
+
+	for _, route := range r.wildcards {
+		if route.pattern.Matches(host) {
+			if service := route.service(path); service != nil {
+				return service
+			}
+		}
+	}
+
+	for _, route := range r.regexes {
+		if route.pattern.Matches(host) {
+			if service := route.service(path); service != nil {
+				return service
+			}
+		}
+	}
+
+	for _, service := range r.legacy {
+		name := strings.ToLower(service.Config.Name)
+		if (host == name || (r.legacyPrefix && strings.HasPrefix(host, name+"."))) && (path == "" || service.Config.MatchesPath(path)) {
+			return service
+		}
+	}
+
+	return r.fallback
+}
+
+// MARK: service
+// Returns the service mounted under the longest prefix of the path, with an empty path matching any mount
+func (r *hostRoute) service(path string) *ProxyService {
+	for _, service := range r.services {
+		if path == "" || service.Config.MatchesPath(path) {
+			return service
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/JPKribs/FinGuard/config"
+)
+
+func TestHostRoutesMatch(t *testing.T) {
+	services := map[string]*ProxyService{
+		"jf":       {Config: config.ServiceConfig{Name: "jf", Hosts: []string{"jf.example.com"}}},
+		"wild":     {Config: config.ServiceConfig{Name: "wild", Hosts: []string{"*.example.com"}}},
+		"deep":     {Config: config.ServiceConfig{Name: "deep", Hosts: []string{"*.lab.example.com"}}},
+		"nodes":    {Config: config.ServiceConfig{Name: "nodes", Hosts: []string{`~node[0-9]+\.lan`}}},
+		"seerr":    {Config: config.ServiceConfig{Name: "seerr", Hosts: []string{"jf.example.com"}, PathPrefix: "/requests"}},
+		"fallback": {Config: config.ServiceConfig{Name: "fallback", Default: true}},
+	}
+
+	routes, err := buildHostRoutes(services, nil, false)
+	if err != nil {
+		t.Fatalf("buildHostRoutes: %v", err)
+	}
+
+	tests := []struct {
+		host string
+		path string
+		want string
+	}{
+		{"jf.example.com", "/", "jf"},
+		{"JF.Example.com:443", "/web", "jf"},
+		{"jf.example.com", "/requests", "seerr"},
+		{"jf.example.com", "/requests/movie/1", "seerr"},
+		{"jf.example.com", "/requestsfoo", "jf"},
+		{"other.example.com", "/", "wild"},
+		{"a.lab.example.com", "/", "deep"},
+		{"example.com", "/", "fallback"},
+		{"node7.lan", "/", "nodes"},
+		{"node7.lan.evil.com", "/", "fallback"},
+		{"jf.example.com.evil.com", "/", "fallback"},
+		{"unknown", "/", "fallback"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host+tt.path, func(t *testing.T) {
+			got := routes.match(tt.host, tt.path)
+			if got == nil || got.Config.Name != tt.want {
+				t.Errorf("match = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostRoutesBareNames(t *testing.T) {
+	services := map[string]*ProxyService{
+		"jf":       {Config: config.ServiceConfig{Name: "jf"}},
+		"jf-music": {Config: config.ServiceConfig{Name: "jf-music"}},
+		"hosted":   {Config: config.ServiceConfig{Name: "hosted", Hosts: []string{"hosted.example.com"}}},
+	}
+
+	tests := []struct {
+		name        string
+		baseDomains []string
+		prefixMatch bool
+		host        string
+		want        string
+	}{
+		{name: "exact name", host: "jf", want: "jf"},
+		{name: "prefix not matched by default", host: "jf.evil.com", want: ""},
+		{name: "prefix matched when enabled", prefixMatch: true, host: "jf.finguard.local", want: "jf"},
+		{name: "longer name wins with prefix matching", prefixMatch: true, host: "jf-music.finguard.local", want: "jf-music"},
+		{name: "name without dot is not a prefix", prefixMatch: true, host: "jfx.finguard.local", want: ""},
+		{name: "services with hosts have no bare name", prefixMatch: true, host: "hosted", want: ""},
+		{name: "base domains replace bare names", baseDomains: []string{"example.com"}, prefixMatch: true, host: "jf", want: ""},
+		{name: "base domain name", baseDomains: []string{"example.com"}, host: "jf.example.com", want: "jf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes, err := buildHostRoutes(services, tt.baseDomains, tt.prefixMatch)
+			if err != nil {
+				t.Fatalf("buildHostRoutes: %v", err)
+			}
+
+			got := routes.match(tt.host, "/")
+			switch {
+			case tt.want == "" && got != nil:
+				t.Errorf("match = %s, want none", got.Config.Name)
+			case tt.want != "" && (got == nil || got.Config.Name != tt.want):
+				t.Errorf("match = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildHostRoutesConflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		services map[string]*ProxyService
+		wantErr  bool
+	}{
+		{
+			name: "same host",
+			services: map[string]*ProxyService{
+				"a": {Config: config.ServiceConfig{Name: "a", Hosts: []string{"media.example.com"}}},
+				"b": {Config: config.ServiceConfig{Name: "b", Hosts: []string{"Media.Example.com"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "same host and prefix",
+			services: map[string]*ProxyService{
+				"a": {Config: config.ServiceConfig{Name: "a", Hosts: []string{"media.example.com"}, PathPrefix: "/x"}},
+				"b": {Config: config.ServiceConfig{Name: "b", Hosts: []string{"media.example.com"}, PathPrefix: "/x/"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "same host with different prefixes",
+			services: map[string]*ProxyService{
+				"a": {Config: config.ServiceConfig{Name: "a", Hosts: []string{"media.example.com"}}},
+				"b": {Config: config.ServiceConfig{Name: "b", Hosts: []string{"media.example.com"}, PathPrefix: "/x"}},
+			},
+		},
+		{
+			name: "two defaults",
+			services: map[string]*ProxyService{
+				"a": {Config: config.ServiceConfig{Name: "a", Default: true}},
+				"b": {Config: config.ServiceConfig{Name: "b", Default: true}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid host",
+			services: map[string]*ProxyService{
+				"a": {Config: config.ServiceConfig{Name: "a", Hosts: []string{"~("}}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := buildHostRoutes(tt.services, nil, false)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
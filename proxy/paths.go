@@ -0,0 +1,207 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/JPKribs/FinGuard/config"
+)
+
+const maxRewriteBody = 8 << 20
+
+var htmlLinkPattern = regexp.MustCompile(`(?i)\s(?:href|src|action|formaction|poster)\s*=\s*["']?(/[^"'\s>]*)`)
+
+// Sub-path mounting functions
+
+// MARK: redirectToMount
+// Redirects a request for the bare mount path to the path with a trailing slash so relative links resolve under it
+func (s *Server) redirectToMount(w http.ResponseWriter, r *http.Request, svc config.ServiceConfig) bool {
+	if !svc.StripsPrefix() || r.URL.Path != svc.RoutePrefix() {
+		return false
+	}
+
+	target := r.URL.Path + "/"
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+
+	http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	return true
+}
+
+// MARK: stripRoutePrefix
+// Removes the mount path from a request before it is sent upstream and tells the upstream where it is mounted
+func (s *Server) stripRoutePrefix(pr *httputil.ProxyRequest, svc config.ServiceConfig) {
+	if !svc.StripsPrefix() {
+		return
+	}
+
+	prefix := svc.RoutePrefix()
+	pr.Out.URL.Path = stripPathPrefix(pr.Out.URL.Path, prefix)
+	if pr.Out.URL.RawPath != "" {
+		pr.Out.URL.RawPath = stripPathPrefix(pr.Out.URL.RawPath, prefix)
+	}
+	pr.Out.Header.Set("X-Forwarded-Prefix", prefix)
+
+	// Let the transport negotiate compression itself so HTML arrives decoded and can be rewritten
+	pr.Out.Header.Del("Accept-Encoding")
+}
+
+// MARK: rewriteMountedResponse
+// Adds the mount path back to redirects, cookie paths and root-relative links in HTML from an upstream that does not know it
+func (s *Server) rewriteMountedResponse(resp *http.Response, svc config.ServiceConfig, upstream *url.URL) error {
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		return nil
+	}
+
+	prefix := svc.RoutePrefix()
+
+	if location := resp.Header.Get("Location"); location != "" {
+		resp.Header.Set("Location", mountLocation(location, prefix, upstream, resp.Request))
+	}
+
+	if cookies := resp.Header.Values("Set-Cookie"); len(cookies) > 0 {
+		rewritten := make([]string, len(cookies))
+		for i, cookie := range cookies {
+			rewritten[i] = mountCookiePath(cookie, prefix)
+		}
+		resp.Header["Set-Cookie"] = rewritten
+	}
+
+	if resp.Request != nil && resp.Request.Method == http.MethodHead {
+		return nil
+	}
+
+	if isHTMLResponse(resp) {
+		return rewriteHTMLLinks(resp, prefix)
+	}
+
+	return nil
+}
+
+// MARK: stripPathPrefix
+// Removes the mount path from a request path, keeping it rooted
+func stripPathPrefix(path, prefix string) string {
+	path = strings.TrimPrefix(path, prefix)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// MARK: mountPath
+// Places an upstream root-relative path under the mount path, even one that already starts with the prefix
+func mountPath(path, prefix string) string {
+	return prefix + path
+}
+
+// MARK: mountLocation
+// Rewrites a redirect target that points at the upstream root so it stays under the mount path
+func mountLocation(location, prefix string, upstream *url.URL, out *http.Request) string {
+	target, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+
+	switch {
+	case target.Host == "" && strings.HasPrefix(target.Path, "/"):
+	case strings.EqualFold(target.Host, upstream.Host):
+		// The upstream's own address is not reachable by clients, so keep them on the proxy
+		target.Scheme = ""
+		target.Host = ""
+		target.User = nil
+	case out != nil && strings.EqualFold(target.Host, out.Header.Get("X-Forwarded-Host")):
+	default:
+		return location
+	}
+
+	target.Path = mountPath(target.Path, prefix)
+	if target.RawPath != "" {
+		target.RawPath = mountPath(target.RawPath, prefix)
+	}
+	return target.String()
+}
+
+// MARK: mountCookiePath
+// Scopes the Path attribute of a Set-Cookie header to the mount path, leaving other attributes untouched
+func mountCookiePath(cookie, prefix string) string {
+	parts := strings.Split(cookie, ";")
+	for i := 1; i < len(parts); i++ {
+		attr := strings.TrimSpace(parts[i])
+		if len(attr) < 5 || !strings.EqualFold(attr[:5], "path=") {
+			continue
+		}
+
+		path := attr[5:]
+		if !strings.HasPrefix(path, "/") {
+			continue
+		}
+		if path == "/" {
+			path = prefix
+		} else {
+			path = mountPath(path, prefix)
+		}
+		parts[i] = " Path=" + path
+	}
+	return strings.Join(parts, ";")
+}
+
+// MARK: isHTMLResponse
+// Checks if a response body is an uncompressed HTML document
+func isHTMLResponse(resp *http.Response) bool {
+	if resp.Header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+// MARK: rewriteHTMLLinks
+// Places root-relative href, src and action links of an HTML body under the mount path, passing very large bodies through unchanged
+func rewriteHTMLLinks(resp *http.Response, prefix string) error {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRewriteBody+1))
+	if err != nil {
+		return err
+	}
+
+	if len(body) > maxRewriteBody {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return nil
+	}
+	resp.Body.Close()
+
+	var rewritten bytes.Buffer
+	rewritten.Grow(len(body))
+
+	last := 0
+	for _, match := range htmlLinkPattern.FindAllSubmatchIndex(body, -1) {
+		start, end := match[2], match[3]
+		link := string(body[start:end])
+
+		// Protocol-relative links point at another host
+		if strings.HasPrefix(link, "//") {
+			continue
+		}
+
+		rewritten.Write(body[last:start])
+		rewritten.WriteString(mountPath(link, prefix))
+		last = end
+	}
+	rewritten.Write(body[last:])
+
+	resp.Body = io.NopCloser(&rewritten)
+	resp.ContentLength = int64(rewritten.Len())
+	resp.Header.Set("Content-Length", strconv.Itoa(rewritten.Len()))
+	return nil
+}
@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMountPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/", "/requests/"},
+		{"/login", "/requests/login"},
+		{"/requests", "/requests/requests"},
+		{"/requests/movie/1", "/requests/requests/movie/1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := mountPath(tt.path, "/requests"); got != tt.want {
+				t.Errorf("mountPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMountCookiePath(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie string
+		want   string
+	}{
+		{"root path", "session=abc; Path=/; HttpOnly", "session=abc; Path=/requests; HttpOnly"},
+		{"sub path", "session=abc; path=/api", "session=abc; Path=/requests/api"},
+		{"path already starting with the prefix", "session=abc; Path=/requests", "session=abc; Path=/requests/requests"},
+		{"no path", "session=abc; Secure", "session=abc; Secure"},
+		{"relative path", "session=abc; Path=api", "session=abc; Path=api"},
+		{"value containing path=", "q=path=/x; Path=/", "q=path=/x; Path=/requests"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mountCookiePath(tt.cookie, "/requests"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMountLocation(t *testing.T) {
+	upstream, _ := url.Parse("http://10.0.0.5:5055")
+	out := &http.Request{Header: http.Header{"X-Forwarded-Host": []string{"media.example.com"}}}
+
+	tests := []struct {
+		name     string
+		location string
+		want     string
+	}{
+		{"root-relative", "/login?next=%2F", "/requests/login?next=%2F"},
+		{"path starting with the prefix", "/requests", "/requests/requests"},
+		{"upstream address", "http://10.0.0.5:5055/setup", "/requests/setup"},
+		{"proxy host", "https://media.example.com/setup", "https://media.example.com/requests/setup"},
+		{"foreign host", "https://accounts.example.org/auth", "https://accounts.example.org/auth"},
+		{"relative", "setup", "setup"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mountLocation(tt.location, "/requests", upstream, out); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteHTMLLinks(t *testing.T) {
+	body := `<a href="/">Home</a><a href='/requests'>Requests</a><img src=/logo.png>` +
+		`<script src="//cdn.example.com/x.js"></script><a href="https://example.com/">Out</a><a href="rel">Rel</a>`
+	want := `<a href="/requests/">Home</a><a href='/requests/requests'>Requests</a><img src=/requests/logo.png>` +
+		`<script src="//cdn.example.com/x.js"></script><a href="https://example.com/">Out</a><a href="rel">Rel</a>`
+
+	resp := &http.Response{
+		Header: http.Header{"Content-Type": []string{"text/html; charset=utf-8"}},
+		Body:   io.NopCloser(strings.NewReader(body)),
+	}
+	if err := rewriteHTMLLinks(resp, "/requests"); err != nil {
+		t.Fatalf("rewriteHTMLLinks: %v", err)
+	}
+
+	got, _ := io.ReadAll(resp.Body)
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if resp.Header.Get("Content-Length") != strconv.Itoa(len(want)) || resp.ContentLength != int64(len(want)) {
+		t.Errorf("content length %s / %d, want %d", resp.Header.Get("Content-Length"), resp.ContentLength, len(want))
+	}
+}
+
+func TestStripPathPrefix(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/requests", "/"},
+		{"/requests/", "/"},
+		{"/requests/movie/1", "/movie/1"},
+	}
+
+	for _, tt := range tests {
+		if got := stripPathPrefix(tt.path, "/requests"); got != tt.want {
+			t.Errorf("stripPathPrefix(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
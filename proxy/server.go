@@ -2,24 +2,87 @@ package proxy
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"html"
+	"maps"
 	"net"
 	"net/http"
 	"net/http/httputil"
-	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/JPKribs/FinGuard/config"
 	"github.com/JPKribs/FinGuard/internal"
 )
 
+const tunnelDownPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Tunnel %s is down</title></head>
+<body style="font-family: sans-serif; max-width: 40em; margin: 4em auto;">
+<h1>503 Service Unavailable</h1>
+<p>The service <strong>%s</strong> only accepts traffic through the WireGuard tunnel <strong>%s</strong>, which is not running.</p>
+<p>Requests will be forwarded again once the tunnel is back up.</p>
+</body>
+</html>
+`
+
 // MARK: NewServer
 // Creates a new proxy server instance with logger
 func NewServer(logger *internal.Logger) *Server {
 	return &Server{
-		logger:   logger,
-		services: make(map[string]*ProxyService),
+		logger:       logger,
+		services:     make(map[string]*ProxyService),
+		certificates: newCertificateStore(logger),
+		routes:       &hostRoutes{exact: make(map[string]*hostRoute)},
+	}
+}
+
+// MARK: SetTunnelDialer
+// Sets the dialer used to reach upstreams of services bound to a tunnel
+func (s *Server) SetTunnelDialer(dialer TunnelDialFunc) {
+	// Stored atomically since dial functions read it from transport goroutines without the server lock
+	s.tunnelDialer.Store(&dialer)
+}
+
+// MARK: SetTunnelSelector
+// Sets the function that picks which tunnel a service dials through, allowing failover between tunnels
+func (s *Server) SetTunnelSelector(selector TunnelSelectFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tunnelSelector = selector
+}
+
+// MARK: SetTunnelStateChecker
+// Sets the function that reports whether a tunnel is up, used to enforce require_tunnel
+func (s *Server) SetTunnelStateChecker(checker TunnelStateFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tunnelState = checker
+}
+
+// MARK: SetTunnelWaker
+// Sets the function that brings an on-demand tunnel up before a request is forwarded through it
+func (s *Server) SetTunnelWaker(waker TunnelWakeFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tunnelWaker = waker
+}
+
+// MARK: ResetServiceConnections
+// Closes idle upstream connections of a service so new requests dial through its current tunnel
+func (s *Server) ResetServiceConnections(name string) {
+	s.mu.RLock()
+	service, exists := s.services[name]
+	s.mu.RUnlock()
+
+	if !exists {
+		return
+	}
+
+	if transport, ok := service.Proxy.Transport.(*http.Transport); ok {
+		transport.CloseIdleConnections()
 	}
 }
 
@@ -76,6 +139,13 @@ func (s *Server) Stop(ctx context.Context) error {
 		}
 	}
 
+	if s.tlsServer != nil {
+		if err := s.tlsServer.Shutdown(ctx); err != nil {
+			return fmt.Errorf("shutting down proxy TLS server: %w", err)
+		}
+		s.tlsServer = nil
+	}
+
 	s.running = false
 	return nil
 }
@@ -99,19 +169,15 @@ func (s *Server) AddService(svc config.ServiceConfig) error {
 	// Check if service already exists
 	if _, exists := s.services[svc.Name]; exists {
 		s.logger.Warn("Service already exists, updating", "name", svc.Name)
-		delete(s.services, svc.Name)
 	}
 
-	upstream, err := url.Parse(svc.Upstream)
+	targets, err := newUpstreamTargets(svc)
 	if err != nil {
-		return fmt.Errorf("parsing upstream URL %s: %w", svc.Upstream, err)
+		return err
 	}
 
 	transport := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   5 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
+		DialContext:           s.dialContextFor(svc, 5*time.Second),
 		MaxIdleConns:          10,
 		MaxIdleConnsPerHost:   2,
 		IdleConnTimeout:       60 * time.Second,
@@ -120,47 +186,189 @@ func (s *Server) AddService(svc config.ServiceConfig) error {
 		ExpectContinueTimeout: 1 * time.Second,
 	}
 
-	proxy := &httputil.ReverseProxy{
+	service := &ProxyService{
+		Config:   svc,
+		Upstream: targets[0].url,
+		Targets:  targets,
+		Health:   &ServiceHealth{Healthy: true, LastCheck: time.Now()},
+	}
+
+	service.Proxy = &httputil.ReverseProxy{
 		Transport: transport,
 		Rewrite: func(pr *httputil.ProxyRequest) {
+			upstream := service.targetFor(pr.In).url
+			s.stripRoutePrefix(pr, svc)
 			pr.SetURL(upstream)
 			pr.Out.Host = upstream.Host
 			s.setProxyHeaders(pr, svc)
 		},
 		ModifyResponse: func(resp *http.Response) error {
+			target := service.targetFor(resp.Request)
+			target.recordSuccess()
 			s.setSecurityHeaders(resp)
+			if svc.StripsPrefix() {
+				return s.rewriteMountedResponse(resp, svc, target.url)
+			}
 			return nil
 		},
 		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
-			s.handleProxyError(w, r, svc, err)
+			s.handleProxyError(w, r, service, err)
 		},
 	}
 
-	service := &ProxyService{
-		Config:   svc,
-		Upstream: upstream,
-		Proxy:    proxy,
-		Health:   &ServiceHealth{Healthy: true, LastCheck: time.Now()},
+	services := maps.Clone(s.services)
+	services[svc.Name] = service
+
+	routes, err := buildHostRoutes(services, s.baseDomains, s.hostPrefixMatch)
+	if err != nil {
+		return fmt.Errorf("adding service %s: %w", svc.Name, err)
 	}
 
-	s.services[svc.Name] = service
-	s.logger.Info("Added service", "name", svc.Name, "upstream", svc.Upstream)
+	s.services = services
+	s.routes = routes
+	s.updateServiceCertificate(svc)
+
+	s.logger.Info("Added service", "name", svc.Name, "upstream", svc.Upstream, "upstreams", len(targets))
 	return nil
 }
 
+// MARK: dialContextFor
+// Returns the dial function for a service, routing tunnel-bound services through the tunnel dialer
+func (s *Server) dialContextFor(svc config.ServiceConfig, timeout time.Duration) func(ctx context.Context, network, address string) (net.Conn, error) {
+	direct := (&net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+
+	if svc.Tunnel == "" {
+		return direct
+	}
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		tunnelDialer := s.loadTunnelDialer()
+		if tunnelDialer == nil {
+			return direct(ctx, network, address)
+		}
+
+		tunnelName, down := s.tunnelDown(svc)
+		if down {
+			return nil, &TunnelDownError{Service: svc.Name, Tunnel: tunnelName}
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return tunnelDialer(ctx, tunnelName, network, address)
+	}
+}
+
+// MARK: loadTunnelDialer
+// Returns the tunnel dialer currently set, or nil when tunnel-bound services dial directly
+func (s *Server) loadTunnelDialer() TunnelDialFunc {
+	if dialer := s.tunnelDialer.Load(); dialer != nil {
+		return *dialer
+	}
+	return nil
+}
+
+// MARK: Error
+// Describes which tunnel blocked the service
+func (e *TunnelDownError) Error() string {
+	return fmt.Sprintf("service %s requires tunnel %s, which is not running", e.Service, e.Tunnel)
+}
+
+// MARK: activeTunnel
+// Returns the tunnel a service currently dials through
+func (s *Server) activeTunnel(svc config.ServiceConfig) string {
+	s.mu.RLock()
+	selector := s.tunnelSelector
+	s.mu.RUnlock()
+
+	if selector != nil {
+		return selector(svc)
+	}
+	return svc.Tunnel
+}
+
+// MARK: tunnelDown
+// Reports whether a require_tunnel service must refuse traffic because its active tunnel is not running
+func (s *Server) tunnelDown(svc config.ServiceConfig) (string, bool) {
+	tunnelName := s.activeTunnel(svc)
+	if !svc.RequireTunnel || svc.Tunnel == "" {
+		return tunnelName, false
+	}
+
+	s.mu.RLock()
+	checker := s.tunnelState
+	s.mu.RUnlock()
+
+	if checker == nil {
+		return tunnelName, false
+	}
+	return tunnelName, !checker(tunnelName)
+}
+
+// MARK: wakeTunnel
+// Holds the service's active tunnel open for the request, starting it first if it is on-demand
+func (s *Server) wakeTunnel(ctx context.Context, svc config.ServiceConfig) (func(), error) {
+	s.mu.RLock()
+	waker := s.tunnelWaker
+	s.mu.RUnlock()
+
+	if svc.Tunnel == "" || waker == nil {
+		return func() {}, nil
+	}
+
+	return waker(ctx, s.activeTunnel(svc))
+}
+
+// MARK: writeTunnelDownPage
+// Responds with a 503 page naming the tunnel a require_tunnel service is waiting on
+func (s *Server) writeTunnelDownPage(w http.ResponseWriter, r *http.Request, svc config.ServiceConfig, tunnelName string) {
+	s.logger.Warn("Refusing request while tunnel is down",
+		"service", svc.Name,
+		"tunnel", tunnelName,
+		"host", r.Host,
+		"path", r.URL.Path,
+		"remote", s.getClientIP(r))
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Retry-After", "30")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	fmt.Fprintf(w, tunnelDownPage, html.EscapeString(tunnelName), html.EscapeString(svc.Name), html.EscapeString(tunnelName))
+}
+
 // MARK: handleProxyError
-// Enhanced error handler that categorizes and logs different proxy error types
-func (s *Server) handleProxyError(w http.ResponseWriter, r *http.Request, svc config.ServiceConfig, err error) {
+// Enhanced error handler that categorizes and logs different proxy error types and counts them against the upstream
+func (s *Server) handleProxyError(w http.ResponseWriter, r *http.Request, service *ProxyService, err error) {
 	var statusCode int
 	var errorType string
 
+	svc := service.Config
+	target := service.targetFor(r)
+	upstream := target.url.String()
+
+	var tunnelErr *TunnelDownError
+	if errors.As(err, &tunnelErr) {
+		s.writeTunnelDownPage(w, r, svc, tunnelErr.Tunnel)
+		return
+	}
+
+	// A client going away says nothing about the upstream
+	if !errors.Is(r.Context().Err(), context.Canceled) && target.recordFailure() && len(service.Targets) > 1 {
+		s.logger.Warn("Ejected upstream after repeated failures",
+			"service", svc.Name,
+			"upstream", upstream,
+			"duration", ejectionDuration.String())
+	}
+
 	switch {
 	case strings.Contains(err.Error(), "context canceled"):
 		statusCode = http.StatusRequestTimeout
 		errorType = "timeout"
 		s.logger.Warn("Request timeout",
 			"service", svc.Name,
-			"upstream", svc.Upstream,
+			"upstream", upstream,
 			"host", r.Host,
 			"path", r.URL.Path,
 			"method", r.Method,
@@ -171,7 +379,7 @@ func (s *Server) handleProxyError(w http.ResponseWriter, r *http.Request, svc co
 		errorType = "connection_refused"
 		s.logger.Error("Upstream connection refused",
 			"service", svc.Name,
-			"upstream", svc.Upstream,
+			"upstream", upstream,
 			"host", r.Host,
 			"error", err.Error())
 
@@ -180,7 +388,7 @@ func (s *Server) handleProxyError(w http.ResponseWriter, r *http.Request, svc co
 		errorType = "dns_failure"
 		s.logger.Error("DNS resolution failed",
 			"service", svc.Name,
-			"upstream", svc.Upstream,
+			"upstream", upstream,
 			"host", r.Host,
 			"error", err.Error())
 
@@ -189,7 +397,7 @@ func (s *Server) handleProxyError(w http.ResponseWriter, r *http.Request, svc co
 		errorType = "upstream_timeout"
 		s.logger.Error("Upstream timeout",
 			"service", svc.Name,
-			"upstream", svc.Upstream,
+			"upstream", upstream,
 			"host", r.Host,
 			"timeout_duration", "15s",
 			"error", err.Error())
@@ -199,7 +407,7 @@ func (s *Server) handleProxyError(w http.ResponseWriter, r *http.Request, svc co
 		errorType = "proxy_error"
 		s.logger.Error("Proxy error",
 			"service", svc.Name,
-			"upstream", svc.Upstream,
+			"upstream", upstream,
 			"host", r.Host,
 			"error_type", errorType,
 			"error", err.Error())
@@ -241,7 +449,7 @@ func (s *Server) checkAllServices() {
 }
 
 // MARK: checkServiceHealth
-// Performs health check on individual service and updates health status
+// Performs health checks on every upstream of a service and marks the service healthy while any of them is
 func (s *Server) checkServiceHealth(service *ProxyService) {
 	service.mu.Lock()
 	defer service.mu.Unlock()
@@ -249,18 +457,22 @@ func (s *Server) checkServiceHealth(service *ProxyService) {
 	client := &http.Client{
 		Timeout: 5 * time.Second,
 		Transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout: 3 * time.Second,
-			}).DialContext,
+			DialContext: s.dialContextFor(service.Config, 3*time.Second),
 		},
 	}
+	defer client.CloseIdleConnections()
 
-	healthURL := service.Upstream.String()
-	if !strings.HasSuffix(healthURL, "/") {
-		healthURL += "/"
+	errs := make([]string, len(service.Targets))
+	var wg sync.WaitGroup
+	for i, target := range service.Targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.checkUpstream(client, target)
+		}()
 	}
+	wg.Wait()
 
-	resp, err := client.Get(healthURL)
 	now := time.Now()
 
 	if service.Health == nil {
@@ -268,40 +480,59 @@ func (s *Server) checkServiceHealth(service *ProxyService) {
 	}
 
 	service.Health.LastCheck = now
+	healthy := false
+	lastError := ""
 
-	if err != nil || (resp != nil && resp.StatusCode >= 500) {
-		service.Health.Healthy = false
-		service.Health.Consecutive++
-		if err != nil {
-			service.Health.LastError = err.Error()
+	for i, target := range service.Targets {
+		targetHealthy, recovered, consecutive := target.recordCheck(errs[i], now)
+		if targetHealthy {
+			healthy = true
 		} else {
-			service.Health.LastError = fmt.Sprintf("HTTP %d", resp.StatusCode)
-			resp.Body.Close()
+			lastError = errs[i]
 		}
 
-		if service.Health.Consecutive >= 3 {
+		if consecutive >= 3 {
 			s.logger.Error("Service unhealthy",
 				"name", service.Config.Name,
-				"upstream", service.Config.Upstream,
-				"consecutive_failures", service.Health.Consecutive,
-				"error", service.Health.LastError)
+				"upstream", target.url.String(),
+				"consecutive_failures", consecutive,
+				"error", errs[i])
+		} else if recovered {
+			s.logger.Info("Service recovered",
+				"name", service.Config.Name,
+				"upstream", target.url.String())
 		}
-	} else {
-		wasUnhealthy := !service.Health.Healthy
+	}
+
+	if healthy {
 		service.Health.Healthy = true
 		service.Health.Consecutive = 0
 		service.Health.LastError = ""
+	} else {
+		service.Health.Healthy = false
+		service.Health.Consecutive++
+		service.Health.LastError = lastError
+	}
+}
 
-		if resp != nil {
-			resp.Body.Close()
-		}
+// MARK: checkUpstream
+// Requests the root of an upstream and returns why it is unhealthy, or an empty string when it answers without a server error
+func (s *Server) checkUpstream(client *http.Client, target *upstreamTarget) string {
+	healthURL := target.url.String()
+	if !strings.HasSuffix(healthURL, "/") {
+		healthURL += "/"
+	}
 
-		if wasUnhealthy {
-			s.logger.Info("Service recovered",
-				"name", service.Config.Name,
-				"upstream", service.Config.Upstream)
-		}
+	resp, err := client.Get(healthURL)
+	if err != nil {
+		return err.Error()
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Sprintf("HTTP %d", resp.StatusCode)
 	}
+	return ""
 }
 
 // MARK: RemoveService
@@ -315,6 +546,13 @@ func (s *Server) RemoveService(name string) error {
 	}
 
 	delete(s.services, name)
+	if routes, err := buildHostRoutes(s.services, s.baseDomains, s.hostPrefixMatch); err == nil {
+		s.routes = routes
+	}
+	s.certificates.removeService(name)
+	if s.acme != nil {
+		s.acme.release(name)
+	}
 	s.logger.Info("Removed service", "name", name)
 	return nil
 }
@@ -357,8 +595,12 @@ func (s *Server) IsReady() bool {
 // MARK: handleRequest
 // Routes incoming requests to appropriate service based on hostname
 func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
+	if s.serveACMEChallenge(w, r) {
+		return
+	}
+
 	s.mu.RLock()
-	service := s.findServiceByHost(r.Host)
+	service := s.findService(r.Host, r.URL.Path)
 	s.mu.RUnlock()
 
 	if service == nil {
@@ -367,38 +609,45 @@ func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	service.Proxy.ServeHTTP(w, r)
-}
+	if s.redirectsToHTTPS(r, service.Config) {
+		s.redirectToHTTPS(w, r)
+		return
+	}
 
-// MARK: findServiceByHost
-// Matches hostname to service using subdomain pattern matching
-func (s *Server) findServiceByHost(host string) *ProxyService {
-	var defaultService *ProxyService
+	if s.redirectToMount(w, r, service.Config) {
+		return
+	}
 
-	// Strip port if present
-	hostname := strings.Split(host, ":")[0]
-	hostname = strings.ToLower(hostname)
+	release, err := s.wakeTunnel(r.Context(), service.Config)
+	if err != nil {
+		s.logger.Error("Failed to bring up tunnel", "service", service.Config.Name, "error", err)
+		s.writeTunnelDownPage(w, r, service.Config, s.activeTunnel(service.Config))
+		return
+	}
+	defer release()
 
-	for _, service := range s.services {
-		if service.Config.Default {
-			defaultService = service
-		}
+	if tunnelName, down := s.tunnelDown(service.Config); down {
+		s.writeTunnelDownPage(w, r, service.Config, tunnelName)
+		return
+	}
 
-		serviceName := strings.ToLower(service.Config.Name) // Convert to lowercase
+	target := service.selectTarget(w, r)
+	target.active.Add(1)
+	defer target.active.Add(-1)
 
-		// Check if hostname starts with service name as subdomain
-		// e.g., "requests.finguard.local" starts with "requests."
-		if strings.HasPrefix(hostname, serviceName+".") {
-			return service
-		}
+	service.Proxy.ServeHTTP(w, withTarget(r, target))
+}
 
-		// Exact match for service name (unlikely but keep for compatibility)
-		if hostname == serviceName {
-			return service
-		}
-	}
+// MARK: findService
+// Returns the most specific service for a hostname and request path, or the default service if none match
+func (s *Server) findService(host, path string) *ProxyService {
+	return s.routes.match(host, path)
+}
 
-	return defaultService
+// MARK: findServiceByHost
+// Returns the most specific service for a hostname regardless of path, as needed when picking a certificate
+func (s *Server) findServiceByHost(host string) *ProxyService {
+	return s.routes.match(host, "")
 }
 
 // MARK: setProxyHeaders
@@ -0,0 +1,348 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/JPKribs/FinGuard/config"
+	"github.com/JPKribs/FinGuard/internal"
+)
+
+const certificateReloadInterval = 30 * time.Second
+
+// TLS listener functions
+
+// MARK: StartTLS
+// Starts the HTTPS listener, choosing a certificate for each connection by SNI
+func (s *Server) StartTLS(ctx context.Context, addr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tlsServer != nil {
+		return fmt.Errorf("proxy TLS server already running")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleRequest)
+
+	s.tlsServer = &http.Server{
+		Addr:           addr,
+		Handler:        s.withMiddleware(mux),
+		ReadTimeout:    30 * time.Second,
+		WriteTimeout:   30 * time.Second,
+		IdleTimeout:    120 * time.Second,
+		MaxHeaderBytes: 20 << 20,
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: s.getCertificate,
+		},
+	}
+	s.tlsAddr = addr
+
+	server := s.tlsServer
+	go func() {
+		s.logger.Info("Starting proxy TLS server", "addr", addr)
+		if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("Proxy TLS server failed", "error", err)
+		}
+	}()
+
+	go s.certificates.watch(ctx)
+
+	return nil
+}
+
+// MARK: SetCertificates
+// Replaces the hostname certificates, returning an error for each pair that failed to load
+func (s *Server) SetCertificates(certs []config.CertificateConfig) error {
+	return s.certificates.setGlobal(certs)
+}
+
+// MARK: getCertificate
+// Picks the certificate for a TLS handshake from the requested server name
+func (s *Server) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	host := strings.TrimSuffix(strings.ToLower(hello.ServerName), ".")
+
+	var serviceName string
+	if host != "" {
+		s.mu.RLock()
+		if service := s.findServiceByHost(host); service != nil {
+			serviceName = service.Config.Name
+		}
+		s.mu.RUnlock()
+	}
+
+	if cert := s.certificates.lookup(host, serviceName); cert != nil {
+		return cert, nil
+	}
+
+	return nil, fmt.Errorf("no certificate for %q", hello.ServerName)
+}
+
+// MARK: redirectToHTTPS
+// Sends a plain HTTP request to the same host and path on the HTTPS listener
+func (s *Server) redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	s.mu.RLock()
+	tlsAddr := s.tlsAddr
+	s.mu.RUnlock()
+
+	if _, port, err := net.SplitHostPort(tlsAddr); err == nil && port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+}
+
+// MARK: redirectsToHTTPS
+// Checks if a plain HTTP request for a service should be redirected to the HTTPS listener
+func (s *Server) redirectsToHTTPS(r *http.Request, svc config.ServiceConfig) bool {
+	if r.TLS != nil || !svc.RedirectsHTTPS() {
+		return false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tlsServer != nil
+}
+
+// MARK: updateServiceCertificate
+// Points the certificate store at the files a service is served with, whether configured or issued over ACME
+func (s *Server) updateServiceCertificate(svc config.ServiceConfig) {
+	switch {
+	case svc.UsesACME() && s.acme != nil:
+		certFile, keyFile := s.acme.manage(svc.Name, svc.CertificateDomains(s.baseDomains))
+		// Files that do not exist yet are loaded once the first certificate is issued
+		if err := s.certificates.setService(svc.Name, certFile, keyFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			s.logger.Error("Failed to load ACME certificate", "name", svc.Name, "error", err)
+		}
+	case svc.UsesACME():
+		s.certificates.removeService(svc.Name)
+		s.logger.Warn("Service requests an ACME certificate but ACME is not enabled", "name", svc.Name)
+	case svc.HasCertificate():
+		// A missing certificate leaves the service reachable over HTTP and is retried when the files appear
+		if err := s.certificates.setService(svc.Name, svc.TLS.CertFile, svc.TLS.KeyFile); err != nil {
+			s.logger.Error("Failed to load service certificate", "name", svc.Name, "error", err)
+		}
+	default:
+		s.certificates.removeService(svc.Name)
+	}
+
+	if s.acme != nil && !svc.UsesACME() {
+		s.acme.release(svc.Name)
+	}
+}
+
+// Certificate store functions
+
+// MARK: newCertificateStore
+// Creates an empty certificate store
+func newCertificateStore(logger *internal.Logger) *certificateStore {
+	return &certificateStore{
+		logger:   logger,
+		hosts:    make(map[string]*certificateFile),
+		services: make(map[string]*certificateFile),
+	}
+}
+
+// MARK: setGlobal
+// Loads the configured hostname certificates, keeping entries whose files failed to load so they are retried
+func (c *certificateStore) setGlobal(certs []config.CertificateConfig) error {
+	global := make([]*certificateFile, 0, len(certs))
+	hosts := make(map[string]*certificateFile)
+	var errs []error
+
+	for _, certConfig := range certs {
+		file := &certificateFile{certFile: certConfig.CertFile, keyFile: certConfig.KeyFile}
+		if err := file.load(); err != nil {
+			errs = append(errs, err)
+		}
+		global = append(global, file)
+
+		for _, host := range certConfig.Hosts {
+			hosts[strings.ToLower(strings.TrimSpace(host))] = file
+		}
+	}
+
+	c.mu.Lock()
+	c.global = global
+	c.hosts = hosts
+	c.mu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// MARK: setService
+// Loads the certificate a service is served with
+func (c *certificateStore) setService(name, certFile, keyFile string) error {
+	file := &certificateFile{certFile: certFile, keyFile: keyFile}
+	err := file.load()
+
+	c.mu.Lock()
+	c.services[name] = file
+	c.mu.Unlock()
+
+	return err
+}
+
+// MARK: removeService
+// Forgets the certificate of a removed service
+func (c *certificateStore) removeService(name string) {
+	c.mu.Lock()
+	delete(c.services, name)
+	c.mu.Unlock()
+}
+
+// MARK: lookup
+// Returns the most specific certificate for a host: an explicit hostname, the matching service, then any certificate covering the name
+func (c *certificateStore) lookup(host, serviceName string) *tls.Certificate {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if host != "" {
+		if file, ok := c.hosts[host]; ok && file.cert != nil {
+			return file.cert
+		}
+		if dot := strings.IndexByte(host, '.'); dot > 0 {
+			if file, ok := c.hosts["*"+host[dot:]]; ok && file.cert != nil {
+				return file.cert
+			}
+		}
+	}
+
+	if file, ok := c.services[serviceName]; ok && file.cert != nil {
+		return file.cert
+	}
+
+	for _, file := range c.global {
+		if file.cert != nil && file.cert.Leaf != nil && file.cert.Leaf.VerifyHostname(host) == nil {
+			return file.cert
+		}
+	}
+
+	// Service certificates may cover names the service is not routed by, such as extra ACME domains
+	if host != "" {
+		for _, name := range slices.Sorted(maps.Keys(c.services)) {
+			file := c.services[name]
+			if file.cert != nil && file.cert.Leaf != nil && file.cert.Leaf.VerifyHostname(host) == nil {
+				return file.cert
+			}
+		}
+	}
+
+	// Clients without SNI, such as those connecting by IP, get the first usable certificate
+	if host == "" || net.ParseIP(host) != nil {
+		for _, file := range c.global {
+			if file.cert != nil {
+				return file.cert
+			}
+		}
+	}
+
+	return nil
+}
+
+// MARK: watch
+// Periodically reloads certificates whose files have changed until the context ends
+func (c *certificateStore) watch(ctx context.Context) {
+	ticker := time.NewTicker(certificateReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.reloadChanged()
+		}
+	}
+}
+
+// MARK: reloadChanged
+// Reloads each certificate whose certificate or key file was modified since it was last read
+func (c *certificateStore) reloadChanged() {
+	c.mu.RLock()
+	files := make([]*certificateFile, 0, len(c.global)+len(c.services))
+	files = append(files, c.global...)
+	for _, file := range c.services {
+		files = append(files, file)
+	}
+	c.mu.RUnlock()
+
+	for _, file := range files {
+		modTime, err := file.latestModTime()
+		if err != nil {
+			continue
+		}
+
+		c.mu.RLock()
+		changed := !modTime.Equal(file.modTime)
+		c.mu.RUnlock()
+		if !changed {
+			continue
+		}
+
+		replacement := &certificateFile{certFile: file.certFile, keyFile: file.keyFile}
+		loadErr := replacement.load()
+
+		c.mu.Lock()
+		file.modTime = replacement.modTime
+		if loadErr == nil {
+			file.cert = replacement.cert
+		}
+		c.mu.Unlock()
+
+		if loadErr != nil {
+			c.logger.Warn("Failed to reload certificate, keeping the previous one", "cert_file", file.certFile, "error", loadErr)
+			continue
+		}
+		c.logger.Info("Reloaded certificate", "cert_file", file.certFile, "expires", replacement.cert.Leaf.NotAfter)
+	}
+}
+
+// MARK: load
+// Reads the certificate and key pair and records when the files were last modified
+func (f *certificateFile) load() error {
+	modTime, err := f.latestModTime()
+	if err != nil {
+		return err
+	}
+	f.modTime = modTime
+
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading certificate %s: %w", f.certFile, err)
+	}
+
+	f.cert = &cert
+	return nil
+}
+
+// MARK: latestModTime
+// Returns the newer modification time of the certificate and key files
+func (f *certificateFile) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{f.certFile, f.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("reading certificate file %s: %w", path, err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -1,38 +1,112 @@
 package proxy
 
 import (
+	"context"
+	"crypto/tls"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/JPKribs/FinGuard/config"
 	"github.com/JPKribs/FinGuard/internal"
+	"golang.org/x/crypto/acme"
 )
 
 // MARK: ProxyService
 type ProxyService struct {
 	Config   config.ServiceConfig
 	Upstream *url.URL
+	Targets  []*upstreamTarget
 	Proxy    *httputil.ReverseProxy
 	Health   *ServiceHealth
+	next     atomic.Uint64
 	mu       sync.RWMutex
 }
 
+// MARK: upstreamTarget
+type upstreamTarget struct {
+	url          *url.URL
+	id           string
+	active       atomic.Int64
+	health       ServiceHealth
+	failures     int
+	ejectedUntil time.Time
+	mu           sync.Mutex
+}
+
+// MARK: upstreamContextKey
+type upstreamContextKey struct{}
+
+// MARK: UpstreamStatus
+type UpstreamStatus struct {
+	URL               string `json:"url"`
+	Ejected           bool   `json:"ejected"`
+	ActiveConnections int64  `json:"active_connections"`
+	ServiceHealth
+}
+
 // MARK: responseWriter
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
 }
 
+// MARK: TunnelDialFunc
+type TunnelDialFunc func(ctx context.Context, tunnelName, network, address string) (net.Conn, error)
+
+// MARK: TunnelSelectFunc
+type TunnelSelectFunc func(svc config.ServiceConfig) string
+
+// MARK: TunnelStateFunc
+type TunnelStateFunc func(tunnelName string) bool
+
+// MARK: TunnelWakeFunc
+type TunnelWakeFunc func(ctx context.Context, tunnelName string) (release func(), err error)
+
+// MARK: TunnelDownError
+type TunnelDownError struct {
+	Service string
+	Tunnel  string
+}
+
 // MARK: Server
 type Server struct {
-	logger   *internal.Logger
-	services map[string]*ProxyService
-	server   *http.Server
-	running  bool
-	mu       sync.RWMutex
+	logger          *internal.Logger
+	services        map[string]*ProxyService
+	server          *http.Server
+	running         bool
+	tunnelDialer    atomic.Pointer[TunnelDialFunc]
+	tunnelSelector  TunnelSelectFunc
+	tunnelState     TunnelStateFunc
+	tunnelWaker     TunnelWakeFunc
+	tlsServer       *http.Server
+	tlsAddr         string
+	certificates    *certificateStore
+	acme            *acmeIssuer
+	baseDomains     []string
+	hostPrefixMatch bool
+	routes          *hostRoutes
+	mu              sync.RWMutex
+}
+
+// MARK: hostRoutes
+type hostRoutes struct {
+	exact        map[string]*hostRoute
+	wildcards    []*hostRoute
+	regexes      []*hostRoute
+	legacy       []*ProxyService
+	legacyPrefix bool
+	fallback     *ProxyService
+}
+
+// MARK: hostRoute
+type hostRoute struct {
+	pattern  config.HostPattern
+	services []*ProxyService
 }
 
 // MARK: ServiceHealth
@@ -42,3 +116,43 @@ type ServiceHealth struct {
 	LastError   string    `json:"last_error,omitempty"`
 	Consecutive int       `json:"consecutive_failures"`
 }
+
+// MARK: certificateStore
+type certificateStore struct {
+	logger   *internal.Logger
+	global   []*certificateFile
+	hosts    map[string]*certificateFile
+	services map[string]*certificateFile
+	mu       sync.RWMutex
+}
+
+// MARK: certificateFile
+type certificateFile struct {
+	certFile string
+	keyFile  string
+	modTime  time.Time
+	cert     *tls.Certificate
+}
+
+// MARK: acmeIssuer
+type acmeIssuer struct {
+	logger       *internal.Logger
+	config       config.ACMEConfig
+	client       *acme.Client
+	certificates *certificateStore
+	registered   bool
+	tokens       sync.Map
+	managed      map[string]*acmeCertificate
+	wake         chan struct{}
+	mu           sync.Mutex
+}
+
+// MARK: acmeCertificate
+type acmeCertificate struct {
+	name     string
+	domains  []string
+	certFile string
+	keyFile  string
+	failures int
+	retryAt  time.Time
+}
@@ -0,0 +1,27 @@
+package utilities
+
+import (
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	qrCodeSize = 512
+)
+
+// QR code rendering functions
+
+// MARK: QRCodePNG
+// Renders content as a PNG QR code image
+func QRCodePNG(content string) ([]byte, error) {
+	return qrcode.Encode(content, qrcode.Medium, qrCodeSize)
+}
+
+// MARK: QRCodeText
+// Renders content as a QR code made of block characters for display in a terminal
+func QRCodeText(content string) (string, error) {
+	code, err := qrcode.New(content, qrcode.Low)
+	if err != nil {
+		return "", err
+	}
+	return code.ToSmallString(false), nil
+}
@@ -108,6 +108,13 @@ class APIClient {
         });
     }
 
+    // MARK: serviceAction
+    static async serviceAction(name, action) {
+        return await this.apiCall(`/services/${encodeURIComponent(name)}/${action}`, {
+            method: 'POST'
+        });
+    }
+
     // TUNNEL ENDPOINTS
 
     // MARK: getTunnels
@@ -130,6 +137,13 @@ class APIClient {
         });
     }
 
+    // MARK: tunnelAction
+    static async tunnelAction(name, action) {
+        return await this.apiCall(`/tunnels/${encodeURIComponent(name)}/${action}`, {
+            method: 'POST'
+        });
+    }
+
     // MARK: deleteTunnel
     static async deleteTunnel(name) {
         return await this.apiCall(`/tunnels/${encodeURIComponent(name)}`, { 
@@ -137,6 +151,14 @@ class APIClient {
         });
     }
 
+    // MARK: provisionPeer
+    static async provisionPeer(tunnelName, peerName) {
+        return await this.apiCall(`/tunnels/${encodeURIComponent(tunnelName)}/provision`, {
+            method: 'POST',
+            body: JSON.stringify({ name: peerName })
+        });
+    }
+
     // LOG ENDPOINTS
 
     // MARK: getLogs
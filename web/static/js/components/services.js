@@ -74,12 +74,29 @@ class ServicesManager {
                 </div>
                 <div style="display: flex; flex-direction: column; justify-content: space-between; align-items: flex-end; margin-left: 1rem; padding-left: 1rem; border-left: 1px solid var(--color-border); align-self: stretch;">
                     <span class="status ${service.status === 'running' ? 'running' : 'stopped'}">${service.status}</span>
+                    ${this.generateServiceActionsHTML(service)}
                     <button class="btn-danger btn-small" onclick="window.ServicesManager.deleteService('${window.Utils.escapeHtml(service.name)}')">Delete</button>                   
                 </div>
             </div>
         `;
     }
 
+    // MARK: generateServiceActionsHTML
+    static generateServiceActionsHTML(service) {
+        const escapedName = window.Utils.escapeHtml(service.name);
+
+        if (service.status === 'disabled') {
+            return `<button class="btn-small" onclick="window.ServicesManager.serviceAction('${escapedName}', 'enable')" title="Enable and start serving the service">Enable</button>`;
+        }
+
+        const startStopButton = service.status === 'running' ?
+            `<button class="btn-small" onclick="window.ServicesManager.serviceAction('${escapedName}', 'stop')" title="Stop serving until started again">Stop</button>` :
+            `<button class="btn-small" onclick="window.ServicesManager.serviceAction('${escapedName}', 'start')" title="Start serving the service">Start</button>`;
+
+        return `${startStopButton}
+                    <button class="btn-small" onclick="window.ServicesManager.serviceAction('${escapedName}', 'disable')" title="Stop serving and keep it stopped across restarts">Disable</button>`;
+    }
+
     // MARK: buildServiceInfoRows
     static buildServiceInfoRows(service) {
         const infoRows = [
@@ -90,6 +107,18 @@ class ServicesManager {
             infoRows.push({ label: 'Tunnel', value: service.tunnel });
         }
 
+        if (service.backup_tunnels && service.backup_tunnels.length > 0) {
+            infoRows.push({ label: 'Backup Tunnels', value: service.backup_tunnels.join(', ') });
+        }
+
+        if (service.active_tunnel && service.active_tunnel !== service.tunnel) {
+            infoRows.push({ label: 'Active Tunnel', value: service.active_tunnel });
+        }
+
+        if (service.tunnel) {
+            infoRows.push({ label: 'Require Tunnel', value: service.require_tunnel ? '✓' : '✗' });
+        }
+
         infoRows.push(
             { label: 'Jellyfin', value: service.jellyfin ? '✓' : '✗' },
             { label: 'WebSocket', value: service.websocket ? '✓' : '✗' },
@@ -147,6 +176,18 @@ class ServicesManager {
 
     // SERVICE OPERATIONS
 
+    // MARK: serviceAction
+    static async serviceAction(name, action) {
+        try {
+            const response = await window.APIClient.serviceAction(name, action);
+            window.Utils.showAlert(response.message || `Service "${name}" updated`, 'success');
+            this.loadServices();
+        } catch (error) {
+            console.error(`Failed to ${action} service:`, error);
+            window.Utils.showAlert(`Failed to ${action} service "${name}": ${error.message}`, 'error');
+        }
+    }
+
     // MARK: deleteService
     static async deleteService(name) {
         if (!this.confirmServiceDeletion(name)) return;
@@ -288,7 +329,8 @@ class ServicesManager {
             websocket: document.getElementById('serviceWebsocket').checked,
             default: document.getElementById('serviceDefault').checked,
             publish_mdns: document.getElementById('serviceMDNS').checked,
-            tunnel: document.getElementById('serviceTunnel').value || undefined
+            tunnel: document.getElementById('serviceTunnel').value || undefined,
+            require_tunnel: document.getElementById('serviceRequireTunnel').checked
         };
     }
 
@@ -307,6 +349,11 @@ class ServicesManager {
             return false;
         }
 
+        if (service.require_tunnel && !service.tunnel) {
+            window.Utils.showAlert('Require Tunnel needs a tunnel to be selected', 'error');
+            return false;
+        }
+
         return true;
     }
 
@@ -70,8 +70,17 @@ class TunnelsManager {
         const infoRows = [];
         
         if (tunnel.interface) infoRows.push({ label: 'Interface', value: tunnel.interface });
+        if (tunnel.public_key) infoRows.push({ label: 'Public Key', value: tunnel.public_key });
         infoRows.push({ label: 'Peers', value: tunnel.peers || 0 });
         infoRows.push({ label: 'MTU', value: tunnel.mtu || 'N/A' });
+
+        if (tunnel.supervisor && tunnel.supervisor.state !== 'up') {
+            infoRows.push({ label: 'Supervisor', value: this.formatSupervisor(tunnel.supervisor) });
+        }
+
+        if (tunnel.probes && tunnel.probes.length > 0) {
+            infoRows.push({ label: 'Reachability', value: this.formatProbes(tunnel.probes) });
+        }
         
         if (tunnel.routes && tunnel.routes.length > 0) {
             const routeText = this.formatRoutes(tunnel.routes);
@@ -81,6 +90,23 @@ class TunnelsManager {
         return infoRows;
     }
 
+    // MARK: formatSupervisor
+    static formatSupervisor(supervisor) {
+        let text = supervisor.state;
+        if (supervisor.attempts > 0) text += `, attempt ${supervisor.attempts}`;
+        if (supervisor.next_retry) text += `, retry at ${new Date(supervisor.next_retry).toLocaleTimeString()}`;
+        return text;
+    }
+
+    // MARK: formatProbes
+    static formatProbes(probes) {
+        return probes.map(probe => {
+            if (!probe.last_run) return `${probe.name}: pending`;
+            const state = probe.healthy ? 'up' : 'down';
+            return `${probe.name}: ${state}, ${probe.avg_latency_ms.toFixed(1)} ms, ${Math.round(probe.loss_percent)}% loss`;
+        }).join('; ');
+    }
+
     // MARK: formatRoutes
     static formatRoutes(routes) {
         if (routes.length > 2) {
@@ -108,9 +134,26 @@ class TunnelsManager {
             `<button class="btn-small" onclick="window.TunnelsManager.restartTunnel('${escapedName}')" title="Restart tunnel to apply route changes">Restart</button>` : 
             '';
 
+        const provisionButton = tunnel.server_mode ?
+            `<button class="btn-small" onclick="window.TunnelsManager.provisionDevice('${escapedName}')" title="Create a client config for a new device">Add Device</button>` :
+            '';
+
+        let lifecycleButtons;
+        if (tunnel.state === 'disabled') {
+            lifecycleButtons = `<button class="btn-small" onclick="window.TunnelsManager.tunnelAction('${escapedName}', 'enable')" title="Enable and start the tunnel">Enable</button>`;
+        } else {
+            const startStopButton = isRunning ?
+                `<button class="btn-small" onclick="window.TunnelsManager.tunnelAction('${escapedName}', 'stop')" title="Stop the tunnel until it is started again">Stop</button>` :
+                `<button class="btn-small" onclick="window.TunnelsManager.tunnelAction('${escapedName}', 'start')" title="Start the tunnel">Start</button>`;
+            lifecycleButtons = `${startStopButton}
+                <button class="btn-small" onclick="window.TunnelsManager.tunnelAction('${escapedName}', 'disable')" title="Stop the tunnel and keep it stopped across restarts">Disable</button>`;
+        }
+
         return `
             <div style="display: flex; flex-direction: column; gap: 0.25rem;">
+                ${provisionButton}
                 ${restartButton}
+                ${lifecycleButtons}
                 <button class="btn-danger btn-small" onclick="window.TunnelsManager.deleteTunnel('${escapedName}')">Delete</button>
             </div>
         `;
@@ -147,6 +190,50 @@ class TunnelsManager {
         setTimeout(() => this.loadTunnels(), 1000);
     }
 
+    // MARK: tunnelAction
+    static async tunnelAction(name, action) {
+        try {
+            const response = await window.APIClient.tunnelAction(name, action);
+            window.Utils.showAlert(response.message || `Tunnel "${name}" updated`, 'success');
+            this.loadTunnels();
+        } catch (error) {
+            this.handleTunnelError(error, action, name);
+        }
+    }
+
+    // MARK: provisionDevice
+    static async provisionDevice(tunnelName) {
+        const deviceName = prompt(`Name for the new device on "${tunnelName}":`);
+        if (!deviceName) return;
+
+        try {
+            const response = await window.APIClient.provisionPeer(tunnelName, deviceName.trim());
+            this.showProvisionedDevice(deviceName.trim(), response.data);
+            this.loadTunnels();
+        } catch (error) {
+            this.handleTunnelError(error, 'provision a device on', tunnelName);
+        }
+    }
+
+    // MARK: showProvisionedDevice
+    static showProvisionedDevice(deviceName, data) {
+        const modal = document.createElement('div');
+        modal.id = 'provisionModal';
+        modal.className = 'token-modal';
+        modal.innerHTML = `
+            <div class="token-modal-content">
+                <h3>${window.Utils.escapeHtml(deviceName)}</h3>
+                <p>Scan with the WireGuard app. The private key is not stored, so save this config now.</p>
+                <img src="${data.qr_code}" alt="WireGuard client config QR code" style="width: 100%; max-width: 320px; display: block; margin: 0 auto;">
+                <textarea readonly rows="10" style="width: 100%; font-family: monospace;">${window.Utils.escapeHtml(data.client_config)}</textarea>
+                <div class="token-actions">
+                    <button type="button" onclick="document.getElementById('provisionModal').remove()">Done</button>
+                </div>
+            </div>
+        `;
+        document.body.appendChild(modal);
+    }
+
     // MARK: deleteTunnel
     static async deleteTunnel(name) {
         if (!this.confirmTunnelDeletion(name)) return;
@@ -375,7 +462,9 @@ class TunnelsManager {
             peers: peers,
             monitor_interval: parseInt(formData.get('tunnelMonitorInterval')) || 30,
             stale_connection_timeout: parseInt(formData.get('tunnelStaleTimeout')) || 300,
-            reconnection_retries: parseInt(formData.get('tunnelReconnectRetries')) || 3
+            reconnection_retries: parseInt(formData.get('tunnelReconnectRetries')) || 3,
+            on_demand: formData.get('tunnelOnDemand') === 'on',
+            idle_timeout: parseInt(formData.get('tunnelIdleTimeout')) || 300
         };
     }
 
@@ -470,7 +559,8 @@ class TunnelsManager {
             { id: 'tunnelMTU', value: '1420' },
             { id: 'tunnelMonitorInterval', value: '30' },
             { id: 'tunnelStaleTimeout', value: '300' },
-            { id: 'tunnelReconnectRetries', value: '3' }
+            { id: 'tunnelReconnectRetries', value: '3' },
+            { id: 'tunnelIdleTimeout', value: '300' }
         ];
         
         fieldsWithDefaults.forEach(field => {
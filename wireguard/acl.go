@@ -0,0 +1,343 @@
+package wireguard
+
+import (
+	"net/netip"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/JPKribs/FinGuard/config"
+	"golang.zx2c4.com/wireguard/tun"
+)
+
+// Filter device functions
+
+// MARK: newFilterDevice
+// Wraps the device handed to WireGuard so packets from peers can be checked against the tunnel ACL
+func newFilterDevice(dev tun.Device) *filterDevice {
+	d := &filterDevice{Device: dev, done: make(chan struct{})}
+	go d.sweepFlows()
+	return d
+}
+
+// MARK: Read
+// Reads packets headed to peers and remembers their flows so replies are let back in
+func (d *filterDevice) Read(bufs [][]byte, sizes []int, offset int) (int, error) {
+	n, err := d.Device.Read(bufs, sizes, offset)
+	if d.acl.Load() != nil {
+		now := time.Now().UnixNano()
+		for i := 0; i < n; i++ {
+			d.trackFlow(bufs[i][offset:offset+sizes[i]], now)
+		}
+	}
+	return n, err
+}
+
+// MARK: Write
+// Drops packets from peers that the ACL denies before they reach the host or network stack
+func (d *filterDevice) Write(bufs [][]byte, offset int) (int, error) {
+	acl := d.acl.Load()
+	if acl == nil {
+		return d.Device.Write(bufs, offset)
+	}
+
+	// Only copy the batch once a packet is actually denied
+	var allowed [][]byte
+	for i, buf := range bufs {
+		if len(buf) > offset && d.permits(acl, buf[offset:]) {
+			if allowed != nil {
+				allowed = append(allowed, buf)
+			}
+			continue
+		}
+		if allowed == nil {
+			allowed = make([][]byte, i, len(bufs))
+			copy(allowed, bufs[:i])
+		}
+	}
+
+	if allowed == nil {
+		return d.Device.Write(bufs, offset)
+	}
+
+	if len(allowed) > 0 {
+		if _, err := d.Device.Write(allowed, offset); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(bufs), nil
+}
+
+// MARK: Close
+// Stops the flow sweeper and closes the wrapped device
+func (d *filterDevice) Close() error {
+	d.closeOnce.Do(func() { close(d.done) })
+	return d.Device.Close()
+}
+
+// MARK: SetACL
+// Replaces the active rule set, keeping hit counts for rules that did not change
+func (d *filterDevice) SetACL(acl *aclFilter) {
+	if previous := d.acl.Load(); previous != nil && acl != nil {
+		acl.inheritCounters(previous)
+	}
+	d.acl.Store(acl)
+}
+
+// MARK: permits
+// Checks if an inbound packet belongs to a flow we opened or is allowed by the rules
+func (d *filterDevice) permits(acl *aclFilter, packet []byte) bool {
+	header, ok := parsePacketHeader(packet)
+	if !ok {
+		// A packet whose IP header cannot be read could evade any rule, so it is never let through
+		return false
+	}
+
+	if flow, ok := inboundFlow(header); ok {
+		if _, exists := d.flows.Load(flow); exists {
+			acl.established.Add(1)
+			return true
+		}
+	}
+
+	return acl.evaluate(header)
+}
+
+// MARK: trackFlow
+// Records the flow of an outbound packet so that inbound replies on it are allowed
+func (d *filterDevice) trackFlow(packet []byte, now int64) {
+	header, ok := parsePacketHeader(packet)
+	if !ok {
+		return
+	}
+
+	flow, ok := outboundFlow(header)
+	if !ok {
+		return
+	}
+
+	if lastSeen, exists := d.flows.Load(flow); exists {
+		lastSeen.(*atomic.Int64).Store(now)
+		return
+	}
+
+	lastSeen := &atomic.Int64{}
+	lastSeen.Store(now)
+	d.flows.Store(flow, lastSeen)
+}
+
+// MARK: sweepFlows
+// Periodically forgets flows that have been idle longer than the flow timeout
+func (d *filterDevice) sweepFlows() {
+	ticker := time.NewTicker(flowSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.done:
+			return
+		case now := <-ticker.C:
+			threshold := now.Add(-flowIdleTimeout).UnixNano()
+			d.flows.Range(func(key, value interface{}) bool {
+				if value.(*atomic.Int64).Load() < threshold {
+					d.flows.Delete(key)
+				}
+				return true
+			})
+		}
+	}
+}
+
+// MARK: applyACL
+// Recompiles the tunnel ACL so rule and peer address changes take effect without a restart
+func (t *Tunnel) applyACL() {
+	if t.filter == nil {
+		return
+	}
+
+	t.filter.SetACL(compileACL(t.config))
+	if t.config.ACL != nil {
+		t.logger.Debug("Applied tunnel ACL", "name", t.name, "rules", len(t.config.ACL.Rules), "default", t.config.ACL.Default)
+	}
+}
+
+// MARK: aclStatus
+// Returns the hit counters of the enforced ACL, if any
+func (t *Tunnel) aclStatus() *ACLStatus {
+	t.mu.RLock()
+	filter := t.filter
+	t.mu.RUnlock()
+
+	if filter == nil {
+		return nil
+	}
+
+	if acl := filter.acl.Load(); acl != nil {
+		return acl.status()
+	}
+	return nil
+}
+
+// Rule evaluation functions
+
+// MARK: compileACL
+// Builds the rule set for a tunnel, or nil when the tunnel has no ACL to enforce
+func compileACL(cfg config.TunnelConfig) *aclFilter {
+	if cfg.ACL == nil || (len(cfg.ACL.Rules) == 0 && cfg.ACL.DefaultAllows()) {
+		return nil
+	}
+
+	acl := &aclFilter{defaultAllow: cfg.ACL.DefaultAllows()}
+
+	for _, ruleConfig := range cfg.ACL.Rules {
+		rule := &aclRule{
+			config: ruleConfig,
+			allow:  strings.EqualFold(ruleConfig.Action, config.ACLActionAllow),
+			proto:  ruleConfig.ProtocolName(),
+		}
+		if ruleConfig.Destination != "" {
+			rule.dest, _ = ruleConfig.DestinationPrefix()
+		}
+		if ruleConfig.Port != "" {
+			rule.portLow, rule.portHigh, _ = ruleConfig.PortRange()
+		}
+		acl.rules = append(acl.rules, rule)
+	}
+
+	for _, peer := range cfg.Peers {
+		for _, allowedIP := range peer.AllowedIPs {
+			prefix, err := netip.ParsePrefix(strings.TrimSpace(allowedIP))
+			if err != nil {
+				continue
+			}
+			acl.peers = append(acl.peers, aclPeerPrefix{
+				prefix:    prefix.Masked(),
+				name:      peer.Name,
+				publicKey: strings.TrimSpace(peer.PublicKey),
+			})
+		}
+	}
+
+	// Most specific first, matching how WireGuard picks the peer for a source address
+	sort.SliceStable(acl.peers, func(i, j int) bool {
+		return acl.peers[i].prefix.Bits() > acl.peers[j].prefix.Bits()
+	})
+
+	return acl
+}
+
+// MARK: evaluate
+// Applies the first matching rule to a new inbound packet, falling back to the default action
+func (a *aclFilter) evaluate(header packetHeader) bool {
+	peer := a.sourcePeer(header.src)
+
+	for _, rule := range a.rules {
+		if rule.matches(peer, header) {
+			rule.hits.Add(1)
+			return rule.allow
+		}
+	}
+
+	a.defaultHits.Add(1)
+	return a.defaultAllow
+}
+
+// MARK: sourcePeer
+// Returns the peer whose allowed IPs contain the source address
+func (a *aclFilter) sourcePeer(src netip.Addr) *aclPeerPrefix {
+	for i := range a.peers {
+		if a.peers[i].prefix.Contains(src) {
+			return &a.peers[i]
+		}
+	}
+	return nil
+}
+
+// MARK: matches
+// Checks a packet against the peer, destination, protocol and port of a rule, failing closed when the packet hides them
+func (r *aclRule) matches(peer *aclPeerPrefix, header packetHeader) bool {
+	if r.config.Peer != "" && (peer == nil || (r.config.Peer != peer.name && r.config.Peer != peer.publicKey)) {
+		return false
+	}
+
+	if r.dest.IsValid() && !r.dest.Contains(header.dst) {
+		return false
+	}
+
+	// Without a transport header the protocol or port is unknown, so only a deny rule may claim the packet
+	if header.opaque && (r.proto != config.ACLProtocolAny || r.portLow != 0) {
+		return !r.allow
+	}
+
+	switch r.proto {
+	case config.ACLProtocolTCP:
+		if header.proto != ipProtoTCP {
+			return false
+		}
+	case config.ACLProtocolUDP:
+		if header.proto != ipProtoUDP {
+			return false
+		}
+	case config.ACLProtocolICMP:
+		if header.proto != ipProtoICMP && header.proto != ipProtoICMPv6 {
+			return false
+		}
+	}
+
+	if r.portLow != 0 {
+		// Non-first fragments and truncated headers carry no ports
+		if header.fragment || len(header.transport) < 4 {
+			return !r.allow
+		}
+		if header.dstPort < r.portLow || header.dstPort > r.portHigh {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MARK: inheritCounters
+// Carries hit counts over from the previous rule set where the rules are unchanged
+func (a *aclFilter) inheritCounters(previous *aclFilter) {
+	if a.defaultAllow == previous.defaultAllow {
+		a.defaultHits.Store(previous.defaultHits.Load())
+	}
+	a.established.Store(previous.established.Load())
+
+	for i, rule := range a.rules {
+		if i < len(previous.rules) && previous.rules[i].config == rule.config {
+			rule.hits.Store(previous.rules[i].hits.Load())
+		}
+	}
+}
+
+// MARK: status
+// Reports the rules of the active ACL with their hit counts
+func (a *aclFilter) status() *ACLStatus {
+	status := &ACLStatus{
+		Default:     config.ACLActionAllow,
+		DefaultHits: a.defaultHits.Load(),
+		Established: a.established.Load(),
+		Rules:       make([]ACLRuleStatus, 0, len(a.rules)),
+	}
+	if !a.defaultAllow {
+		status.Default = config.ACLActionDeny
+	}
+
+	for _, rule := range a.rules {
+		status.Rules = append(status.Rules, ACLRuleStatus{
+			Name:        rule.config.Name,
+			Action:      strings.ToLower(rule.config.Action),
+			Peer:        rule.config.Peer,
+			Destination: rule.config.Destination,
+			Protocol:    rule.proto,
+			Port:        rule.config.Port,
+			Hits:        rule.hits.Load(),
+		})
+	}
+
+	return status
+}
@@ -0,0 +1,275 @@
+package wireguard
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+
+	"github.com/JPKribs/FinGuard/config"
+)
+
+func TestCompileACL(t *testing.T) {
+	tests := []struct {
+		name    string
+		acl     *config.ACLConfig
+		wantNil bool
+	}{
+		{name: "no acl", acl: nil, wantNil: true},
+		{name: "allow all without rules", acl: &config.ACLConfig{}, wantNil: true},
+		{name: "deny default without rules", acl: &config.ACLConfig{Default: config.ACLActionDeny}},
+		{name: "rules", acl: &config.ACLConfig{Rules: []config.ACLRule{{Action: config.ACLActionDeny, Port: "22", Protocol: "tcp"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			acl := compileACL(config.TunnelConfig{Name: "wg0", ACL: tt.acl})
+			if (acl == nil) != tt.wantNil {
+				t.Fatalf("compileACL returned %v, want nil %v", acl, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestCompileACLOrdersPeersBySpecificity(t *testing.T) {
+	acl := compileACL(config.TunnelConfig{
+		Name: "wg0",
+		ACL:  &config.ACLConfig{Default: config.ACLActionDeny},
+		Peers: []config.PeerConfig{
+			{Name: "site", PublicKey: "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE=", AllowedIPs: []string{"10.0.0.0/16"}},
+			{Name: "phone", PublicKey: "AgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgI=", AllowedIPs: []string{"10.0.5.2/32", "bad"}},
+		},
+	})
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"10.0.5.2", "phone"},
+		{"10.0.7.9", "site"},
+	}
+
+	for _, tt := range tests {
+		if peer := acl.sourcePeer(netip.MustParseAddr(tt.src)); peer == nil || peer.name != tt.want {
+			t.Errorf("sourcePeer(%s) = %+v, want %s", tt.src, peer, tt.want)
+		}
+	}
+}
+
+func TestACLEvaluate(t *testing.T) {
+	const laptopKey = "AgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgI="
+	peers := []config.PeerConfig{
+		{Name: "phone", PublicKey: "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE=", AllowedIPs: []string{"10.0.0.2/32", "fd00::2/128"}},
+		{Name: "laptop", PublicKey: laptopKey, AllowedIPs: []string{"10.0.0.3/32"}},
+	}
+
+	denySSH := &config.ACLConfig{Rules: []config.ACLRule{
+		{Action: config.ACLActionDeny, Protocol: "tcp", Port: "22"},
+	}}
+
+	allowJellyfin := &config.ACLConfig{
+		Default: config.ACLActionDeny,
+		Rules: []config.ACLRule{
+			{Action: config.ACLActionAllow, Peer: "phone", Destination: "192.168.1.50", Protocol: "tcp", Port: "8096"},
+			{Action: config.ACLActionAllow, Peer: laptopKey, Protocol: "icmp"},
+			{Action: config.ACLActionAllow, Peer: "laptop", Protocol: "udp", Port: "5000-5010"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		acl      *config.ACLConfig
+		src      string
+		dst      string
+		proto    byte
+		dstPort  uint16
+		fragment bool
+		opaque   bool
+		want     bool
+	}{
+		{
+			name:    "deny rule matches",
+			acl:     denySSH,
+			src:     "10.0.0.2",
+			dst:     "192.168.1.10",
+			proto:   ipProtoTCP,
+			dstPort: 22,
+			want:    false,
+		},
+		{
+			name:    "other port falls through to default allow",
+			acl:     denySSH,
+			src:     "10.0.0.2",
+			dst:     "192.168.1.10",
+			proto:   ipProtoTCP,
+			dstPort: 80,
+			want:    true,
+		},
+		{
+			name:    "udp does not match a tcp rule",
+			acl:     denySSH,
+			src:     "10.0.0.2",
+			dst:     "192.168.1.10",
+			proto:   ipProtoUDP,
+			dstPort: 22,
+			want:    true,
+		},
+		{
+			name:     "ipv4 fragment cannot slip past a deny port rule",
+			acl:      denySSH,
+			src:      "10.0.0.2",
+			dst:      "192.168.1.10",
+			proto:    ipProtoTCP,
+			fragment: true,
+			want:     false,
+		},
+		{
+			name:    "ipv6 denied port",
+			acl:     denySSH,
+			src:     "fd00::2",
+			dst:     "fd00::1",
+			proto:   ipProtoTCP,
+			dstPort: 22,
+			want:    false,
+		},
+		{
+			name:    "ipv6 allowed port",
+			acl:     denySSH,
+			src:     "fd00::2",
+			dst:     "fd00::1",
+			proto:   ipProtoTCP,
+			dstPort: 443,
+			want:    true,
+		},
+		{
+			name:     "ipv6 fragment cannot slip past a deny port rule",
+			acl:      denySSH,
+			src:      "fd00::2",
+			dst:      "fd00::1",
+			proto:    ipProtoTCP,
+			fragment: true,
+			want:     false,
+		},
+		{
+			name:   "opaque ipv6 header chain is denied by a port rule",
+			acl:    denySSH,
+			src:    "fd00::2",
+			dst:    "fd00::1",
+			opaque: true,
+			want:   false,
+		},
+		{
+			name:  "truncated transport header is denied by a port rule",
+			acl:   denySSH,
+			src:   "10.0.0.2",
+			dst:   "192.168.1.10",
+			proto: ipProtoTCP,
+			want:  false,
+		},
+		{
+			name:    "peer, destination and port allow",
+			acl:     allowJellyfin,
+			src:     "10.0.0.2",
+			dst:     "192.168.1.50",
+			proto:   ipProtoTCP,
+			dstPort: 8096,
+			want:    true,
+		},
+		{
+			name:    "wrong destination falls to default deny",
+			acl:     allowJellyfin,
+			src:     "10.0.0.2",
+			dst:     "192.168.1.51",
+			proto:   ipProtoTCP,
+			dstPort: 8096,
+			want:    false,
+		},
+		{
+			name:    "other peer does not match a named peer rule",
+			acl:     allowJellyfin,
+			src:     "10.0.0.3",
+			dst:     "192.168.1.50",
+			proto:   ipProtoTCP,
+			dstPort: 8096,
+			want:    false,
+		},
+		{
+			name:  "peer matched by public key",
+			acl:   allowJellyfin,
+			src:   "10.0.0.3",
+			dst:   "192.168.1.50",
+			proto: ipProtoICMP,
+			want:  true,
+		},
+		{
+			name:    "port range",
+			acl:     allowJellyfin,
+			src:     "10.0.0.3",
+			dst:     "192.168.1.50",
+			proto:   ipProtoUDP,
+			dstPort: 5005,
+			want:    true,
+		},
+		{
+			name:     "fragment never matches an allow port rule",
+			acl:      allowJellyfin,
+			src:      "10.0.0.2",
+			dst:      "192.168.1.50",
+			proto:    ipProtoTCP,
+			fragment: true,
+			want:     false,
+		},
+		{
+			name:    "unknown source peer",
+			acl:     allowJellyfin,
+			src:     "10.0.0.9",
+			dst:     "192.168.1.50",
+			proto:   ipProtoTCP,
+			dstPort: 8096,
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := packetHeader{
+				src:      netip.MustParseAddr(tt.src),
+				dst:      netip.MustParseAddr(tt.dst),
+				proto:    tt.proto,
+				dstPort:  tt.dstPort,
+				fragment: tt.fragment,
+				opaque:   tt.opaque,
+			}
+			if tt.dstPort != 0 {
+				header.srcPort = 40000
+				header.transport = binary.BigEndian.AppendUint16(binary.BigEndian.AppendUint16(nil, header.srcPort), header.dstPort)
+			}
+
+			acl := compileACL(config.TunnelConfig{Name: "wg0", ACL: tt.acl, Peers: peers})
+			if got := acl.evaluate(header); got != tt.want {
+				t.Errorf("evaluate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestACLEvaluateCountsHits(t *testing.T) {
+	acl := compileACL(config.TunnelConfig{
+		Name: "wg0",
+		ACL: &config.ACLConfig{Rules: []config.ACLRule{
+			{Action: config.ACLActionDeny, Protocol: "tcp", Port: "22"},
+		}},
+	})
+
+	denied := packetHeader{src: netip.MustParseAddr("10.0.0.2"), dst: netip.MustParseAddr("192.168.1.10"), proto: ipProtoTCP, srcPort: 40000, dstPort: 22, transport: []byte{0x9c, 0x40, 0, 22}}
+	allowed := packetHeader{src: netip.MustParseAddr("10.0.0.2"), dst: netip.MustParseAddr("192.168.1.10"), proto: ipProtoTCP, srcPort: 40000, dstPort: 80, transport: []byte{0x9c, 0x40, 0, 80}}
+	acl.evaluate(denied)
+	acl.evaluate(denied)
+	acl.evaluate(allowed)
+
+	if hits := acl.rules[0].hits.Load(); hits != 2 {
+		t.Errorf("rule hits = %d, want 2", hits)
+	}
+	if hits := acl.defaultHits.Load(); hits != 1 {
+		t.Errorf("default hits = %d, want 1", hits)
+	}
+}
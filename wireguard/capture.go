@@ -0,0 +1,213 @@
+package wireguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.zx2c4.com/wireguard/tun"
+)
+
+const (
+	DefaultCaptureDuration = 30 * time.Second
+	MaxCaptureDuration     = 10 * time.Minute
+	DefaultCapturePackets  = 10000
+	MaxCapturePackets      = 1000000
+	captureQueueSize       = 4096
+	captureSnapLen         = 65535
+)
+
+var (
+	ErrCaptureUnsupported = errors.New("packet capture requires userspace or netstack mode")
+	ErrCaptureInProgress  = errors.New("a capture is already running on this tunnel")
+)
+
+// Capture device functions
+
+// MARK: newCaptureDevice
+// Wraps the device handed to WireGuard so decrypted packets can be copied to an active capture
+func newCaptureDevice(dev tun.Device) *captureDevice {
+	return &captureDevice{Device: dev}
+}
+
+// MARK: Read
+// Reads packets headed into the tunnel and records them as outbound
+func (d *captureDevice) Read(bufs [][]byte, sizes []int, offset int) (int, error) {
+	n, err := d.Device.Read(bufs, sizes, offset)
+	if capture := d.active.Load(); capture != nil {
+		for i := 0; i < n; i++ {
+			capture.record(bufs[i][offset:offset+sizes[i]], CaptureOutbound)
+		}
+	}
+	return n, err
+}
+
+// MARK: Write
+// Records packets decrypted from peers as inbound before delivering them
+func (d *captureDevice) Write(bufs [][]byte, offset int) (int, error) {
+	if capture := d.active.Load(); capture != nil {
+		for _, buf := range bufs {
+			if len(buf) > offset {
+				capture.record(buf[offset:], CaptureInbound)
+			}
+		}
+	}
+	return d.Device.Write(bufs, offset)
+}
+
+// Tunnel capture functions
+
+// MARK: Capture
+// Streams matching packets as pcapng until the duration passes, the packet limit is hit or the context ends
+func (t *Tunnel) Capture(ctx context.Context, opts CaptureOptions, w io.Writer) error {
+	t.mu.RLock()
+	dev := t.capture
+	t.mu.RUnlock()
+
+	if dev == nil || !t.IsRunning() {
+		return fmt.Errorf("tunnel %s is not running", t.name)
+	}
+
+	opts = normalizeCaptureOptions(opts)
+	capture := &packetCapture{
+		options: opts,
+		packets: make(chan capturedPacket, captureQueueSize),
+		full:    make(chan struct{}),
+	}
+
+	if !dev.active.CompareAndSwap(nil, capture) {
+		return ErrCaptureInProgress
+	}
+	defer dev.active.Store(nil)
+
+	t.logger.Info("Packet capture started", "tunnel", t.name, "duration", opts.Duration, "max_packets", opts.MaxPackets, "host", opts.Host, "port", opts.Port)
+
+	start := time.Now()
+	written, err := t.writeCapture(ctx, capture, w)
+	dropped := capture.dropped.Load()
+
+	if err != nil {
+		t.logger.Warn("Packet capture ended early", "tunnel", t.name, "packets", written, "dropped", dropped, "error", err)
+		return err
+	}
+
+	t.logger.Info("Packet capture finished", "tunnel", t.name, "packets", written, "dropped", dropped, "elapsed", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// MARK: writeCapture
+// Drains captured packets into a pcapng stream, flushing whenever the queue runs dry
+func (t *Tunnel) writeCapture(ctx context.Context, capture *packetCapture, w io.Writer) (int, error) {
+	out, err := newPcapngWriter(w, t.interfaceName(), captureSnapLen)
+	if err != nil {
+		return 0, fmt.Errorf("writing capture header: %w", err)
+	}
+
+	start := time.Now()
+	timer := time.NewTimer(capture.options.Duration)
+	defer timer.Stop()
+
+	written := 0
+	for done := false; !done; {
+		select {
+		case pkt := <-capture.packets:
+			if err := out.WritePacket(pkt); err != nil {
+				return written, err
+			}
+			written++
+			if len(capture.packets) == 0 {
+				if err := out.Flush(); err != nil {
+					return written, err
+				}
+			}
+		case <-capture.full:
+			done = true
+		case <-timer.C:
+			done = true
+		case <-ctx.Done():
+			return written, ctx.Err()
+		}
+	}
+
+	// Packets matched before the capture ended may still be queued
+	for len(capture.packets) > 0 {
+		if err := out.WritePacket(<-capture.packets); err != nil {
+			return written, err
+		}
+		written++
+	}
+
+	if err := out.WriteStats(start, time.Now(), capture.dropped.Load()); err != nil {
+		return written, err
+	}
+
+	return written, out.Flush()
+}
+
+// MARK: normalizeCaptureOptions
+// Applies the default and maximum duration and packet count
+func normalizeCaptureOptions(opts CaptureOptions) CaptureOptions {
+	if opts.Duration <= 0 {
+		opts.Duration = DefaultCaptureDuration
+	}
+	opts.Duration = min(opts.Duration, MaxCaptureDuration)
+
+	if opts.MaxPackets <= 0 {
+		opts.MaxPackets = DefaultCapturePackets
+	}
+	opts.MaxPackets = min(opts.MaxPackets, MaxCapturePackets)
+
+	return opts
+}
+
+// Packet recording functions
+
+// MARK: record
+// Copies a matching packet onto the capture queue without ever blocking the data path
+func (c *packetCapture) record(packet []byte, direction CaptureDirection) {
+	if !c.options.matches(packet) {
+		return
+	}
+
+	count := c.matched.Add(1)
+	if count > int64(c.options.MaxPackets) {
+		return
+	}
+	if count == int64(c.options.MaxPackets) {
+		defer c.once.Do(func() { close(c.full) })
+	}
+
+	length := len(packet)
+	data := make([]byte, min(length, captureSnapLen))
+	copy(data, packet)
+
+	select {
+	case c.packets <- capturedPacket{data: data, length: length, direction: direction, timestamp: time.Now()}:
+	default:
+		c.dropped.Add(1)
+	}
+}
+
+// MARK: matches
+// Checks a packet against the host and port filter
+func (o CaptureOptions) matches(packet []byte) bool {
+	if !o.Host.IsValid() && o.Port == 0 {
+		return true
+	}
+
+	header, ok := parsePacketHeader(packet)
+	if !ok {
+		return false
+	}
+
+	if o.Host.IsValid() && header.src != o.Host && header.dst != o.Host {
+		return false
+	}
+	if o.Port != 0 && header.srcPort != o.Port && header.dstPort != o.Port {
+		return false
+	}
+
+	return true
+}
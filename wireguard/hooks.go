@@ -0,0 +1,57 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/JPKribs/FinGuard/internal"
+)
+
+const (
+	hookTimeout = 30 * time.Second
+	hookShell   = "sh"
+)
+
+// Interface hook functions
+
+// MARK: runHooks
+// Runs PreUp/PostUp/PreDown/PostDown commands in order, substituting %i with the interface name
+func runHooks(ctx context.Context, logger *internal.Logger, tunnelName, iface, phase string, commands []string) error {
+	for _, command := range commands {
+		if err := runHook(ctx, logger, tunnelName, iface, phase, command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MARK: runHook
+// Runs a single hook command through the shell with a timeout, logging its combined output
+func runHook(ctx context.Context, logger *internal.Logger, tunnelName, iface, phase, command string) error {
+	command = strings.ReplaceAll(command, "%i", iface)
+
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+
+	logger.Info("Running tunnel hook", "tunnel", tunnelName, "phase", phase, "command", command)
+
+	cmd := exec.CommandContext(ctx, hookShell, "-c", command)
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+
+	if trimmed := strings.TrimSpace(string(output)); trimmed != "" {
+		logger.Info("Tunnel hook output", "tunnel", tunnelName, "phase", phase, "output", trimmed)
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s hook timed out after %s: %s", phase, hookTimeout, command)
+	}
+	if err != nil {
+		return fmt.Errorf("%s hook failed: %s: %w", phase, command, err)
+	}
+
+	return nil
+}
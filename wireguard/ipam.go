@@ -0,0 +1,104 @@
+package wireguard
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/JPKribs/FinGuard/config"
+)
+
+const (
+	maxPoolScan = 1 << 16
+)
+
+// Address management functions
+
+// MARK: AllocatePeerAddress
+// Returns the first free host address in the tunnel's server address pool as a single host CIDR
+func AllocatePeerAddress(cfg config.TunnelConfig) (string, error) {
+	if cfg.Server == nil {
+		return "", fmt.Errorf("tunnel %s is not in server mode", cfg.Name)
+	}
+
+	pool, err := netip.ParsePrefix(cfg.Server.AddressPool)
+	if err != nil {
+		return "", fmt.Errorf("invalid address pool %s: %w", cfg.Server.AddressPool, err)
+	}
+	pool = pool.Masked()
+
+	used := usedPoolPrefixes(cfg, pool)
+	broadcast := lastPoolAddress(pool)
+
+	addr := pool.Addr().Next()
+	for i := 0; i < maxPoolScan && addr.IsValid() && pool.Contains(addr); i++ {
+		if addr.Is4() && addr == broadcast {
+			break
+		}
+
+		if !prefixesContain(used, addr) {
+			return netip.PrefixFrom(addr, addr.BitLen()).String(), nil
+		}
+
+		addr = addr.Next()
+	}
+
+	return "", fmt.Errorf("address pool %s for tunnel %s is exhausted", cfg.Server.AddressPool, cfg.Name)
+}
+
+// MARK: usedPoolPrefixes
+// Collects the tunnel and peer addresses that already occupy part of the pool
+func usedPoolPrefixes(cfg config.TunnelConfig, pool netip.Prefix) []netip.Prefix {
+	var used []netip.Prefix
+
+	for _, addr := range cfg.Addresses {
+		if prefix, err := netip.ParsePrefix(addr); err == nil {
+			used = append(used, netip.PrefixFrom(prefix.Addr(), prefix.Addr().BitLen()))
+		}
+	}
+
+	for _, peer := range cfg.Peers {
+		for _, allowedIP := range peer.AllowedIPs {
+			prefix, err := netip.ParsePrefix(allowedIP)
+			if err != nil {
+				continue
+			}
+			// Routes covering the whole pool belong to site peers, not to individual clients
+			if prefix.Bits() > pool.Bits() && pool.Overlaps(prefix) {
+				used = append(used, prefix.Masked())
+			}
+		}
+	}
+
+	return used
+}
+
+// MARK: prefixesContain
+// Checks if any prefix contains the address
+func prefixesContain(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// MARK: lastPoolAddress
+// Returns the last address of a prefix, which is the broadcast address for IPv4 pools
+func lastPoolAddress(pool netip.Prefix) netip.Addr {
+	bytes := pool.Addr().AsSlice()
+	hostBits := pool.Addr().BitLen() - pool.Bits()
+
+	for i := len(bytes) - 1; i >= 0 && hostBits > 0; i-- {
+		if hostBits >= 8 {
+			bytes[i] = 0xff
+			hostBits -= 8
+		} else {
+			bytes[i] |= byte(1<<hostBits) - 1
+			hostBits = 0
+		}
+	}
+
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
@@ -0,0 +1,130 @@
+package wireguard
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/JPKribs/FinGuard/config"
+)
+
+func TestAllocatePeerAddress(t *testing.T) {
+	tests := []struct {
+		name      string
+		pool      string
+		addresses []string
+		peers     [][]string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name: "first host of an empty pool",
+			pool: "10.8.0.0/24",
+			want: "10.8.0.1/32",
+		},
+		{
+			name:      "skips the server address",
+			pool:      "10.8.0.0/24",
+			addresses: []string{"10.8.0.1/24"},
+			want:      "10.8.0.2/32",
+		},
+		{
+			name:      "skips peer addresses and fills gaps",
+			pool:      "10.8.0.0/24",
+			addresses: []string{"10.8.0.1/24"},
+			peers:     [][]string{{"10.8.0.2/32"}, {"10.8.0.4/32"}},
+			want:      "10.8.0.3/32",
+		},
+		{
+			name:      "site peer covering the pool does not use it up",
+			pool:      "10.8.0.0/24",
+			addresses: []string{"10.8.0.1/24"},
+			peers:     [][]string{{"10.8.0.0/16"}},
+			want:      "10.8.0.2/32",
+		},
+		{
+			name:  "peer subnet inside the pool is used",
+			pool:  "10.8.0.0/24",
+			peers: [][]string{{"10.8.0.0/30"}},
+			want:  "10.8.0.4/32",
+		},
+		{
+			name:  "unmasked pool",
+			pool:  "10.8.0.77/29",
+			peers: [][]string{{"10.8.0.73/32"}},
+			want:  "10.8.0.74/32",
+		},
+		{
+			name:      "broadcast address is never handed out",
+			pool:      "10.8.0.0/30",
+			addresses: []string{"10.8.0.1/30"},
+			peers:     [][]string{{"10.8.0.2/32"}},
+			wantErr:   true,
+		},
+		{
+			name:      "ipv6 pool",
+			pool:      "fd00:8::/64",
+			addresses: []string{"fd00:8::1/64"},
+			want:      "fd00:8::2/128",
+		},
+		{
+			name:    "invalid pool",
+			pool:    "not-a-pool",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.TunnelConfig{
+				Name:      "wg0",
+				Addresses: tt.addresses,
+				Server:    &config.TunnelServerConfig{AddressPool: tt.pool},
+			}
+			for _, allowedIPs := range tt.peers {
+				cfg.Peers = append(cfg.Peers, config.PeerConfig{AllowedIPs: allowedIPs})
+			}
+
+			got, err := AllocatePeerAddress(cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllocatePeerAddressRequiresServerMode(t *testing.T) {
+	if _, err := AllocatePeerAddress(config.TunnelConfig{Name: "wg0"}); err == nil {
+		t.Fatal("expected an error for a tunnel without server mode")
+	}
+}
+
+func TestLastPoolAddress(t *testing.T) {
+	tests := []struct {
+		pool string
+		want string
+	}{
+		{"10.8.0.0/24", "10.8.0.255"},
+		{"10.8.0.0/30", "10.8.0.3"},
+		{"10.8.0.0/21", "10.8.7.255"},
+		{"192.168.1.5/32", "192.168.1.5"},
+		{"fd00::/120", "fd00::ff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pool, func(t *testing.T) {
+			pool := netip.MustParsePrefix(tt.pool).Masked()
+			if got := lastPoolAddress(pool); got != netip.MustParseAddr(tt.want) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,759 @@
+package wireguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/JPKribs/FinGuard/config"
+	"github.com/JPKribs/FinGuard/internal"
+	"github.com/vishvananda/netlink"
+	"golang.zx2c4.com/wireguard/wgctrl"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+const (
+	kernelResolveTimeout = 5 * time.Second
+)
+
+// Kernel tunnel creation and lifecycle functions
+
+// MARK: NewKernelTunnel
+// Creates a tunnel backed by the in-kernel WireGuard module, configured over netlink
+func NewKernelTunnel(cfg config.TunnelConfig, logger *internal.Logger, resolver *AsyncResolver) (*KernelTunnel, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("tunnel name cannot be empty")
+	}
+
+	if logger == nil {
+		logger = &internal.Logger{}
+	}
+
+	if resolver == nil {
+		resolver = NewAsyncResolver()
+	}
+
+	return &KernelTunnel{
+		name:            cfg.Name,
+		config:          cfg,
+		logger:          logger,
+		resolver:        resolver,
+		peerSupervisors: make(map[string]*supervisor),
+	}, nil
+}
+
+// MARK: isKernelWireGuardAvailable
+// Checks that the WireGuard module is loaded and the genetlink family is reachable
+func isKernelWireGuardAvailable(paths config.WireGuardPaths) bool {
+	if _, err := os.Stat("/sys/module/wireguard"); err != nil {
+		return false
+	}
+
+	client, err := wgctrl.New()
+	if err != nil {
+		return false
+	}
+	defer client.Close()
+
+	_, err = client.Devices()
+	return err == nil
+}
+
+// MARK: Start
+// Creates the WireGuard link, assigns addresses, configures peers and installs routes
+func (kt *KernelTunnel) Start(ctx context.Context) error {
+	kt.mu.Lock()
+	defer kt.mu.Unlock()
+
+	if atomic.LoadInt64(&kt.running) == 1 {
+		return fmt.Errorf("tunnel %s already running", kt.name)
+	}
+
+	kt.logger.Info("Starting kernel tunnel", "name", kt.name)
+
+	if err := runHooks(ctx, kt.logger, kt.name, kt.name, "PreUp", kt.config.PreUp); err != nil {
+		return err
+	}
+
+	client, err := wgctrl.New()
+	if err != nil {
+		return fmt.Errorf("opening WireGuard netlink client: %w", err)
+	}
+	kt.client = client
+
+	link, err := kt.createLink()
+	if err != nil {
+		kt.cleanupOnFailure()
+		return fmt.Errorf("creating link: %w", err)
+	}
+
+	for _, addr := range kt.config.Addresses {
+		if err := kt.addAddress(link, addr); err != nil {
+			kt.cleanupOnFailure()
+			return err
+		}
+		kt.logger.Info("Added address to tunnel", "name", kt.name, "address", addr)
+	}
+
+	if err := kt.configureDevice(); err != nil {
+		kt.cleanupOnFailure()
+		return fmt.Errorf("configuring WireGuard device: %w", err)
+	}
+
+	if err := netlink.LinkSetUp(link); err != nil {
+		kt.cleanupOnFailure()
+		return fmt.Errorf("bringing interface %s up: %w", kt.name, err)
+	}
+
+	if _, manageRoutes, _ := kt.config.RouteTable(); manageRoutes {
+		for _, route := range kt.config.Routes {
+			if err := kt.addRoute(link, route); err != nil {
+				kt.logger.Error("Failed to add route", "name", kt.name, "route", route, "error", err)
+			} else {
+				kt.logger.Info("Added route to tunnel", "name", kt.name, "route", route)
+			}
+		}
+	}
+
+	if err := runHooks(ctx, kt.logger, kt.name, kt.name, "PostUp", kt.config.PostUp); err != nil {
+		kt.cleanupOnFailure()
+		return err
+	}
+
+	kt.peerClock.reset()
+	atomic.StoreInt64(&kt.running, 1)
+	kt.logger.Info("Kernel tunnel started", "name", kt.name)
+
+	// Monitoring outlives the caller's context, which ends once the start request returns
+	monitorCtx, cancel := context.WithCancel(context.Background())
+	kt.cancelMonitor = cancel
+	kt.startMonitoring(monitorCtx)
+	return nil
+}
+
+// MARK: Stop
+// Deletes the WireGuard link, which also drops its addresses and routes
+func (kt *KernelTunnel) Stop(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt64(&kt.running, 1, 0) {
+		return nil
+	}
+
+	kt.logger.Info("Stopping kernel tunnel", "name", kt.name)
+
+	kt.stopMonitoringRoutine()
+
+	kt.mu.Lock()
+	defer kt.mu.Unlock()
+
+	if err := runHooks(ctx, kt.logger, kt.name, kt.name, "PreDown", kt.config.PreDown); err != nil {
+		kt.logger.Error("PreDown hook failed", "name", kt.name, "error", err)
+	}
+
+	if err := kt.deleteLink(); err != nil {
+		kt.logger.Error("Failed to delete link", "name", kt.name, "error", err)
+	}
+
+	if err := runHooks(ctx, kt.logger, kt.name, kt.name, "PostDown", kt.config.PostDown); err != nil {
+		kt.logger.Error("PostDown hook failed", "name", kt.name, "error", err)
+	}
+
+	if kt.client != nil {
+		kt.client.Close()
+		kt.client = nil
+	}
+
+	kt.lastError = nil
+	kt.logger.Info("Kernel tunnel stopped", "name", kt.name)
+	return nil
+}
+
+// MARK: Update
+// Applies configuration changes incrementally without bringing the interface down
+func (kt *KernelTunnel) Update(ctx context.Context, cfg config.TunnelConfig) error {
+	if cfg.Name != kt.name {
+		return fmt.Errorf("cannot change tunnel name from %s to %s", kt.name, cfg.Name)
+	}
+
+	kt.mu.Lock()
+	defer kt.mu.Unlock()
+
+	oldConfig := kt.config
+	kt.config = cfg
+
+	if atomic.LoadInt64(&kt.running) == 1 {
+		if err := kt.applyUpdate(oldConfig); err != nil {
+			kt.config = oldConfig
+			kt.lastError = err
+			return fmt.Errorf("applying updated config: %w", err)
+		}
+
+		kt.logger.Info("Applied configuration update", "name", kt.name)
+	}
+
+	return nil
+}
+
+// MARK: IsRunning
+// Reports whether the tunnel is up without querying device statistics
+func (kt *KernelTunnel) IsRunning() bool {
+	return atomic.LoadInt64(&kt.running) == 1
+}
+
+// MARK: Status
+// Returns current tunnel status information
+func (kt *KernelTunnel) Status(ctx context.Context) TunnelStatus {
+	state := "stopped"
+	if atomic.LoadInt64(&kt.running) == 1 {
+		state = "running"
+	}
+
+	status := TunnelStatus{
+		Name:       kt.name,
+		State:      state,
+		Interface:  kt.name,
+		MTU:        kt.config.MTU,
+		Peers:      len(kt.config.Peers),
+		ServerMode: kt.config.Server != nil,
+	}
+	status.PublicKey, _ = PublicKey(kt.config.PrivateKey)
+
+	kt.mu.RLock()
+	if state == "running" {
+		status.PeerStats = kt.peerStatuses()
+	}
+	if kt.lastError != nil {
+		status.Error = kt.lastError.Error()
+	}
+	kt.mu.RUnlock()
+
+	return status
+}
+
+// MARK: peerStatuses
+// Reads live per-peer statistics over netlink, called with the lock held
+func (kt *KernelTunnel) peerStatuses() []PeerStatus {
+	var live map[string]PeerStatus
+	if kt.client != nil {
+		dev, err := kt.client.Device(kt.name)
+		if err != nil {
+			kt.logger.Debug("Failed to read peer statistics", "tunnel", kt.name, "error", err)
+		} else {
+			live = make(map[string]PeerStatus, len(dev.Peers))
+			for _, peer := range dev.Peers {
+				status := PeerStatus{
+					PublicKey:           peer.PublicKey.String(),
+					LastHandshake:       handshakeTime(peer.LastHandshakeTime.Unix(), int64(peer.LastHandshakeTime.Nanosecond())),
+					RxBytes:             peer.ReceiveBytes,
+					TxBytes:             peer.TransmitBytes,
+					PersistentKeepalive: int(peer.PersistentKeepaliveInterval / time.Second),
+				}
+				if peer.Endpoint != nil {
+					status.Endpoint = peer.Endpoint.String()
+				}
+				live[status.PublicKey] = status
+			}
+		}
+	}
+
+	return mergePeerStatuses(kt.config.Peers, live, supervisorStatuses(kt.peerSupervisors, nil), &kt.peerClock, staleTimeoutFor(kt.config))
+}
+
+// Link setup functions
+
+// MARK: createLink
+// Creates a fresh wireguard link, replacing any stale link left behind with the same name
+func (kt *KernelTunnel) createLink() (netlink.Link, error) {
+	if existing, err := netlink.LinkByName(kt.name); err == nil {
+		if existing.Type() != "wireguard" {
+			return nil, fmt.Errorf("interface %s already exists with type %s", kt.name, existing.Type())
+		}
+		kt.logger.Info("Removing stale WireGuard link", "name", kt.name)
+		if err := netlink.LinkDel(existing); err != nil {
+			return nil, fmt.Errorf("removing stale link: %w", err)
+		}
+	}
+
+	mtu := kt.config.MTU
+	if mtu <= 0 {
+		mtu = 1420
+	}
+
+	link := &netlink.Wireguard{LinkAttrs: netlink.LinkAttrs{Name: kt.name, MTU: mtu}}
+	if err := netlink.LinkAdd(link); err != nil {
+		return nil, fmt.Errorf("adding wireguard link %s: %w", kt.name, err)
+	}
+
+	return netlink.LinkByName(kt.name)
+}
+
+// MARK: deleteLink
+// Removes the wireguard link if it exists
+func (kt *KernelTunnel) deleteLink() error {
+	link, err := netlink.LinkByName(kt.name)
+	if err != nil {
+		return nil
+	}
+
+	return netlink.LinkDel(link)
+}
+
+// MARK: cleanupOnFailure
+// Cleans up resources when startup fails
+func (kt *KernelTunnel) cleanupOnFailure() {
+	kt.deleteLink()
+
+	if kt.client != nil {
+		kt.client.Close()
+		kt.client = nil
+	}
+}
+
+// MARK: addAddress
+// Assigns an address to the link, ignoring addresses that are already present
+func (kt *KernelTunnel) addAddress(link netlink.Link, cidr string) error {
+	addr, err := netlink.ParseAddr(cidr)
+	if err != nil {
+		return fmt.Errorf("parsing address %s: %w", cidr, err)
+	}
+
+	if err := netlink.AddrAdd(link, addr); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("adding address %s to interface %s: %w", cidr, kt.name, err)
+	}
+
+	return nil
+}
+
+// MARK: removeAddress
+// Removes an address from the link
+func (kt *KernelTunnel) removeAddress(link netlink.Link, cidr string) error {
+	addr, err := netlink.ParseAddr(cidr)
+	if err != nil {
+		return fmt.Errorf("parsing address %s: %w", cidr, err)
+	}
+
+	if err := netlink.AddrDel(link, addr); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing address %s from interface %s: %w", cidr, kt.name, err)
+	}
+
+	return nil
+}
+
+// MARK: addRoute
+// Routes a destination through the link, ignoring routes that already exist
+func (kt *KernelTunnel) addRoute(link netlink.Link, destination string) error {
+	_, destNet, err := net.ParseCIDR(destination)
+	if err != nil {
+		return fmt.Errorf("parsing destination %s: %w", destination, err)
+	}
+
+	table, _, _ := kt.config.RouteTable()
+	route := &netlink.Route{LinkIndex: link.Attrs().Index, Dst: destNet, Table: table}
+	if err := netlink.RouteAdd(route); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("adding route %s via interface %s: %w", destination, kt.name, err)
+	}
+
+	return nil
+}
+
+// MARK: removeRoute
+// Removes a route through the link
+func (kt *KernelTunnel) removeRoute(link netlink.Link, destination string) error {
+	_, destNet, err := net.ParseCIDR(destination)
+	if err != nil {
+		return fmt.Errorf("parsing destination %s: %w", destination, err)
+	}
+
+	table, _, _ := kt.config.RouteTable()
+	route := &netlink.Route{LinkIndex: link.Attrs().Index, Dst: destNet, Table: table}
+	if err := netlink.RouteDel(route); err != nil && !strings.Contains(err.Error(), "no such process") {
+		return fmt.Errorf("removing route %s: %w", destination, err)
+	}
+
+	return nil
+}
+
+// WireGuard configuration functions
+
+// MARK: configureDevice
+// Replaces the full device configuration with the current tunnel config
+func (kt *KernelTunnel) configureDevice() error {
+	privateKey, err := wgtypes.ParseKey(strings.TrimSpace(kt.config.PrivateKey))
+	if err != nil {
+		return fmt.Errorf("invalid private key format: %w", err)
+	}
+
+	peers := make([]wgtypes.PeerConfig, 0, len(kt.config.Peers))
+	for _, peer := range kt.config.Peers {
+		peerConfig, err := kt.buildPeerConfig(peer, nil)
+		if err != nil {
+			return fmt.Errorf("building config for peer %s: %w", peer.Name, err)
+		}
+		peers = append(peers, peerConfig)
+	}
+
+	cfg := wgtypes.Config{
+		PrivateKey:   &privateKey,
+		ReplacePeers: true,
+		Peers:        peers,
+	}
+
+	if kt.config.ListenPort > 0 {
+		cfg.ListenPort = &kt.config.ListenPort
+	}
+
+	if kt.config.FwMark != 0 {
+		fwMark := int(kt.config.FwMark)
+		cfg.FirewallMark = &fwMark
+	}
+
+	return kt.client.ConfigureDevice(kt.name, cfg)
+}
+
+// MARK: applyUpdate
+// Diffs the old and new configs and applies only what changed
+func (kt *KernelTunnel) applyUpdate(oldConfig config.TunnelConfig) error {
+	link, err := netlink.LinkByName(kt.name)
+	if err != nil {
+		return fmt.Errorf("finding interface %s: %w", kt.name, err)
+	}
+
+	if kt.config.MTU > 0 && kt.config.MTU != oldConfig.MTU {
+		if err := netlink.LinkSetMTU(link, kt.config.MTU); err != nil {
+			return fmt.Errorf("setting MTU to %d: %w", kt.config.MTU, err)
+		}
+	}
+
+	added, removed := diffStrings(oldConfig.Addresses, kt.config.Addresses)
+	for _, addr := range removed {
+		if err := kt.removeAddress(link, addr); err != nil {
+			return err
+		}
+	}
+	for _, addr := range added {
+		if err := kt.addAddress(link, addr); err != nil {
+			return err
+		}
+	}
+
+	deviceConfig, err := kt.buildDeviceDiff(oldConfig)
+	if err != nil {
+		return err
+	}
+
+	if err := kt.client.ConfigureDevice(kt.name, deviceConfig); err != nil {
+		return fmt.Errorf("configuring WireGuard device: %w", err)
+	}
+
+	if _, manageRoutes, _ := kt.config.RouteTable(); !manageRoutes {
+		return nil
+	}
+
+	added, removed = diffStrings(oldConfig.Routes, kt.config.Routes)
+	for _, route := range removed {
+		if err := kt.removeRoute(link, route); err != nil {
+			kt.logger.Error("Failed to remove route", "name", kt.name, "route", route, "error", err)
+		}
+	}
+	for _, route := range added {
+		if err := kt.addRoute(link, route); err != nil {
+			kt.logger.Error("Failed to add route", "name", kt.name, "route", route, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// MARK: buildDeviceDiff
+// Builds a device config containing only changed interface fields and peers
+func (kt *KernelTunnel) buildDeviceDiff(oldConfig config.TunnelConfig) (wgtypes.Config, error) {
+	var cfg wgtypes.Config
+
+	if strings.TrimSpace(kt.config.PrivateKey) != strings.TrimSpace(oldConfig.PrivateKey) {
+		privateKey, err := wgtypes.ParseKey(strings.TrimSpace(kt.config.PrivateKey))
+		if err != nil {
+			return cfg, fmt.Errorf("invalid private key format: %w", err)
+		}
+		cfg.PrivateKey = &privateKey
+	}
+
+	if kt.config.ListenPort != oldConfig.ListenPort {
+		cfg.ListenPort = &kt.config.ListenPort
+	}
+
+	if kt.config.FwMark != oldConfig.FwMark {
+		fwMark := int(kt.config.FwMark)
+		cfg.FirewallMark = &fwMark
+	}
+
+	oldPeers := make(map[string]config.PeerConfig, len(oldConfig.Peers))
+	for _, peer := range oldConfig.Peers {
+		oldPeers[strings.TrimSpace(peer.PublicKey)] = peer
+	}
+
+	for _, peer := range kt.config.Peers {
+		key := strings.TrimSpace(peer.PublicKey)
+		oldPeer, existed := oldPeers[key]
+		delete(oldPeers, key)
+
+		if existed && peerConfigEqual(oldPeer, peer) {
+			continue
+		}
+
+		var previous *config.PeerConfig
+		if existed {
+			previous = &oldPeer
+		}
+
+		peerConfig, err := kt.buildPeerConfig(peer, previous)
+		if err != nil {
+			return cfg, fmt.Errorf("building config for peer %s: %w", peer.Name, err)
+		}
+		cfg.Peers = append(cfg.Peers, peerConfig)
+	}
+
+	for key, peer := range oldPeers {
+		publicKey, err := wgtypes.ParseKey(key)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid public key for removed peer %s: %w", peer.Name, err)
+		}
+		cfg.Peers = append(cfg.Peers, wgtypes.PeerConfig{PublicKey: publicKey, Remove: true})
+	}
+
+	return cfg, nil
+}
+
+// MARK: buildPeerConfig
+// Converts a peer to its netlink form, leaving the endpoint alone when it has not changed
+func (kt *KernelTunnel) buildPeerConfig(peer config.PeerConfig, previous *config.PeerConfig) (wgtypes.PeerConfig, error) {
+	publicKey, err := wgtypes.ParseKey(strings.TrimSpace(peer.PublicKey))
+	if err != nil {
+		return wgtypes.PeerConfig{}, fmt.Errorf("invalid public key format: %w", err)
+	}
+
+	if len(peer.AllowedIPs) == 0 {
+		return wgtypes.PeerConfig{}, fmt.Errorf("peer %s must have at least one allowed IP", peer.Name)
+	}
+
+	allowedIPs := make([]net.IPNet, 0, len(peer.AllowedIPs))
+	for _, allowedIP := range peer.AllowedIPs {
+		_, ipNet, err := net.ParseCIDR(allowedIP)
+		if err != nil {
+			return wgtypes.PeerConfig{}, fmt.Errorf("invalid allowed IP %s: %w", allowedIP, err)
+		}
+		allowedIPs = append(allowedIPs, *ipNet)
+	}
+
+	peerConfig := wgtypes.PeerConfig{
+		PublicKey:         publicKey,
+		ReplaceAllowedIPs: true,
+		AllowedIPs:        allowedIPs,
+	}
+
+	presharedKey := wgtypes.Key{}
+	if peer.Preshared != "" {
+		presharedKey, err = wgtypes.ParseKey(strings.TrimSpace(peer.Preshared))
+		if err != nil {
+			return wgtypes.PeerConfig{}, fmt.Errorf("invalid preshared key format: %w", err)
+		}
+	}
+	peerConfig.PresharedKey = &presharedKey
+
+	keepalive := time.Duration(0)
+	if peer.Persistent || peer.PersistentKeepaliveInt > 0 {
+		interval := peer.PersistentKeepaliveInt
+		if interval <= 0 {
+			interval = defaultKeepalive
+		}
+		keepalive = time.Duration(interval) * time.Second
+	}
+	peerConfig.PersistentKeepaliveInterval = &keepalive
+
+	if peer.Endpoint != "" && (previous == nil || previous.Endpoint != peer.Endpoint) {
+		endpoint, err := kt.resolveEndpoint(peer.Endpoint)
+		if err != nil {
+			kt.logger.Warn("Failed to resolve peer endpoint", "tunnel", kt.name, "peer", peer.Name, "error", err)
+		} else {
+			peerConfig.Endpoint = endpoint
+		}
+	}
+
+	return peerConfig, nil
+}
+
+// MARK: resolveEndpoint
+// Resolves a peer endpoint to a UDP address through the shared resolver
+func (kt *KernelTunnel) resolveEndpoint(endpoint string) (*net.UDPAddr, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), kernelResolveTimeout)
+	defer cancel()
+
+	resolved, err := resolveDialAddress(ctx, kt.resolver, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return net.ResolveUDPAddr("udp", resolved)
+}
+
+// MARK: peerConfigEqual
+// Checks if two peer configurations would produce the same device state
+func peerConfigEqual(a, b config.PeerConfig) bool {
+	if a.Endpoint != b.Endpoint || a.Preshared != b.Preshared ||
+		a.Persistent != b.Persistent || a.PersistentKeepaliveInt != b.PersistentKeepaliveInt {
+		return false
+	}
+
+	added, removed := diffStrings(a.AllowedIPs, b.AllowedIPs)
+	return len(added) == 0 && len(removed) == 0
+}
+
+// MARK: diffStrings
+// Returns the entries added to and removed from a string list
+func diffStrings(oldList, newList []string) (added, removed []string) {
+	oldSet := make(map[string]bool, len(oldList))
+	for _, item := range oldList {
+		oldSet[item] = true
+	}
+
+	newSet := make(map[string]bool, len(newList))
+	for _, item := range newList {
+		newSet[item] = true
+		if !oldSet[item] {
+			added = append(added, item)
+		}
+	}
+
+	for _, item := range oldList {
+		if !newSet[item] {
+			removed = append(removed, item)
+		}
+	}
+
+	return added, removed
+}
+
+// Connection monitoring functions
+
+// MARK: startMonitoring
+// Starts the peer monitoring routine
+func (kt *KernelTunnel) startMonitoring(ctx context.Context) {
+	monitorInterval := time.Duration(kt.config.MonitorInterval) * time.Second
+	if monitorInterval <= 0 {
+		monitorInterval = defaultMonitorInterval
+	}
+
+	staleTimeout := time.Duration(kt.config.StaleConnectionTimeout) * time.Second
+	if staleTimeout <= 0 {
+		staleTimeout = defaultStaleTimeout
+	}
+
+	go func() {
+		ticker := time.NewTicker(monitorInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				kt.monitorPeers(staleTimeout)
+			}
+		}
+	}()
+}
+
+// MARK: stopMonitoringRoutine
+// Stops the peer monitoring routine
+func (kt *KernelTunnel) stopMonitoringRoutine() {
+	kt.mu.Lock()
+	defer kt.mu.Unlock()
+
+	if kt.cancelMonitor != nil {
+		kt.cancelMonitor()
+		kt.cancelMonitor = nil
+	}
+}
+
+// MARK: monitorPeers
+// Re-resolves endpoints of peers whose handshakes have gone stale
+func (kt *KernelTunnel) monitorPeers(staleTimeout time.Duration) {
+	if atomic.LoadInt64(&kt.running) != 1 {
+		return
+	}
+
+	kt.mu.Lock()
+	defer kt.mu.Unlock()
+
+	if kt.client == nil {
+		return
+	}
+
+	dev, err := kt.client.Device(kt.name)
+	if err != nil {
+		kt.logger.Debug("Failed to read device state", "tunnel", kt.name, "error", err)
+		return
+	}
+
+	peersByKey := make(map[string]config.PeerConfig, len(kt.config.Peers))
+	for _, peer := range kt.config.Peers {
+		peersByKey[strings.TrimSpace(peer.PublicKey)] = peer
+	}
+
+	now := time.Now()
+	budget := peerRetryBudget(kt.config)
+
+	for _, devicePeer := range dev.Peers {
+		key := devicePeer.PublicKey.String()
+		sup := peerSupervisor(kt.peerSupervisors, key, budget)
+		if !devicePeer.LastHandshakeTime.IsZero() && now.Sub(devicePeer.LastHandshakeTime) < staleTimeout {
+			sup.up(now)
+			continue
+		}
+
+		peer, ok := peersByKey[key]
+		if !ok || peer.Endpoint == "" || !sup.due(now) {
+			continue
+		}
+
+		delay := sup.failure(now, "handshake stale")
+		kt.logger.Info("Peer connection stale, refreshing endpoint",
+			"tunnel", kt.name, "peer", peer.Name, "attempt", sup.attemptCount(), "state", sup.current(), "next_retry_in", delay)
+		kt.refreshPeerEndpoint(peer, devicePeer.Endpoint)
+	}
+}
+
+// MARK: refreshPeerEndpoint
+// Updates a peer's endpoint in place if its hostname now resolves elsewhere
+func (kt *KernelTunnel) refreshPeerEndpoint(peer config.PeerConfig, current *net.UDPAddr) {
+	endpoint, err := kt.resolveEndpoint(peer.Endpoint)
+	if err != nil {
+		kt.logger.Error("Failed to re-resolve endpoint",
+			"tunnel", kt.name, "peer", peer.Name, "endpoint", peer.Endpoint, "error", err)
+		return
+	}
+
+	if current != nil && current.String() == endpoint.String() {
+		return
+	}
+
+	publicKey, err := wgtypes.ParseKey(strings.TrimSpace(peer.PublicKey))
+	if err != nil {
+		return
+	}
+
+	err = kt.client.ConfigureDevice(kt.name, wgtypes.Config{
+		Peers: []wgtypes.PeerConfig{{PublicKey: publicKey, UpdateOnly: true, Endpoint: endpoint}},
+	})
+	if err != nil {
+		kt.logger.Error("Failed to update peer endpoint",
+			"tunnel", kt.name, "peer", peer.Name, "endpoint", endpoint.String(), "error", err)
+		kt.lastError = err
+		return
+	}
+
+	kt.logger.Info("Updated peer endpoint", "tunnel", kt.name, "peer", peer.Name, "new_endpoint", endpoint.String())
+}
@@ -0,0 +1,43 @@
+package wireguard
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// Key generation functions
+
+// MARK: GeneratePrivateKey
+// Generates a new Curve25519 private key and returns it with its public key, base64 encoded
+func GeneratePrivateKey() (string, string, error) {
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		return "", "", fmt.Errorf("generating private key: %w", err)
+	}
+
+	return key.String(), key.PublicKey().String(), nil
+}
+
+// MARK: GeneratePresharedKey
+// Generates a new random preshared key, base64 encoded
+func GeneratePresharedKey() (string, error) {
+	key, err := wgtypes.GenerateKey()
+	if err != nil {
+		return "", fmt.Errorf("generating preshared key: %w", err)
+	}
+
+	return key.String(), nil
+}
+
+// MARK: PublicKey
+// Derives the base64 public key for a base64 private key
+func PublicKey(privateKey string) (string, error) {
+	key, err := wgtypes.ParseKey(strings.TrimSpace(privateKey))
+	if err != nil {
+		return "", fmt.Errorf("invalid private key format: %w", err)
+	}
+
+	return key.PublicKey().String(), nil
+}
@@ -0,0 +1,190 @@
+package wireguard
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/JPKribs/FinGuard/config"
+)
+
+const (
+	killSwitchTablePrefix    = "finguard_killswitch_"
+	killSwitchResolveTimeout = 5 * time.Second
+)
+
+// MARK: TunnelRunning
+// Reports whether the named tunnel exists and is currently up
+func (m *Manager) TunnelRunning(name string) bool {
+	m.mu.RLock()
+	tunnel, exists := m.tunnels[name]
+	m.mu.RUnlock()
+
+	return exists && tunnel.IsRunning()
+}
+
+// MARK: ApplyKillSwitch
+// Installs or removes the nftables rules that keep a tunnel's routes from leaking onto other interfaces, whether or not it is running
+func (m *Manager) ApplyKillSwitch(cfg config.TunnelConfig) error {
+	if !cfg.KillSwitch {
+		m.removeKillSwitch(cfg.Name)
+		return nil
+	}
+
+	if m.usesTunnelStack() {
+		// Tunnel-bound traffic never touches host routing in these modes, so it cannot leak
+		m.logger.Debug("Kill switch not needed for in-process tunnel stack", "tunnel", cfg.Name, "mode", m.mode)
+		return nil
+	}
+
+	ruleset := m.killSwitchRuleset(cfg)
+	if ruleset == "" {
+		m.removeKillSwitch(cfg.Name)
+		return nil
+	}
+
+	cmd := exec.Command(m.paths.Nft, "-f", "-")
+	cmd.Stdin = strings.NewReader(ruleset)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("installing kill switch for %s: %w (output: %s)", cfg.Name, err, strings.TrimSpace(string(output)))
+	}
+
+	m.logger.Info("Installed tunnel kill switch", "tunnel", cfg.Name, "table", killSwitchTable(cfg.Name))
+	return nil
+}
+
+// MARK: removeKillSwitch
+// Deletes the nftables table holding a tunnel's kill switch rules, if present
+func (m *Manager) removeKillSwitch(name string) {
+	if m.usesTunnelStack() {
+		return
+	}
+
+	table := killSwitchTable(name)
+	if err := exec.Command(m.paths.Nft, "list", "table", "inet", table).Run(); err != nil {
+		return
+	}
+
+	if output, err := exec.Command(m.paths.Nft, "delete", "table", "inet", table).CombinedOutput(); err != nil {
+		m.logger.Warn("Failed to remove tunnel kill switch", "tunnel", name, "error", err, "output", string(output))
+		return
+	}
+
+	m.logger.Info("Removed tunnel kill switch", "tunnel", name)
+}
+
+// MARK: killSwitchRuleset
+// Builds an nft script that rejects traffic to the tunnel's routes unless it leaves through the tunnel
+func (m *Manager) killSwitchRuleset(cfg config.TunnelConfig) string {
+	var v4, v6 []string
+	for _, route := range cfg.Routes {
+		prefix, ok := killSwitchPrefix(route)
+		if !ok {
+			m.logger.Warn("Skipping invalid route in kill switch", "tunnel", cfg.Name, "route", route)
+			continue
+		}
+		if strings.Contains(prefix, ":") {
+			v6 = append(v6, prefix)
+		} else {
+			v4 = append(v4, prefix)
+		}
+	}
+
+	if len(v4) == 0 && len(v6) == 0 {
+		return ""
+	}
+
+	table := killSwitchTable(cfg.Name)
+
+	var b strings.Builder
+	// Declaring and deleting first lets the whole script replace any previous rules atomically
+	fmt.Fprintf(&b, "table inet %s\n", table)
+	fmt.Fprintf(&b, "delete table inet %s\n", table)
+	fmt.Fprintf(&b, "table inet %s {\n", table)
+	b.WriteString("\tchain output {\n")
+	b.WriteString("\t\ttype filter hook output priority filter; policy accept;\n")
+	fmt.Fprintf(&b, "\t\toifname %q accept\n", cfg.Name)
+	b.WriteString("\t\toifname \"lo\" accept\n")
+	if cfg.FwMark != 0 {
+		fmt.Fprintf(&b, "\t\tmeta mark %d accept\n", cfg.FwMark)
+	}
+
+	// Encrypted WireGuard packets must still reach peers when a route covers their endpoints
+	for _, endpoint := range m.killSwitchEndpoints(cfg) {
+		host, port, err := net.SplitHostPort(endpoint)
+		if err != nil {
+			continue
+		}
+		family := "ip"
+		if strings.Contains(host, ":") {
+			family = "ip6"
+		}
+		fmt.Fprintf(&b, "\t\t%s daddr %s udp dport %s accept\n", family, host, port)
+	}
+
+	if len(v4) > 0 {
+		fmt.Fprintf(&b, "\t\tip daddr { %s } reject with icmpx type admin-prohibited\n", strings.Join(v4, ", "))
+	}
+	if len(v6) > 0 {
+		fmt.Fprintf(&b, "\t\tip6 daddr { %s } reject with icmpx type admin-prohibited\n", strings.Join(v6, ", "))
+	}
+	b.WriteString("\t}\n")
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// MARK: killSwitchEndpoints
+// Resolves peer endpoints to ip:port pairs so they can be exempted from the kill switch
+func (m *Manager) killSwitchEndpoints(cfg config.TunnelConfig) []string {
+	var endpoints []string
+	for _, peer := range cfg.Peers {
+		if peer.Endpoint == "" {
+			continue
+		}
+
+		result := <-m.resolver.ResolveAsync(peer.Endpoint, killSwitchResolveTimeout)
+		if result.err != nil {
+			m.logger.Warn("Failed to resolve peer endpoint for kill switch", "tunnel", cfg.Name, "endpoint", peer.Endpoint, "error", result.err)
+			continue
+		}
+		endpoints = append(endpoints, result.endpoint)
+	}
+
+	return endpoints
+}
+
+// MARK: killSwitchPrefix
+// Normalizes a route or bare address into CIDR notation for an nft set
+func killSwitchPrefix(route string) (string, bool) {
+	route = strings.TrimSpace(route)
+	if _, ipNet, err := net.ParseCIDR(route); err == nil {
+		return ipNet.String(), true
+	}
+
+	ip := net.ParseIP(route)
+	if ip == nil {
+		return "", false
+	}
+	if ip.To4() != nil {
+		return ip.String() + "/32", true
+	}
+	return ip.String() + "/128", true
+}
+
+// MARK: killSwitchTable
+// Returns the nftables table name used for a tunnel's kill switch
+func killSwitchTable(name string) string {
+	var b strings.Builder
+	b.WriteString(killSwitchTablePrefix)
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
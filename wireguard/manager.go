@@ -3,6 +3,8 @@ package wireguard
 import (
 	"context"
 	"fmt"
+	"io"
+	"net"
 	"sync/atomic"
 	"time"
 
@@ -11,13 +13,14 @@ import (
 )
 
 const (
-	maxRetryAttempts               = 3
-	managerRetryDelay              = 2 * time.Second
+	hostDialTimeout                = 5 * time.Second
+	hostDialKeepAlive              = 30 * time.Second
 	shutdownTimeout                = 30 * time.Second
 	healthCheckInterval            = 15 * time.Second
 	ModeWgQuick         TunnelMode = "wg-quick"
 	ModeKernel          TunnelMode = "kernel"
 	ModeUserspace       TunnelMode = "userspace"
+	ModeNetstack        TunnelMode = "netstack"
 	ModeAuto            TunnelMode = "auto"
 )
 
@@ -30,53 +33,83 @@ func NewManager(mode TunnelMode, paths config.WireGuardPaths, logger *internal.L
 		logger = &internal.Logger{}
 	}
 
-	actualMode := determineActualMode(mode, paths, logger)
+	actualMode, err := determineActualMode(mode, paths, logger)
+	if err != nil {
+		return nil, err
+	}
 
 	return &Manager{
-		logger:   logger,
-		tunnels:  make(map[string]TunnelInterface),
-		mode:     actualMode,
-		paths:    paths,
-		resolver: NewAsyncResolver(),
+		logger:      logger,
+		tunnels:     make(map[string]TunnelInterface),
+		mode:        actualMode,
+		paths:       paths,
+		resolver:    NewAsyncResolver(),
+		probers:     make(map[string]*tunnelProber),
+		onDemand:    make(map[string]*onDemandTunnel),
+		supervisors: make(map[string]*supervisor),
+		pending:     make(map[string]config.TunnelConfig),
 	}, nil
 }
 
+// MARK: SetPeerChangeHandler
+// Registers a callback receiving the peers of a tunnel after they were changed outside FinGuard
+func (m *Manager) SetPeerChangeHandler(handler PeerChangeHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.peerHandler = handler
+}
+
 // MARK: determineActualMode
-func determineActualMode(requestedMode TunnelMode, paths config.WireGuardPaths, logger *internal.Logger) TunnelMode {
+func determineActualMode(requestedMode TunnelMode, paths config.WireGuardPaths, logger *internal.Logger) (TunnelMode, error) {
 	switch requestedMode {
 	case ModeWgQuick:
 		if isWgQuickAvailable(paths) {
-			return ModeWgQuick
+			return ModeWgQuick, nil
 		}
 		logger.Warn("wg-quick not available, falling back to userspace mode")
-		return ModeUserspace
+		return userspaceOrNetstack(logger), nil
 	case ModeKernel:
 		if isKernelWireGuardAvailable(paths) {
-			return ModeKernel
+			return ModeKernel, nil
 		}
 		logger.Warn("kernel WireGuard not available, falling back to userspace mode")
-		return ModeUserspace
+		return userspaceOrNetstack(logger), nil
+	case ModeUserspace:
+		// An explicit userspace request must not quietly lose the host routes a TUN device provides
+		if !isTUNAvailable() {
+			return "", fmt.Errorf("userspace mode requested but TUN devices cannot be created: grant CAP_NET_ADMIN and access to /dev/net/tun, or set mode to netstack or auto")
+		}
+		return ModeUserspace, nil
+	case ModeNetstack:
+		return ModeNetstack, nil
 	case ModeAuto:
 		if isWgQuickAvailable(paths) {
 			logger.Info("Auto-selected wg-quick mode for best performance")
-			return ModeWgQuick
+			return ModeWgQuick, nil
 		}
 		if isKernelWireGuardAvailable(paths) {
 			logger.Info("Auto-selected kernel mode")
-			return ModeKernel
+			return ModeKernel, nil
 		}
-		logger.Info("Auto-selected userspace mode")
-		return ModeUserspace
+		if isTUNAvailable() {
+			logger.Info("Auto-selected userspace mode")
+			return ModeUserspace, nil
+		}
+		logger.Info("Auto-selected netstack mode, TUN device creation not permitted")
+		return ModeNetstack, nil
 	default:
-		return ModeUserspace
+		return userspaceOrNetstack(logger), nil
 	}
 }
 
-// MARK: isKernelWireGuardAvailable
-func isKernelWireGuardAvailable(paths config.WireGuardPaths) bool {
-	// For now, we'll implement this when we add kernel support
-	// This is just wg-quick for this step
-	return false
+// MARK: userspaceOrNetstack
+// Selects userspace mode when a TUN device can be created, otherwise the rootless netstack mode
+func userspaceOrNetstack(logger *internal.Logger) TunnelMode {
+	if isTUNAvailable() {
+		return ModeUserspace
+	}
+	logger.Warn("TUN device creation not permitted, falling back to netstack mode")
+	return ModeNetstack
 }
 
 // MARK: GetMode
@@ -97,13 +130,15 @@ func (m *Manager) Start(ctx context.Context) error {
 	m.logger.Info("Starting WireGuard tunnel manager", "mode", m.mode)
 	atomic.StoreInt64(&m.running, 1)
 	m.lastError = nil
-	atomic.StoreInt32(&m.retryAttempts, 0)
 
 	m.ctx, m.cancel = context.WithCancel(ctx)
 
 	m.wg.Add(1)
 	go m.healthMonitor()
 
+	m.wg.Add(1)
+	go m.idleMonitor()
+
 	return nil
 }
 
@@ -120,22 +155,25 @@ func (m *Manager) Stop(ctx context.Context) error {
 		m.cancel()
 	}
 
+	m.stopProbers()
+
 	ctx, cancel := context.WithTimeout(ctx, shutdownTimeout)
 	defer cancel()
 
 	m.mu.Lock()
-	tunnels := make([]TunnelInterface, 0, len(m.tunnels))
-	for _, tunnel := range m.tunnels {
-		tunnels = append(tunnels, tunnel)
+	tunnels := make(map[string]TunnelInterface, len(m.tunnels))
+	for name, tunnel := range m.tunnels {
+		tunnels[name] = tunnel
 	}
 	m.mu.Unlock()
 
 	var errors []error
-	for _, tunnel := range tunnels {
+	for name, tunnel := range tunnels {
 		if err := tunnel.Stop(ctx); err != nil {
 			m.logger.Error("Failed to stop tunnel", "error", err)
 			errors = append(errors, fmt.Errorf("tunnel: %w", err))
 		}
+		m.removeKillSwitch(name)
 	}
 
 	m.wg.Wait()
@@ -154,7 +192,7 @@ func (m *Manager) Stop(ctx context.Context) error {
 // Tunnel management functions
 
 // MARK: CreateTunnel
-// Creates a new tunnel with retry logic and starts it
+// Creates a new tunnel and starts it, leaving retries to its supervisor
 func (m *Manager) CreateTunnel(ctx context.Context, cfg config.TunnelConfig) error {
 	if atomic.LoadInt64(&m.running) == 0 {
 		return fmt.Errorf("tunnel manager not running")
@@ -188,49 +226,71 @@ func (m *Manager) CreateTunnel(ctx context.Context, cfg config.TunnelConfig) err
 		m.mu.Unlock()
 	}
 
-	var tunnel TunnelInterface
-	var err error
+	if cfg.ACL != nil && !m.usesTunnelStack() {
+		m.logger.Warn("Tunnel ACL is only enforced in userspace and netstack modes", "name", cfg.Name, "mode", m.mode)
+	}
 
-	for attempt := 1; attempt <= maxRetryAttempts; attempt++ {
-		// Choose tunnel type based on mode
-		switch m.mode {
-		case ModeWgQuick:
-			tunnel, err = NewWgQuickTunnel(cfg, m.paths, m.logger, m.resolver)
-		case ModeUserspace:
-			tunnel, err = NewTunnel(cfg, m.logger, m.resolver)
-		default:
-			err = fmt.Errorf("unsupported tunnel mode: %s", m.mode)
-		}
-		if err != nil {
-			m.logger.Error("Failed to create tunnel", "name", cfg.Name, "attempt", attempt, "error", err)
-			if attempt < maxRetryAttempts {
-				time.Sleep(managerRetryDelay * time.Duration(attempt))
-				continue
-			}
-			return fmt.Errorf("creating tunnel %s after %d attempts: %w", cfg.Name, maxRetryAttempts, err)
-		}
+	// Installed before the tunnel starts so its routes never leak while it comes up
+	if err := m.ApplyKillSwitch(cfg); err != nil {
+		m.logger.Error("Failed to apply kill switch", "name", cfg.Name, "error", err)
+	}
 
-		if err = tunnel.Start(ctx); err != nil {
-			m.logger.Error("Failed to start tunnel", "name", cfg.Name, "attempt", attempt, "error", err)
-			tunnel.Stop(ctx)
-			if attempt < maxRetryAttempts {
-				time.Sleep(managerRetryDelay * time.Duration(attempt))
-				continue
-			}
-			return fmt.Errorf("starting tunnel %s after %d attempts: %w", cfg.Name, maxRetryAttempts, err)
-		}
+	sup := m.supervisorFor(cfg)
+	sup.starting(time.Now())
 
-		break
+	// A single attempt here; the supervisor retries in the background with backoff
+	tunnel, err := m.newTunnel(cfg)
+	if err != nil {
+		m.logger.Error("Failed to create tunnel", "name", cfg.Name, "error", err)
+		err = fmt.Errorf("creating tunnel %s: %w", cfg.Name, err)
+		m.deferCreate(cfg, sup, err)
+		return err
+	}
+
+	if err := tunnel.Start(ctx); err != nil {
+		m.logger.Error("Failed to start tunnel", "name", cfg.Name, "error", err)
+		tunnel.Stop(ctx)
+		err = fmt.Errorf("starting tunnel %s: %w", cfg.Name, err)
+		m.deferCreate(cfg, sup, err)
+		return err
 	}
 
 	m.mu.Lock()
 	m.tunnels[cfg.Name] = tunnel
 	m.mu.Unlock()
 
+	m.clearPending(cfg.Name)
+	sup.up(time.Now())
+	m.startProber(cfg)
+
 	m.logger.Info("Created tunnel", "name", cfg.Name, "mode", m.mode)
 	return nil
 }
 
+// MARK: newTunnel
+// Constructs a tunnel of the type matching the manager's mode
+func (m *Manager) newTunnel(cfg config.TunnelConfig) (TunnelInterface, error) {
+	switch m.mode {
+	case ModeWgQuick:
+		return NewWgQuickTunnel(cfg, m.paths, m.logger, m.resolver)
+	case ModeUserspace:
+		tunnel, err := NewTunnel(cfg, m.logger, m.resolver)
+		if err != nil {
+			return nil, err
+		}
+		m.mu.RLock()
+		tunnel.peersChanged = m.peerHandler
+		m.mu.RUnlock()
+		return tunnel, nil
+	case ModeNetstack:
+		return NewNetstackTunnel(cfg, m.logger, m.resolver)
+	case ModeKernel:
+		return NewKernelTunnel(cfg, m.logger, m.resolver)
+	default:
+		return nil, fmt.Errorf("unsupported tunnel mode: %s", m.mode)
+	}
+}
+
 // MARK: UpdateTunnel
 // Updates an existing tunnel configuration
 func (m *Manager) UpdateTunnel(ctx context.Context, cfg config.TunnelConfig) error {
@@ -252,35 +312,60 @@ func (m *Manager) UpdateTunnel(ctx context.Context, cfg config.TunnelConfig) err
 		return fmt.Errorf("updating tunnel %s: %w", cfg.Name, err)
 	}
 
+	if err := m.ApplyKillSwitch(cfg); err != nil {
+		m.logger.Error("Failed to apply kill switch", "name", cfg.Name, "error", err)
+	}
+
+	m.supervisorFor(cfg)
+	m.startProber(cfg)
+
 	m.logger.Info("Updated tunnel", "name", cfg.Name)
 	return nil
 }
 
-// MARK: DeleteTunnel
-// Deletes a tunnel and cleans up its resources
-func (m *Manager) DeleteTunnel(ctx context.Context, name string) error {
+// MARK: StopTunnel
+// Stops a tunnel and cleans up its resources while keeping its kill switch in place
+func (m *Manager) StopTunnel(ctx context.Context, name string) error {
 	if atomic.LoadInt64(&m.running) == 0 {
 		return fmt.Errorf("tunnel manager not running")
 	}
 
+	m.forgetSupervisor(name)
+
 	m.mu.Lock()
 	tunnel, exists := m.tunnels[name]
 	if !exists {
 		m.mu.Unlock()
-		m.logger.Debug("Tunnel not found for deletion", "name", name)
+		m.logger.Debug("Tunnel not found for stop", "name", name)
 		return nil // Don't error if tunnel doesn't exist
 	}
 	delete(m.tunnels, name)
 	m.mu.Unlock()
 
+	m.stopProber(name)
+	m.forgetOnDemand(name)
+
 	ctx, cancel := context.WithTimeout(ctx, shutdownTimeout)
 	defer cancel()
 
 	if err := tunnel.Stop(ctx); err != nil {
-		m.logger.Error("Failed to stop tunnel during deletion", "name", name, "error", err)
-		// Continue with deletion even if stop failed
+		m.logger.Error("Failed to stop tunnel", "name", name, "error", err)
+		// Continue even if stop failed
 	}
 
+	m.logger.Info("Stopped tunnel", "name", name)
+	return nil
+}
+
+// MARK: DeleteTunnel
+// Stops a tunnel and removes its kill switch once it is gone from the configuration
+func (m *Manager) DeleteTunnel(ctx context.Context, name string) error {
+	if err := m.StopTunnel(ctx, name); err != nil {
+		return err
+	}
+
+	m.removeKillSwitch(name)
+
 	m.logger.Info("Deleted tunnel", "name", name)
 	return nil
 }
@@ -299,6 +384,9 @@ func (m *Manager) Status(ctx context.Context, name string) (TunnelStatus, error)
 	m.mu.RUnlock()
 
 	if !exists {
+		if status, pending := m.pendingStatus(name); pending {
+			return status, nil
+		}
 		return TunnelStatus{}, fmt.Errorf("tunnel %s not found", name)
 	}
 
@@ -306,10 +394,36 @@ func (m *Manager) Status(ctx context.Context, name string) (TunnelStatus, error)
 	if m.lastError != nil && status.State == "stopped" {
 		status.Error = m.lastError.Error()
 	}
+	m.attachProbeStatus(&status)
+	m.attachSupervisorStatus(&status)
 
 	return status, nil
 }
 
+// MARK: Healthy
+// Reports whether a tunnel is running, passing its probes and has at least one peer that is not stale
+func (s TunnelStatus) Healthy() bool {
+	if s.State != "running" {
+		return false
+	}
+
+	if s.Reachable != nil && !*s.Reachable {
+		return false
+	}
+
+	if len(s.PeerStats) == 0 {
+		return true
+	}
+
+	for _, peer := range s.PeerStats {
+		if peer.Health != PeerHealthStale {
+			return true
+		}
+	}
+
+	return false
+}
+
 // MARK: ListTunnels
 // Returns status information for all tunnels
 func (m *Manager) ListTunnels(ctx context.Context) ([]TunnelStatus, error) {
@@ -326,22 +440,77 @@ func (m *Manager) ListTunnels(ctx context.Context) ([]TunnelStatus, error) {
 		if m.lastError != nil && status.State == "stopped" {
 			status.Error = m.lastError.Error()
 		}
+		m.attachProbeStatus(&status)
+		m.attachSupervisorStatus(&status)
 		statuses = append(statuses, status)
 	}
 
-	return statuses, nil
+	return append(statuses, m.pendingStatuses()...), nil
+}
+
+// MARK: DialContext
+// Dials an address through the named tunnel, using its in-process network stack when the mode has one
+func (m *Manager) DialContext(ctx context.Context, tunnelName, network, address string) (net.Conn, error) {
+	if !m.usesTunnelStack() {
+		dialer := &net.Dialer{Timeout: hostDialTimeout, KeepAlive: hostDialKeepAlive}
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	m.mu.RLock()
+	tunnel, exists := m.tunnels[tunnelName]
+	m.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("tunnel %s not found", tunnelName)
+	}
+
+	dialer, ok := tunnel.(TunnelDialer)
+	if !ok {
+		return nil, fmt.Errorf("tunnel %s does not support dialing", tunnelName)
+	}
+
+	return dialer.DialContext(ctx, network, address)
+}
+
+// MARK: CaptureTunnel
+// Streams a pcapng capture of the decrypted traffic on the named tunnel
+func (m *Manager) CaptureTunnel(ctx context.Context, name string, opts CaptureOptions, w io.Writer) error {
+	if !m.usesTunnelStack() {
+		return ErrCaptureUnsupported
+	}
+
+	m.mu.RLock()
+	tunnel, exists := m.tunnels[name]
+	m.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("tunnel %s not found", name)
+	}
+
+	capturer, ok := tunnel.(TunnelCapturer)
+	if !ok {
+		return ErrCaptureUnsupported
+	}
+
+	return capturer.Capture(ctx, opts, w)
+}
+
+// MARK: usesTunnelStack
+// Checks if tunnels own an in-process network stack rather than relying on host routing
+func (m *Manager) usesTunnelStack() bool {
+	return m.mode == ModeUserspace || m.mode == ModeNetstack
 }
 
 // MARK: IsReady
 // Checks if the tunnel manager is ready to accept operations
 func (m *Manager) IsReady() bool {
-	return atomic.LoadInt64(&m.running) == 1 && atomic.LoadInt32(&m.retryAttempts) < maxRetryAttempts
+	return atomic.LoadInt64(&m.running) == 1
 }
 
 // Recovery and health functions
 
 // MARK: Recover
-// Attempts to recover failed tunnels
+// Immediately retries every stopped or pending tunnel, skipping any remaining backoff
 func (m *Manager) Recover(ctx context.Context) error {
 	if atomic.LoadInt64(&m.running) == 0 {
 		return fmt.Errorf("tunnel manager not running")
@@ -350,25 +519,30 @@ func (m *Manager) Recover(ctx context.Context) error {
 	m.logger.Info("Starting tunnel recovery process")
 
 	m.mu.RLock()
-	tunnels := make([]TunnelInterface, 0, len(m.tunnels))
-	for _, tunnel := range m.tunnels {
-		tunnels = append(tunnels, tunnel)
+	tunnels := make(map[string]TunnelInterface, len(m.tunnels))
+	for name, tunnel := range m.tunnels {
+		tunnels[name] = tunnel
 	}
 	m.mu.RUnlock()
 
 	var recovered, failed int
-	for _, tunnel := range tunnels {
-		status := tunnel.Status(ctx)
-		if status.State == "stopped" {
-			m.logger.Info("Attempting to recover tunnel", "name", status.Name)
-
-			if err := tunnel.Start(ctx); err != nil {
-				m.logger.Error("Failed to recover tunnel", "name", status.Name, "error", err)
-				failed++
-			} else {
-				m.logger.Info("Successfully recovered tunnel", "name", status.Name)
-				recovered++
-			}
+	for name, tunnel := range tunnels {
+		if tunnel.IsRunning() {
+			continue
+		}
+
+		if m.restartTunnel(ctx, name, tunnel, "manual recovery") {
+			recovered++
+		} else {
+			failed++
+		}
+	}
+
+	for _, cfg := range m.pendingConfigs() {
+		if m.retryCreate(ctx, cfg) {
+			recovered++
+		} else {
+			failed++
 		}
 	}
 
@@ -376,7 +550,6 @@ func (m *Manager) Recover(ctx context.Context) error {
 		return fmt.Errorf("recovery completed: %d recovered, %d failed", recovered, failed)
 	}
 
-	atomic.StoreInt32(&m.retryAttempts, 0)
 	m.lastError = nil
 	m.logger.Info("Tunnel recovery completed successfully", "recovered", recovered)
 	return nil
@@ -401,41 +574,125 @@ func (m *Manager) healthMonitor() {
 }
 
 // MARK: performHealthCheck
-// Performs health checks on all tunnels and triggers recovery if needed
+// Updates each tunnel's supervisor and retries stopped, unreachable or never-created tunnels once their backoff elapses
 func (m *Manager) performHealthCheck() {
 	if atomic.LoadInt64(&m.running) == 0 {
 		return
 	}
 
 	m.mu.RLock()
-	tunnels := make([]TunnelInterface, 0, len(m.tunnels))
-	for _, tunnel := range m.tunnels {
-		tunnels = append(tunnels, tunnel)
+	tunnels := make(map[string]TunnelInterface, len(m.tunnels))
+	for name, tunnel := range m.tunnels {
+		tunnels[name] = tunnel
 	}
 	m.mu.RUnlock()
 
-	var failedTunnels []string
-	for _, tunnel := range tunnels {
+	now := time.Now()
+	for name, tunnel := range tunnels {
+		sup := m.getSupervisor(name)
+		if sup == nil {
+			continue
+		}
+
 		status := tunnel.Status(m.ctx)
-		if status.State == "stopped" {
-			failedTunnels = append(failedTunnels, status.Name)
+		m.attachProbeStatus(&status)
+		prober := m.getProber(name)
+
+		switch {
+		case status.State == "stopped":
+			if sup.due(now) {
+				m.restartTunnel(m.ctx, name, tunnel, "tunnel stopped")
+			}
+		case prober != nil && prober.failing():
+			sup.degraded(now, "reachability probes failing")
+			if sup.due(now) {
+				m.restartTunnel(m.ctx, name, tunnel, "reachability probes failing")
+				prober.resetFailures()
+			}
+		case !status.Healthy():
+			sup.degraded(now, "all peers stale")
+		default:
+			sup.up(now)
 		}
 	}
 
-	if len(failedTunnels) > 0 {
-		retryCount := atomic.LoadInt32(&m.retryAttempts)
-		if retryCount < maxRetryAttempts {
-			m.logger.Info("Health check found failed tunnels", "count", len(failedTunnels), "tunnels", failedTunnels)
+	for _, cfg := range m.pendingConfigs() {
+		if sup := m.getSupervisor(cfg.Name); sup != nil && sup.due(now) {
+			m.retryCreate(m.ctx, cfg)
+		}
+	}
+}
 
-			atomic.AddInt32(&m.retryAttempts, 1)
+// MARK: restartTunnel
+// Records a retry with the tunnel's supervisor and restarts it, reporting whether it came back up
+func (m *Manager) restartTunnel(ctx context.Context, name string, tunnel TunnelInterface, reason string) bool {
+	sup := m.getSupervisor(name)
+	if sup == nil {
+		return false
+	}
 
-			if err := m.Recover(m.ctx); err != nil {
-				m.lastError = err
-				m.logger.Error("Automatic recovery failed", "error", err)
-			}
-		} else {
-			m.logger.Error("Maximum retry attempts reached, manual intervention required",
-				"attempts", retryCount, "failed_tunnels", failedTunnels)
+	// Recorded before the attempt so the next one is spaced out even if this start succeeds but the tunnel fails again
+	delay := sup.failure(time.Now(), reason)
+	m.logger.Warn("Restarting tunnel",
+		"name", name,
+		"reason", reason,
+		"attempt", sup.attemptCount(),
+		"state", sup.current(),
+		"next_retry_in", delay)
+
+	if err := tunnel.Stop(ctx); err != nil {
+		m.logger.Debug("Failed to stop tunnel before restart", "name", name, "error", err)
+	}
+
+	if err := tunnel.Start(ctx); err != nil {
+		m.lastError = err
+		sup.setError(err.Error())
+		m.logger.Error("Failed to restart tunnel", "name", name, "error", err)
+		return false
+	}
+
+	// The next health check confirms the tunnel is up before the backoff is cleared
+	sup.starting(time.Now())
+	m.logger.Info("Restarted tunnel", "name", name)
+	return true
+}
+
+// MARK: retryCreate
+// Makes a single attempt to create a tunnel whose earlier creation failed
+func (m *Manager) retryCreate(ctx context.Context, cfg config.TunnelConfig) bool {
+	sup := m.getSupervisor(cfg.Name)
+	if sup == nil {
+		return false
+	}
+
+	delay := sup.failure(time.Now(), "tunnel not created")
+	m.logger.Info("Retrying tunnel creation",
+		"name", cfg.Name,
+		"attempt", sup.attemptCount(),
+		"state", sup.current(),
+		"next_retry_in", delay)
+
+	tunnel, err := m.newTunnel(cfg)
+	if err == nil {
+		if err = tunnel.Start(ctx); err != nil {
+			tunnel.Stop(ctx)
 		}
 	}
+	if err != nil {
+		m.lastError = err
+		sup.setError(err.Error())
+		m.logger.Error("Failed to create tunnel", "name", cfg.Name, "error", err)
+		return false
+	}
+
+	m.mu.Lock()
+	m.tunnels[cfg.Name] = tunnel
+	m.mu.Unlock()
+
+	m.clearPending(cfg.Name)
+	sup.starting(time.Now())
+	m.startProber(cfg)
+
+	m.logger.Info("Created tunnel", "name", cfg.Name, "mode", m.mode)
+	return true
 }
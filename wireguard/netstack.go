@@ -0,0 +1,105 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync/atomic"
+	"time"
+
+	"golang.zx2c4.com/wireguard/tun"
+	"golang.zx2c4.com/wireguard/tun/netstack"
+)
+
+const (
+	dialResolveTimeout = 5 * time.Second
+)
+
+// In-process network stack functions
+
+// MARK: createNetstack
+// Creates the in-process network stack that owns the tunnel addresses for outbound dials
+func (t *Tunnel) createNetstack(mtu int) (tun.Device, *netstack.Net, error) {
+	addrs, err := parseTunnelAddresses(t.config.Addresses)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stackDev, stackNet, err := netstack.CreateNetTUN(addrs, nil, mtu)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating netstack: %w", err)
+	}
+
+	return stackDev, stackNet, nil
+}
+
+// MARK: DialContext
+// Dials an address through the tunnel's in-process network stack, bypassing host routing
+func (t *Tunnel) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	t.mu.RLock()
+	stackNet := t.stackNet
+	t.mu.RUnlock()
+
+	if atomic.LoadInt64(&t.running) == 0 || stackNet == nil {
+		return nil, fmt.Errorf("tunnel %s not running", t.name)
+	}
+
+	resolved, err := resolveDialAddress(ctx, t.resolver, address)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", address, err)
+	}
+
+	return stackNet.DialContext(ctx, network, resolved)
+}
+
+// MARK: DialPing
+// Opens an ICMP echo socket to the target inside the tunnel's in-process network stack
+func (t *Tunnel) DialPing(target netip.Addr) (net.PacketConn, error) {
+	t.mu.RLock()
+	stackNet := t.stackNet
+	t.mu.RUnlock()
+
+	if atomic.LoadInt64(&t.running) == 0 || stackNet == nil {
+		return nil, fmt.Errorf("tunnel %s not running", t.name)
+	}
+
+	return stackNet.DialPingAddr(netip.Addr{}, target)
+}
+
+// MARK: parseTunnelAddresses
+// Extracts the interface IPs from the configured tunnel CIDRs
+func parseTunnelAddresses(cidrs []string) ([]netip.Addr, error) {
+	if len(cidrs) == 0 {
+		return nil, fmt.Errorf("no addresses configured")
+	}
+
+	addrs := make([]netip.Addr, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %s: %w", cidr, err)
+		}
+		addrs = append(addrs, prefix.Addr())
+	}
+
+	return addrs, nil
+}
+
+// MARK: resolveDialAddress
+// Resolves a host:port to an IP:port using the host resolver, since the tunnel stack has no DNS
+func resolveDialAddress(ctx context.Context, resolver *AsyncResolver, address string) (string, error) {
+	if resolved, ok := resolver.ResolveFast(address); ok {
+		return resolved, nil
+	}
+
+	select {
+	case result := <-resolver.ResolveAsync(address, dialResolveTimeout):
+		if result.err != nil {
+			return "", result.err
+		}
+		return result.endpoint, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
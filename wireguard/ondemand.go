@@ -0,0 +1,230 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/JPKribs/FinGuard/config"
+)
+
+const (
+	idleCheckInterval        = 15 * time.Second
+	onDemandStartTimeout     = 60 * time.Second
+	onDemandHandshakeTimeout = 20 * time.Second
+	onDemandHandshakePoll    = 250 * time.Millisecond
+)
+
+// MARK: AcquireTunnel
+// Brings an on-demand tunnel up if needed and holds it open until the returned release is called
+func (m *Manager) AcquireTunnel(ctx context.Context, cfg config.TunnelConfig) (func(), error) {
+	if !cfg.OnDemand {
+		return func() {}, nil
+	}
+
+	if atomic.LoadInt64(&m.running) == 0 {
+		return nil, fmt.Errorf("tunnel manager not running")
+	}
+
+	entry := m.onDemandEntry(cfg)
+
+	// Counted before starting so the idle monitor never stops a tunnel that is being woken
+	atomic.AddInt64(&entry.active, 1)
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			atomic.StoreInt64(&entry.lastActive, time.Now().UnixNano())
+			atomic.AddInt64(&entry.active, -1)
+		})
+	}
+
+	if err := m.wakeOnDemand(ctx, cfg, entry); err != nil {
+		release()
+		return nil, err
+	}
+
+	return release, nil
+}
+
+// MARK: onDemandEntry
+// Returns the idle tracking state for an on-demand tunnel, creating it on first use
+func (m *Manager) onDemandEntry(cfg config.TunnelConfig) *onDemandTunnel {
+	idleTimeout := time.Duration(cfg.IdleTimeout) * time.Second
+	if idleTimeout <= 0 {
+		idleTimeout = config.DefaultIdleTimeout * time.Second
+	}
+
+	m.onDemandMu.Lock()
+	defer m.onDemandMu.Unlock()
+
+	entry, exists := m.onDemand[cfg.Name]
+	if !exists {
+		entry = &onDemandTunnel{lastActive: time.Now().UnixNano()}
+		m.onDemand[cfg.Name] = entry
+	}
+	atomic.StoreInt64(&entry.idleTimeout, int64(idleTimeout))
+
+	return entry
+}
+
+// MARK: forgetOnDemand
+// Drops idle tracking for a tunnel that has been deleted
+func (m *Manager) forgetOnDemand(name string) {
+	m.onDemandMu.Lock()
+	defer m.onDemandMu.Unlock()
+	delete(m.onDemand, name)
+}
+
+// MARK: wakeOnDemand
+// Starts an on-demand tunnel once, letting concurrent callers wait for the same start
+func (m *Manager) wakeOnDemand(ctx context.Context, cfg config.TunnelConfig, entry *onDemandTunnel) error {
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if m.TunnelRunning(cfg.Name) {
+		return nil
+	}
+
+	m.logger.Info("Starting on-demand tunnel", "name", cfg.Name)
+
+	// The start outlives the request that triggered it so other waiting requests can still use it
+	startCtx, cancel := context.WithTimeout(m.ctx, onDemandStartTimeout)
+	defer cancel()
+
+	if err := m.CreateTunnel(startCtx, cfg); err != nil {
+		return fmt.Errorf("starting on-demand tunnel %s: %w", cfg.Name, err)
+	}
+	atomic.StoreInt64(&entry.lastActive, time.Now().UnixNano())
+
+	if err := m.waitForHandshake(ctx, cfg); err != nil {
+		return err
+	}
+
+	m.logger.Info("On-demand tunnel ready", "name", cfg.Name)
+	return nil
+}
+
+// MARK: waitForHandshake
+// Blocks until a peer of the tunnel completes a handshake or the wait times out
+func (m *Manager) waitForHandshake(ctx context.Context, cfg config.TunnelConfig) error {
+	// Without a keepalive nothing is sent until the first dial, which performs the handshake itself
+	initiates := false
+	for _, peer := range cfg.Peers {
+		if peer.Endpoint != "" && (peer.Persistent || peer.PersistentKeepaliveInt > 0) {
+			initiates = true
+			break
+		}
+	}
+	if !initiates {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, onDemandHandshakeTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(onDemandHandshakePoll)
+	defer ticker.Stop()
+
+	for {
+		m.mu.RLock()
+		tunnel, exists := m.tunnels[cfg.Name]
+		m.mu.RUnlock()
+
+		if !exists {
+			return fmt.Errorf("tunnel %s stopped before completing a handshake", cfg.Name)
+		}
+
+		for _, peer := range tunnel.Status(ctx).PeerStats {
+			if peer.LastHandshake != nil && !peer.LastHandshake.IsZero() {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for handshake on tunnel %s: %w", cfg.Name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// MARK: idleMonitor
+// Periodically stops on-demand tunnels that have carried no proxied traffic for their idle timeout
+func (m *Manager) idleMonitor() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.stopIdleTunnels()
+		}
+	}
+}
+
+// MARK: stopIdleTunnels
+// Stops every on-demand tunnel with no active users whose idle timeout has elapsed
+func (m *Manager) stopIdleTunnels() {
+	m.onDemandMu.Lock()
+	entries := make(map[string]*onDemandTunnel, len(m.onDemand))
+	for name, entry := range m.onDemand {
+		entries[name] = entry
+	}
+	m.onDemandMu.Unlock()
+
+	now := time.Now()
+	for name, entry := range entries {
+		if !m.TunnelRunning(name) || !entry.idle(now) {
+			continue
+		}
+
+		entry.mu.Lock()
+		// A request may have arrived between the check and taking the lock
+		if entry.idle(now) {
+			m.stopIdleTunnel(name, time.Duration(atomic.LoadInt64(&entry.idleTimeout)))
+		}
+		entry.mu.Unlock()
+	}
+}
+
+// MARK: idle
+// Checks if nothing holds the tunnel and its last use is older than the idle timeout
+func (e *onDemandTunnel) idle(now time.Time) bool {
+	if atomic.LoadInt64(&e.active) > 0 {
+		return false
+	}
+	lastActive := time.Unix(0, atomic.LoadInt64(&e.lastActive))
+	return now.Sub(lastActive) >= time.Duration(atomic.LoadInt64(&e.idleTimeout))
+}
+
+// MARK: stopIdleTunnel
+// Stops an idle on-demand tunnel while keeping its kill switch and idle tracking in place
+func (m *Manager) stopIdleTunnel(name string, idleTimeout time.Duration) {
+	m.mu.Lock()
+	tunnel, exists := m.tunnels[name]
+	if !exists {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.tunnels, name)
+	m.mu.Unlock()
+
+	m.stopProber(name)
+	m.forgetSupervisor(name)
+
+	ctx, cancel := context.WithTimeout(m.ctx, shutdownTimeout)
+	defer cancel()
+
+	if err := tunnel.Stop(ctx); err != nil {
+		m.logger.Error("Failed to stop idle tunnel", "name", name, "error", err)
+		return
+	}
+
+	m.logger.Info("Stopped idle on-demand tunnel", "name", name, "idle_timeout", idleTimeout)
+}
@@ -0,0 +1,154 @@
+package wireguard
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+// Packet parsing functions
+
+// MARK: parsePacketHeader
+// Extracts the addresses and protocol of an IP packet, plus its TCP or UDP ports when present
+func parsePacketHeader(packet []byte) (packetHeader, bool) {
+	var header packetHeader
+	if len(packet) == 0 {
+		return header, false
+	}
+
+	switch packet[0] >> 4 {
+	case 4:
+		if len(packet) < 20 {
+			return header, false
+		}
+		ihl := int(packet[0]&0x0f) * 4
+		if ihl < 20 || len(packet) < ihl {
+			return header, false
+		}
+		header.src = netip.AddrFrom4([4]byte(packet[12:16]))
+		header.dst = netip.AddrFrom4([4]byte(packet[16:20]))
+		header.proto = packet[9]
+		// Only the first fragment carries the transport header
+		if binary.BigEndian.Uint16(packet[6:8])&0x1fff == 0 {
+			header.transport = packet[ihl:]
+		} else {
+			header.fragment = true
+		}
+	case 6:
+		if len(packet) < 40 {
+			return header, false
+		}
+		header.src = netip.AddrFrom16([16]byte(packet[8:24]))
+		header.dst = netip.AddrFrom16([16]byte(packet[24:40]))
+		parseIPv6Transport(&header, packet[6], packet[40:])
+	default:
+		return header, false
+	}
+
+	if (header.proto == ipProtoTCP || header.proto == ipProtoUDP) && len(header.transport) >= 4 {
+		header.srcPort = binary.BigEndian.Uint16(header.transport[0:2])
+		header.dstPort = binary.BigEndian.Uint16(header.transport[2:4])
+	}
+
+	return header, true
+}
+
+// MARK: parseIPv6Transport
+// Walks the IPv6 extension headers to the upper-layer protocol, marking the header opaque when the chain cannot be followed
+func parseIPv6Transport(header *packetHeader, next byte, payload []byte) {
+	for {
+		switch next {
+		case ipv6HopByHop, ipv6Routing, ipv6DestOptions:
+			if len(payload) < 2 {
+				header.opaque = true
+				return
+			}
+			length := (int(payload[1]) + 1) * 8
+			if len(payload) < length {
+				header.opaque = true
+				return
+			}
+			next, payload = payload[0], payload[length:]
+		case ipv6AuthHeader:
+			if len(payload) < 2 {
+				header.opaque = true
+				return
+			}
+			length := (int(payload[1]) + 2) * 4
+			if len(payload) < length {
+				header.opaque = true
+				return
+			}
+			next, payload = payload[0], payload[length:]
+		case ipv6Fragment:
+			if len(payload) < 8 {
+				header.opaque = true
+				return
+			}
+			offset := binary.BigEndian.Uint16(payload[2:4]) >> 3
+			next, payload = payload[0], payload[8:]
+			// Only the first fragment carries the transport header
+			if offset != 0 {
+				header.proto = next
+				header.fragment = true
+				// Later extension headers sit in the first fragment, hiding the protocol
+				header.opaque = isIPv6Extension(next)
+				return
+			}
+		case ipv6NoNextHeader:
+			header.proto = next
+			return
+		default:
+			header.proto = next
+			header.transport = payload
+			return
+		}
+	}
+}
+
+// MARK: isIPv6Extension
+// Checks if a next header value names an IPv6 extension header rather than an upper-layer protocol
+func isIPv6Extension(next byte) bool {
+	switch next {
+	case ipv6HopByHop, ipv6Routing, ipv6Fragment, ipv6AuthHeader, ipv6DestOptions:
+		return true
+	}
+	return false
+}
+
+// Flow keying functions
+
+// MARK: outboundFlow
+// Keys an outbound packet by its protocol, local and remote address and ports
+func outboundFlow(header packetHeader) (packetFlow, bool) {
+	localPort, remotePort, ok := flowPorts(header, header.srcPort, header.dstPort)
+	if !ok {
+		return packetFlow{}, false
+	}
+	return packetFlow{proto: header.proto, local: header.src, remote: header.dst, localPort: localPort, remotePort: remotePort}, true
+}
+
+// MARK: inboundFlow
+// Keys an inbound packet the same way as the outbound packets it replies to
+func inboundFlow(header packetHeader) (packetFlow, bool) {
+	localPort, remotePort, ok := flowPorts(header, header.dstPort, header.srcPort)
+	if !ok {
+		return packetFlow{}, false
+	}
+	return packetFlow{proto: header.proto, local: header.dst, remote: header.src, localPort: localPort, remotePort: remotePort}, true
+}
+
+// MARK: flowPorts
+// Returns the ports identifying a flow, using the echo identifier for ICMP
+func flowPorts(header packetHeader, localPort, remotePort uint16) (uint16, uint16, bool) {
+	switch header.proto {
+	case ipProtoTCP, ipProtoUDP:
+		return localPort, remotePort, len(header.transport) >= 4
+	case ipProtoICMP, ipProtoICMPv6:
+		if len(header.transport) < 8 || !isEchoMessage(header.proto, header.transport[0]) {
+			return 0, 0, false
+		}
+		id := uint16(header.transport[4])<<8 | uint16(header.transport[5])
+		return id, id, true
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,265 @@
+package wireguard
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+)
+
+// testIPv4Packet builds an IPv4 packet with a 20 byte header and the given fragment offset in 8 byte units
+func testIPv4Packet(src, dst string, proto byte, fragOffset uint16, payload []byte) []byte {
+	packet := make([]byte, 20, 20+len(payload))
+	packet[0] = 0x45
+	binary.BigEndian.PutUint16(packet[6:8], fragOffset&0x1fff)
+	packet[9] = proto
+	copy(packet[12:16], netip.MustParseAddr(src).AsSlice())
+	copy(packet[16:20], netip.MustParseAddr(dst).AsSlice())
+	return append(packet, payload...)
+}
+
+// testIPv6Packet builds an IPv6 packet whose payload starts with the header named by next
+func testIPv6Packet(src, dst string, next byte, payload []byte) []byte {
+	packet := make([]byte, 40, 40+len(payload))
+	packet[0] = 0x60
+	binary.BigEndian.PutUint16(packet[4:6], uint16(len(payload)))
+	packet[6] = next
+	copy(packet[8:24], netip.MustParseAddr(src).AsSlice())
+	copy(packet[24:40], netip.MustParseAddr(dst).AsSlice())
+	return append(packet, payload...)
+}
+
+// testExtHeader builds an 8 byte Hop-by-Hop, Routing or Destination Options header
+func testExtHeader(next byte, rest []byte) []byte {
+	return append([]byte{next, 0, 0, 0, 0, 0, 0, 0}, rest...)
+}
+
+// testFragmentHeader builds an IPv6 Fragment header with the given offset in 8 byte units
+func testFragmentHeader(next byte, offset uint16, rest []byte) []byte {
+	header := []byte{next, 0, 0, 0, 0, 0, 0, 1}
+	binary.BigEndian.PutUint16(header[2:4], offset<<3)
+	return append(header, rest...)
+}
+
+// testPorts builds the first four bytes of a TCP or UDP header
+func testPorts(src, dst uint16) []byte {
+	ports := make([]byte, 4)
+	binary.BigEndian.PutUint16(ports[0:2], src)
+	binary.BigEndian.PutUint16(ports[2:4], dst)
+	return ports
+}
+
+func TestParsePacketHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		packet   []byte
+		ok       bool
+		proto    byte
+		srcPort  uint16
+		dstPort  uint16
+		fragment bool
+		opaque   bool
+	}{
+		{
+			name:    "ipv4 tcp",
+			packet:  testIPv4Packet("10.0.0.2", "192.168.1.10", ipProtoTCP, 0, testPorts(40000, 22)),
+			ok:      true,
+			proto:   ipProtoTCP,
+			srcPort: 40000,
+			dstPort: 22,
+		},
+		{
+			name:     "ipv4 later fragment",
+			packet:   testIPv4Packet("10.0.0.2", "192.168.1.10", ipProtoUDP, 185, testPorts(40000, 53)),
+			ok:       true,
+			proto:    ipProtoUDP,
+			fragment: true,
+		},
+		{
+			name:   "ipv4 truncated",
+			packet: testIPv4Packet("10.0.0.2", "192.168.1.10", ipProtoTCP, 0, nil)[:12],
+		},
+		{
+			name:   "empty",
+			packet: nil,
+		},
+		{
+			name:   "unknown version",
+			packet: append([]byte{0x50}, make([]byte, 39)...),
+		},
+		{
+			name:    "ipv6 udp",
+			packet:  testIPv6Packet("fd00::2", "fd00::1", ipProtoUDP, testPorts(5353, 53)),
+			ok:      true,
+			proto:   ipProtoUDP,
+			srcPort: 5353,
+			dstPort: 53,
+		},
+		{
+			name:    "ipv6 hop-by-hop and destination options",
+			packet:  testIPv6Packet("fd00::2", "fd00::1", ipv6HopByHop, testExtHeader(ipv6DestOptions, testExtHeader(ipProtoTCP, testPorts(40000, 22)))),
+			ok:      true,
+			proto:   ipProtoTCP,
+			srcPort: 40000,
+			dstPort: 22,
+		},
+		{
+			name:    "ipv6 routing header",
+			packet:  testIPv6Packet("fd00::2", "fd00::1", ipv6Routing, testExtHeader(ipProtoTCP, testPorts(40000, 443))),
+			ok:      true,
+			proto:   ipProtoTCP,
+			srcPort: 40000,
+			dstPort: 443,
+		},
+		{
+			name:    "ipv6 authentication header",
+			packet:  testIPv6Packet("fd00::2", "fd00::1", ipv6AuthHeader, append([]byte{ipProtoTCP, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, testPorts(40000, 22)...)),
+			ok:      true,
+			proto:   ipProtoTCP,
+			srcPort: 40000,
+			dstPort: 22,
+		},
+		{
+			name:    "ipv6 first fragment",
+			packet:  testIPv6Packet("fd00::2", "fd00::1", ipv6Fragment, testFragmentHeader(ipProtoTCP, 0, testPorts(40000, 22))),
+			ok:      true,
+			proto:   ipProtoTCP,
+			srcPort: 40000,
+			dstPort: 22,
+		},
+		{
+			name:     "ipv6 later fragment",
+			packet:   testIPv6Packet("fd00::2", "fd00::1", ipv6Fragment, testFragmentHeader(ipProtoTCP, 100, testPorts(40000, 22))),
+			ok:       true,
+			proto:    ipProtoTCP,
+			fragment: true,
+		},
+		{
+			name:     "ipv6 later fragment hiding an extension header",
+			packet:   testIPv6Packet("fd00::2", "fd00::1", ipv6Fragment, testFragmentHeader(ipv6DestOptions, 100, nil)),
+			ok:       true,
+			proto:    ipv6DestOptions,
+			fragment: true,
+			opaque:   true,
+		},
+		{
+			name:   "ipv6 truncated extension header",
+			packet: testIPv6Packet("fd00::2", "fd00::1", ipv6HopByHop, []byte{ipProtoTCP, 1, 0, 0}),
+			ok:     true,
+			opaque: true,
+		},
+		{
+			name:   "ipv6 no next header",
+			packet: testIPv6Packet("fd00::2", "fd00::1", ipv6NoNextHeader, nil),
+			ok:     true,
+			proto:  ipv6NoNextHeader,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, ok := parsePacketHeader(tt.packet)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if header.proto != tt.proto || header.srcPort != tt.srcPort || header.dstPort != tt.dstPort {
+				t.Errorf("proto %d ports %d->%d, want proto %d ports %d->%d",
+					header.proto, header.srcPort, header.dstPort, tt.proto, tt.srcPort, tt.dstPort)
+			}
+			if header.fragment != tt.fragment || header.opaque != tt.opaque {
+				t.Errorf("fragment %v opaque %v, want fragment %v opaque %v",
+					header.fragment, header.opaque, tt.fragment, tt.opaque)
+			}
+		})
+	}
+}
+
+func TestFlowKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		outbound []byte
+		inbound  []byte
+		want     bool
+	}{
+		{
+			name:     "tcp reply",
+			outbound: testIPv4Packet("10.0.0.2", "192.168.1.10", ipProtoTCP, 0, testPorts(40000, 8096)),
+			inbound:  testIPv4Packet("192.168.1.10", "10.0.0.2", ipProtoTCP, 0, testPorts(8096, 40000)),
+			want:     true,
+		},
+		{
+			name:     "same local port from another remote",
+			outbound: testIPv4Packet("10.0.0.2", "192.168.1.10", ipProtoTCP, 0, testPorts(40000, 8096)),
+			inbound:  testIPv4Packet("192.168.1.11", "10.0.0.2", ipProtoTCP, 0, testPorts(8096, 40000)),
+			want:     false,
+		},
+		{
+			name:     "same local port from another remote port",
+			outbound: testIPv4Packet("10.0.0.2", "192.168.1.10", ipProtoUDP, 0, testPorts(40000, 53)),
+			inbound:  testIPv4Packet("192.168.1.10", "10.0.0.2", ipProtoUDP, 0, testPorts(5353, 40000)),
+			want:     false,
+		},
+		{
+			name:     "protocol mismatch",
+			outbound: testIPv4Packet("10.0.0.2", "192.168.1.10", ipProtoTCP, 0, testPorts(40000, 53)),
+			inbound:  testIPv4Packet("192.168.1.10", "10.0.0.2", ipProtoUDP, 0, testPorts(53, 40000)),
+			want:     false,
+		},
+		{
+			name:     "ipv6 udp reply",
+			outbound: testIPv6Packet("fd00::2", "fd00::1", ipProtoUDP, testPorts(40000, 53)),
+			inbound:  testIPv6Packet("fd00::1", "fd00::2", ipProtoUDP, testPorts(53, 40000)),
+			want:     true,
+		},
+		{
+			name:     "icmp echo reply",
+			outbound: testIPv4Packet("10.0.0.2", "192.168.1.10", ipProtoICMP, 0, []byte{8, 0, 0, 0, 0, 7, 0, 1}),
+			inbound:  testIPv4Packet("192.168.1.10", "10.0.0.2", ipProtoICMP, 0, []byte{0, 0, 0, 0, 0, 7, 0, 1}),
+			want:     true,
+		},
+		{
+			name:     "icmp echo reply with another identifier",
+			outbound: testIPv4Packet("10.0.0.2", "192.168.1.10", ipProtoICMP, 0, []byte{8, 0, 0, 0, 0, 7, 0, 1}),
+			inbound:  testIPv4Packet("192.168.1.10", "10.0.0.2", ipProtoICMP, 0, []byte{0, 0, 0, 0, 0, 8, 0, 1}),
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, ok := flowKey(tt.outbound, true)
+			if !ok {
+				t.Fatal("outbound packet has no flow key")
+			}
+			in, ok := flowKey(tt.inbound, false)
+			if !ok {
+				t.Fatal("inbound packet has no flow key")
+			}
+			if got := out == in; got != tt.want {
+				t.Errorf("keys %+v and %+v equal = %v, want %v", out, in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlowKeyUnkeyedPackets(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet []byte
+	}{
+		{"icmp destination unreachable", testIPv4Packet("10.0.0.2", "192.168.1.10", ipProtoICMP, 0, []byte{3, 1, 0, 0, 0, 0, 0, 0})},
+		{"truncated tcp header", testIPv4Packet("10.0.0.2", "192.168.1.10", ipProtoTCP, 0, []byte{0x9c, 0x40})},
+		{"ipv4 later fragment", testIPv4Packet("10.0.0.2", "192.168.1.10", ipProtoTCP, 185, testPorts(40000, 8096))},
+		{"other protocol", testIPv4Packet("10.0.0.2", "192.168.1.10", 47, 0, testPorts(40000, 8096))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if key, ok := flowKey(tt.packet, true); ok {
+				t.Errorf("got key %+v, want none", key)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package wireguard
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+const (
+	pcapngSectionHeader     = 0x0A0D0D0A
+	pcapngInterfaceDesc     = 0x00000001
+	pcapngInterfaceStats    = 0x00000005
+	pcapngEnhancedPacket    = 0x00000006
+	pcapngByteOrderMagic    = 0x1A2B3C4D
+	pcapngLinkTypeRaw       = 101
+	pcapngOptEnd            = 0
+	pcapngOptShbUserAppl    = 4
+	pcapngOptIfName         = 2
+	pcapngOptIfTsResol      = 9
+	pcapngOptEpbFlags       = 2
+	pcapngOptIsbStartTime   = 2
+	pcapngOptIsbEndTime     = 3
+	pcapngOptIsbIfDrop      = 5
+	pcapngFlagInbound       = 1
+	pcapngFlagOutbound      = 2
+	pcapngTimestampUnitsExp = 9
+)
+
+// pcapng writer functions
+
+// MARK: newPcapngWriter
+// Starts a pcapng section with a single raw IP interface named after the tunnel
+func newPcapngWriter(w io.Writer, interfaceName string, snapLen int) (*pcapngWriter, error) {
+	p := &pcapngWriter{out: bufio.NewWriter(w), dest: w}
+
+	var shbOpts []byte
+	shbOpts = appendPcapngOption(shbOpts, pcapngOptShbUserAppl, []byte("FinGuard"))
+	shbOpts = appendPcapngOption(shbOpts, pcapngOptEnd, nil)
+
+	shb := make([]byte, 16, 16+len(shbOpts))
+	binary.LittleEndian.PutUint32(shb[0:4], pcapngByteOrderMagic)
+	binary.LittleEndian.PutUint16(shb[4:6], 1)
+	binary.LittleEndian.PutUint16(shb[6:8], 0)
+	binary.LittleEndian.PutUint64(shb[8:16], ^uint64(0))
+	if err := p.writeBlock(pcapngSectionHeader, append(shb, shbOpts...)); err != nil {
+		return nil, err
+	}
+
+	var idbOpts []byte
+	idbOpts = appendPcapngOption(idbOpts, pcapngOptIfName, []byte(interfaceName))
+	idbOpts = appendPcapngOption(idbOpts, pcapngOptIfTsResol, []byte{pcapngTimestampUnitsExp})
+	idbOpts = appendPcapngOption(idbOpts, pcapngOptEnd, nil)
+
+	idb := make([]byte, 8, 8+len(idbOpts))
+	binary.LittleEndian.PutUint16(idb[0:2], pcapngLinkTypeRaw)
+	binary.LittleEndian.PutUint32(idb[4:8], uint32(snapLen))
+	if err := p.writeBlock(pcapngInterfaceDesc, append(idb, idbOpts...)); err != nil {
+		return nil, err
+	}
+
+	return p, p.Flush()
+}
+
+// MARK: WritePacket
+// Writes one packet with its capture time and direction
+func (p *pcapngWriter) WritePacket(pkt capturedPacket) error {
+	var opts []byte
+	flags := make([]byte, 4)
+	if pkt.direction == CaptureInbound {
+		binary.LittleEndian.PutUint32(flags, pcapngFlagInbound)
+	} else {
+		binary.LittleEndian.PutUint32(flags, pcapngFlagOutbound)
+	}
+	opts = appendPcapngOption(opts, pcapngOptEpbFlags, flags)
+	opts = appendPcapngOption(opts, pcapngOptEnd, nil)
+
+	body := make([]byte, 20, 20+pcapngPadded(len(pkt.data))+len(opts))
+	timestamp := uint64(pkt.timestamp.UnixNano())
+	binary.LittleEndian.PutUint32(body[0:4], 0)
+	binary.LittleEndian.PutUint32(body[4:8], uint32(timestamp>>32))
+	binary.LittleEndian.PutUint32(body[8:12], uint32(timestamp))
+	binary.LittleEndian.PutUint32(body[12:16], uint32(len(pkt.data)))
+	binary.LittleEndian.PutUint32(body[16:20], uint32(pkt.length))
+	body = append(body, pkt.data...)
+	body = append(body, make([]byte, pcapngPadded(len(pkt.data))-len(pkt.data))...)
+
+	return p.writeBlock(pcapngEnhancedPacket, append(body, opts...))
+}
+
+// MARK: WriteStats
+// Records the capture window and the number of packets dropped because the reader fell behind
+func (p *pcapngWriter) WriteStats(start, end time.Time, dropped uint64) error {
+	var opts []byte
+	opts = appendPcapngOption(opts, pcapngOptIsbStartTime, pcapngTimestamp(start))
+	opts = appendPcapngOption(opts, pcapngOptIsbEndTime, pcapngTimestamp(end))
+	droppedBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(droppedBytes, dropped)
+	opts = appendPcapngOption(opts, pcapngOptIsbIfDrop, droppedBytes)
+	opts = appendPcapngOption(opts, pcapngOptEnd, nil)
+
+	body := make([]byte, 12, 12+len(opts))
+	timestamp := uint64(end.UnixNano())
+	binary.LittleEndian.PutUint32(body[0:4], 0)
+	binary.LittleEndian.PutUint32(body[4:8], uint32(timestamp>>32))
+	binary.LittleEndian.PutUint32(body[8:12], uint32(timestamp))
+
+	return p.writeBlock(pcapngInterfaceStats, append(body, opts...))
+}
+
+// MARK: Flush
+// Sends buffered blocks to the destination, flushing it too when it supports that
+func (p *pcapngWriter) Flush() error {
+	if err := p.out.Flush(); err != nil {
+		return err
+	}
+	if flusher, ok := p.dest.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// MARK: writeBlock
+// Frames a block body with its type and leading and trailing lengths
+func (p *pcapngWriter) writeBlock(blockType uint32, body []byte) error {
+	header := make([]byte, 8)
+	trailer := make([]byte, 4)
+	total := uint32(12 + len(body))
+	binary.LittleEndian.PutUint32(header[0:4], blockType)
+	binary.LittleEndian.PutUint32(header[4:8], total)
+	binary.LittleEndian.PutUint32(trailer, total)
+
+	for _, part := range [][]byte{header, body, trailer} {
+		if _, err := p.out.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MARK: appendPcapngOption
+// Appends a padded option to an option list
+func appendPcapngOption(opts []byte, code uint16, value []byte) []byte {
+	header := make([]byte, 4)
+	binary.LittleEndian.PutUint16(header[0:2], code)
+	binary.LittleEndian.PutUint16(header[2:4], uint16(len(value)))
+	opts = append(opts, header...)
+	opts = append(opts, value...)
+	return append(opts, make([]byte, pcapngPadded(len(value))-len(value))...)
+}
+
+// MARK: pcapngTimestamp
+// Encodes a time in the nanosecond resolution declared for the interface
+func pcapngTimestamp(t time.Time) []byte {
+	value := make([]byte, 8)
+	timestamp := uint64(t.UnixNano())
+	binary.LittleEndian.PutUint32(value[0:4], uint32(timestamp>>32))
+	binary.LittleEndian.PutUint32(value[4:8], uint32(timestamp))
+	return value
+}
+
+// MARK: pcapngPadded
+// Rounds a length up to the 32-bit alignment pcapng requires
+func pcapngPadded(length int) int {
+	return (length + 3) &^ 3
+}
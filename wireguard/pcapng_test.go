@@ -0,0 +1,209 @@
+package wireguard
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+type testPcapngBlock struct {
+	blockType uint32
+	body      []byte
+}
+
+// readTestPcapngBlocks splits a pcapng stream into blocks, checking the framing of each
+func readTestPcapngBlocks(t *testing.T, data []byte) []testPcapngBlock {
+	t.Helper()
+
+	var blocks []testPcapngBlock
+	for len(data) > 0 {
+		if len(data) < 12 {
+			t.Fatalf("trailing %d bytes do not form a block", len(data))
+		}
+		blockType := binary.LittleEndian.Uint32(data[0:4])
+		total := int(binary.LittleEndian.Uint32(data[4:8]))
+		if total%4 != 0 || total < 12 || total > len(data) {
+			t.Fatalf("block 0x%x has invalid length %d", blockType, total)
+		}
+		if trailer := int(binary.LittleEndian.Uint32(data[total-4 : total])); trailer != total {
+			t.Fatalf("block 0x%x trailing length %d, leading %d", blockType, trailer, total)
+		}
+		blocks = append(blocks, testPcapngBlock{blockType: blockType, body: data[8 : total-4]})
+		data = data[total:]
+	}
+	return blocks
+}
+
+// testPcapngOptions decodes an option list into a map of option code to value
+func testPcapngOptions(t *testing.T, opts []byte) map[uint16][]byte {
+	t.Helper()
+
+	values := make(map[uint16][]byte)
+	for len(opts) >= 4 {
+		code := binary.LittleEndian.Uint16(opts[0:2])
+		length := int(binary.LittleEndian.Uint16(opts[2:4]))
+		if code == pcapngOptEnd {
+			return values
+		}
+		if 4+pcapngPadded(length) > len(opts) {
+			t.Fatalf("option %d overruns the block", code)
+		}
+		values[code] = opts[4 : 4+length]
+		opts = opts[4+pcapngPadded(length):]
+	}
+	t.Fatal("option list is missing its end marker")
+	return nil
+}
+
+func TestPcapngWriter(t *testing.T) {
+	var out bytes.Buffer
+	writer, err := newPcapngWriter(&out, "wg0", captureSnapLen)
+	if err != nil {
+		t.Fatalf("newPcapngWriter: %v", err)
+	}
+
+	start := time.Unix(1700000000, 123456789)
+	packets := []capturedPacket{
+		{data: []byte{0x45, 0, 0, 20, 1}, length: 5, direction: CaptureInbound, timestamp: start},
+		{data: []byte{0x60, 0, 0, 0}, length: 1500, direction: CaptureOutbound, timestamp: start.Add(time.Second)},
+	}
+	for _, pkt := range packets {
+		if err := writer.WritePacket(pkt); err != nil {
+			t.Fatalf("WritePacket: %v", err)
+		}
+	}
+	if err := writer.WriteStats(start, start.Add(2*time.Second), 7); err != nil {
+		t.Fatalf("WriteStats: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	blocks := readTestPcapngBlocks(t, out.Bytes())
+	wantTypes := []uint32{pcapngSectionHeader, pcapngInterfaceDesc, pcapngEnhancedPacket, pcapngEnhancedPacket, pcapngInterfaceStats}
+	if len(blocks) != len(wantTypes) {
+		t.Fatalf("got %d blocks, want %d", len(blocks), len(wantTypes))
+	}
+	for i, block := range blocks {
+		if block.blockType != wantTypes[i] {
+			t.Fatalf("block %d type 0x%x, want 0x%x", i, block.blockType, wantTypes[i])
+		}
+	}
+
+	shb := blocks[0].body
+	if magic := binary.LittleEndian.Uint32(shb[0:4]); magic != pcapngByteOrderMagic {
+		t.Errorf("byte order magic 0x%x", magic)
+	}
+	if appl := testPcapngOptions(t, shb[16:])[pcapngOptShbUserAppl]; string(appl) != "FinGuard" {
+		t.Errorf("user application %q", appl)
+	}
+
+	idb := blocks[1].body
+	if linkType := binary.LittleEndian.Uint16(idb[0:2]); linkType != pcapngLinkTypeRaw {
+		t.Errorf("link type %d, want %d", linkType, pcapngLinkTypeRaw)
+	}
+	if snapLen := binary.LittleEndian.Uint32(idb[4:8]); snapLen != captureSnapLen {
+		t.Errorf("snap length %d, want %d", snapLen, captureSnapLen)
+	}
+	idbOpts := testPcapngOptions(t, idb[8:])
+	if name := idbOpts[pcapngOptIfName]; string(name) != "wg0" {
+		t.Errorf("interface name %q", name)
+	}
+	if resol := idbOpts[pcapngOptIfTsResol]; !bytes.Equal(resol, []byte{pcapngTimestampUnitsExp}) {
+		t.Errorf("timestamp resolution %v", resol)
+	}
+
+	for i, pkt := range packets {
+		epb := blocks[2+i].body
+		timestamp := uint64(binary.LittleEndian.Uint32(epb[4:8]))<<32 | uint64(binary.LittleEndian.Uint32(epb[8:12]))
+		if timestamp != uint64(pkt.timestamp.UnixNano()) {
+			t.Errorf("packet %d timestamp %d, want %d", i, timestamp, pkt.timestamp.UnixNano())
+		}
+		captured := int(binary.LittleEndian.Uint32(epb[12:16]))
+		if captured != len(pkt.data) {
+			t.Errorf("packet %d captured length %d, want %d", i, captured, len(pkt.data))
+		}
+		if original := int(binary.LittleEndian.Uint32(epb[16:20])); original != pkt.length {
+			t.Errorf("packet %d original length %d, want %d", i, original, pkt.length)
+		}
+		if data := epb[20 : 20+captured]; !bytes.Equal(data, pkt.data) {
+			t.Errorf("packet %d data %v, want %v", i, data, pkt.data)
+		}
+
+		wantFlags := uint32(pcapngFlagOutbound)
+		if pkt.direction == CaptureInbound {
+			wantFlags = pcapngFlagInbound
+		}
+		flags := testPcapngOptions(t, epb[20+pcapngPadded(captured):])[pcapngOptEpbFlags]
+		if len(flags) != 4 || binary.LittleEndian.Uint32(flags) != wantFlags {
+			t.Errorf("packet %d flags %v, want %d", i, flags, wantFlags)
+		}
+	}
+
+	isbOpts := testPcapngOptions(t, blocks[4].body[12:])
+	if dropped := isbOpts[pcapngOptIsbIfDrop]; len(dropped) != 8 || binary.LittleEndian.Uint64(dropped) != 7 {
+		t.Errorf("dropped count %v, want 7", dropped)
+	}
+	if !bytes.Equal(isbOpts[pcapngOptIsbStartTime], pcapngTimestamp(start)) {
+		t.Errorf("start time %v", isbOpts[pcapngOptIsbStartTime])
+	}
+}
+
+func TestPcapngPadded(t *testing.T) {
+	tests := []struct {
+		length int
+		want   int
+	}{
+		{0, 0},
+		{1, 4},
+		{3, 4},
+		{4, 4},
+		{5, 8},
+		{1500, 1500},
+		{1501, 1504},
+	}
+
+	for _, tt := range tests {
+		if got := pcapngPadded(tt.length); got != tt.want {
+			t.Errorf("pcapngPadded(%d) = %d, want %d", tt.length, got, tt.want)
+		}
+	}
+}
+
+func TestCaptureOptionsMatches(t *testing.T) {
+	// IPv4 TCP from 10.0.0.2:40000 to 192.168.1.10:8096
+	packet := []byte{
+		0x45, 0, 0, 24, 0, 0, 0, 0, 64, ipProtoTCP, 0, 0,
+		10, 0, 0, 2,
+		192, 168, 1, 10,
+		0x9c, 0x40, 0x1f, 0xa0,
+	}
+
+	tests := []struct {
+		name   string
+		opts   CaptureOptions
+		packet []byte
+		want   bool
+	}{
+		{name: "no filter", opts: CaptureOptions{}, packet: packet, want: true},
+		{name: "no filter keeps unparseable packets", opts: CaptureOptions{}, packet: []byte{0}, want: true},
+		{name: "source host", opts: CaptureOptions{Host: netip.MustParseAddr("10.0.0.2")}, packet: packet, want: true},
+		{name: "destination host", opts: CaptureOptions{Host: netip.MustParseAddr("192.168.1.10")}, packet: packet, want: true},
+		{name: "other host", opts: CaptureOptions{Host: netip.MustParseAddr("10.0.0.3")}, packet: packet, want: false},
+		{name: "destination port", opts: CaptureOptions{Port: 8096}, packet: packet, want: true},
+		{name: "source port", opts: CaptureOptions{Port: 40000}, packet: packet, want: true},
+		{name: "other port", opts: CaptureOptions{Port: 443}, packet: packet, want: false},
+		{name: "host and port", opts: CaptureOptions{Host: netip.MustParseAddr("10.0.0.2"), Port: 443}, packet: packet, want: false},
+		{name: "filter drops unparseable packets", opts: CaptureOptions{Port: 8096}, packet: []byte{0}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.matches(tt.packet); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
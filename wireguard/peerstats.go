@@ -0,0 +1,218 @@
+package wireguard
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JPKribs/FinGuard/config"
+)
+
+const (
+	PeerHealthHealthy    = "healthy"
+	PeerHealthStale      = "stale"
+	PeerHealthConnecting = "connecting"
+	PeerHealthUnknown    = "unknown"
+)
+
+// Peer status assembly functions
+
+// MARK: mergePeerStatuses
+// Combines configured peers with live device statistics, keyed by base64 public key
+func mergePeerStatuses(peers []config.PeerConfig, live map[string]PeerStatus, supervisors map[string]SupervisorStatus, clock *peerClock, staleTimeout time.Duration) []PeerStatus {
+	statuses := make([]PeerStatus, 0, len(peers))
+	now := time.Now()
+
+	for _, peer := range peers {
+		key := strings.TrimSpace(peer.PublicKey)
+
+		status, ok := live[key]
+		if !ok {
+			status = PeerStatus{
+				PublicKey: key,
+				Endpoint:  peer.Endpoint,
+				Health:    PeerHealthUnknown,
+			}
+		} else {
+			status.Health = peerHealth(status.LastHandshake, clock.firstSeen(key, now), staleTimeout, now)
+		}
+
+		status.Name = peer.Name
+		status.AllowedIPs = peer.AllowedIPs
+		if supervisor, ok := supervisors[key]; ok {
+			status.ReconnectAttempts = supervisor.Attempts
+			status.Supervisor = &supervisor
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+// MARK: peerHealth
+// Derives a health state from the age of the last handshake, or of the peer itself when it has never completed one
+func peerHealth(lastHandshake *time.Time, firstSeen time.Time, staleTimeout time.Duration, now time.Time) string {
+	if lastHandshake == nil {
+		// A peer that never answers within the timeout is as dead as one that stopped answering
+		if now.Sub(firstSeen) > staleTimeout {
+			return PeerHealthStale
+		}
+		return PeerHealthConnecting
+	}
+
+	if now.Sub(*lastHandshake) > staleTimeout {
+		return PeerHealthStale
+	}
+
+	return PeerHealthHealthy
+}
+
+// MARK: firstSeen
+// Returns when a peer was first reported since the tunnel started, recording now for new peers
+func (c *peerClock) firstSeen(key string, now time.Time) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.since == nil {
+		c.since = make(map[string]time.Time)
+	}
+	if since, ok := c.since[key]; ok {
+		return since
+	}
+	c.since[key] = now
+	return now
+}
+
+// MARK: reset
+// Forgets every peer so ages restart from the next tunnel start
+func (c *peerClock) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.since = nil
+}
+
+// MARK: staleTimeoutFor
+// Returns the configured stale connection timeout or the default
+func staleTimeoutFor(cfg config.TunnelConfig) time.Duration {
+	staleTimeout := time.Duration(cfg.StaleConnectionTimeout) * time.Second
+	if staleTimeout <= 0 {
+		staleTimeout = defaultStaleTimeout
+	}
+	return staleTimeout
+}
+
+// MARK: handshakeTime
+// Converts a unix handshake timestamp to a time, treating zero as never
+func handshakeTime(sec, nsec int64) *time.Time {
+	if sec <= 0 {
+		return nil
+	}
+
+	t := time.Unix(sec, nsec)
+	return &t
+}
+
+// Peer status parsing functions
+
+// MARK: parseUAPIPeerStats
+// Parses the UAPI get operation output into peer statistics keyed by base64 public key
+func parseUAPIPeerStats(output string) map[string]PeerStatus {
+	stats := make(map[string]PeerStatus)
+
+	var current *PeerStatus
+	var handshakeSec, handshakeNsec int64
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.LastHandshake = handshakeTime(handshakeSec, handshakeNsec)
+		stats[current.PublicKey] = *current
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !found {
+			continue
+		}
+
+		if key == "public_key" {
+			flush()
+			current = &PeerStatus{PublicKey: hexToBase64(value)}
+			handshakeSec, handshakeNsec = 0, 0
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		switch key {
+		case "endpoint":
+			current.Endpoint = value
+		case "allowed_ip":
+			current.AllowedIPs = append(current.AllowedIPs, value)
+		case "last_handshake_time_sec":
+			handshakeSec, _ = strconv.ParseInt(value, 10, 64)
+		case "last_handshake_time_nsec":
+			handshakeNsec, _ = strconv.ParseInt(value, 10, 64)
+		case "rx_bytes":
+			current.RxBytes, _ = strconv.ParseInt(value, 10, 64)
+		case "tx_bytes":
+			current.TxBytes, _ = strconv.ParseInt(value, 10, 64)
+		case "persistent_keepalive_interval":
+			current.PersistentKeepalive, _ = strconv.Atoi(value)
+		}
+	}
+	flush()
+
+	return stats
+}
+
+// MARK: parseWgDump
+// Parses `wg show <interface> dump` output into peer statistics keyed by base64 public key
+func parseWgDump(output string) map[string]PeerStatus {
+	stats := make(map[string]PeerStatus)
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	for i, line := range lines {
+		// The first line describes the interface itself
+		if i == 0 {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < 8 {
+			continue
+		}
+
+		status := PeerStatus{PublicKey: fields[0]}
+		if fields[2] != "(none)" {
+			status.Endpoint = fields[2]
+		}
+
+		handshakeSec, _ := strconv.ParseInt(fields[4], 10, 64)
+		status.LastHandshake = handshakeTime(handshakeSec, 0)
+		status.RxBytes, _ = strconv.ParseInt(fields[5], 10, 64)
+		status.TxBytes, _ = strconv.ParseInt(fields[6], 10, 64)
+		status.PersistentKeepalive, _ = strconv.Atoi(fields[7])
+
+		stats[status.PublicKey] = status
+	}
+
+	return stats
+}
+
+// MARK: hexToBase64
+// Converts a hexadecimal WireGuard key back to its base64 form
+func hexToBase64(hexKey string) string {
+	decoded, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return hexKey
+	}
+	return base64.StdEncoding.EncodeToString(decoded)
+}
@@ -0,0 +1,129 @@
+package wireguard
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	testPeerKeyA    = "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE="
+	testPeerKeyAHex = "0101010101010101010101010101010101010101010101010101010101010101"
+	testPeerKeyB    = "AgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgI="
+	testPeerKeyBHex = "0202020202020202020202020202020202020202020202020202020202020202"
+)
+
+func TestParseUAPIPeerStats(t *testing.T) {
+	handshake := time.Unix(1700000000, 500)
+
+	tests := []struct {
+		name   string
+		output string
+		want   map[string]PeerStatus
+	}{
+		{
+			name:   "empty",
+			output: "",
+			want:   map[string]PeerStatus{},
+		},
+		{
+			name: "interface keys before the first peer are ignored",
+			output: "private_key=" + testPeerKeyAHex + "\n" +
+				"listen_port=51820\n" +
+				"errno=0\n",
+			want: map[string]PeerStatus{},
+		},
+		{
+			name: "two peers",
+			output: "private_key=" + testPeerKeyBHex + "\n" +
+				"public_key=" + testPeerKeyAHex + "\n" +
+				"endpoint=203.0.113.5:51820\n" +
+				"allowed_ip=10.0.0.2/32\n" +
+				"allowed_ip=fd00::2/128\n" +
+				"last_handshake_time_sec=1700000000\n" +
+				"last_handshake_time_nsec=500\n" +
+				"rx_bytes=1024\n" +
+				"tx_bytes=2048\n" +
+				"persistent_keepalive_interval=25\n" +
+				"public_key=" + testPeerKeyBHex + "\n" +
+				"allowed_ip=10.0.0.3/32\n" +
+				"last_handshake_time_sec=0\n" +
+				"last_handshake_time_nsec=0\n" +
+				"rx_bytes=0\n" +
+				"tx_bytes=0\n" +
+				"errno=0\n",
+			want: map[string]PeerStatus{
+				testPeerKeyA: {
+					PublicKey:           testPeerKeyA,
+					Endpoint:            "203.0.113.5:51820",
+					AllowedIPs:          []string{"10.0.0.2/32", "fd00::2/128"},
+					LastHandshake:       &handshake,
+					RxBytes:             1024,
+					TxBytes:             2048,
+					PersistentKeepalive: 25,
+				},
+				testPeerKeyB: {
+					PublicKey:  testPeerKeyB,
+					AllowedIPs: []string{"10.0.0.3/32"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseUAPIPeerStats(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWgDump(t *testing.T) {
+	handshake := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name   string
+		output string
+		want   map[string]PeerStatus
+	}{
+		{
+			name:   "interface only",
+			output: "privkey\tpubkey\t51820\toff\n",
+			want:   map[string]PeerStatus{},
+		},
+		{
+			name: "peers",
+			output: "privkey\tpubkey\t51820\toff\n" +
+				testPeerKeyA + "\t(none)\t203.0.113.5:51820\t10.0.0.2/32\t1700000000\t1024\t2048\t25\n" +
+				testPeerKeyB + "\t(none)\t(none)\t10.0.0.3/32\t0\t0\t0\toff\n",
+			want: map[string]PeerStatus{
+				testPeerKeyA: {
+					PublicKey:           testPeerKeyA,
+					Endpoint:            "203.0.113.5:51820",
+					LastHandshake:       &handshake,
+					RxBytes:             1024,
+					TxBytes:             2048,
+					PersistentKeepalive: 25,
+				},
+				testPeerKeyB: {
+					PublicKey: testPeerKeyB,
+				},
+			},
+		},
+		{
+			name: "short lines are skipped",
+			output: "privkey\tpubkey\t51820\toff\n" +
+				testPeerKeyA + "\t(none)\t(none)\n",
+			want: map[string]PeerStatus{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseWgDump(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,438 @@
+package wireguard
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/JPKribs/FinGuard/config"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	probeWindow       = 10
+	probeReadBuffer   = 1500
+	probeBodyLimit    = 4096
+	icmpProtocolIPv4  = 1
+	icmpProtocolIPv6  = 58
+	probeTokenLength  = 8
+	probeHTTPMaxError = http.StatusInternalServerError
+)
+
+var probeSequence uint32
+
+// Prober lifecycle functions
+
+// MARK: startProber
+// Starts reachability probes for a tunnel, replacing any probes already running for it
+func (m *Manager) startProber(cfg config.TunnelConfig) {
+	m.stopProber(cfg.Name)
+
+	if len(cfg.Probes) == 0 || m.ctx == nil {
+		return
+	}
+
+	interval := time.Duration(cfg.MonitorInterval) * time.Second
+	if interval <= 0 {
+		interval = defaultMonitorInterval
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	prober := &tunnelProber{
+		tunnel:   cfg.Name,
+		interval: interval,
+		probes:   make([]*probeState, 0, len(cfg.Probes)),
+		cancel:   cancel,
+	}
+
+	for _, probe := range cfg.Probes {
+		threshold := probe.FailureThreshold
+		if threshold <= 0 {
+			threshold = config.DefaultProbeFailureThreshold
+		}
+
+		prober.probes = append(prober.probes, &probeState{
+			config:    probe,
+			threshold: threshold,
+			status: ProbeStatus{
+				Name:   probe.Label(),
+				Type:   strings.ToLower(probe.Type),
+				Target: probe.Target,
+			},
+		})
+	}
+
+	m.probeMu.Lock()
+	m.probers[cfg.Name] = prober
+	m.probeMu.Unlock()
+
+	m.logger.Info("Started reachability probes", "tunnel", cfg.Name, "probes", len(prober.probes), "interval", interval)
+	go m.runProber(ctx, prober)
+}
+
+// MARK: stopProber
+// Stops the reachability probes for a tunnel
+func (m *Manager) stopProber(name string) {
+	m.probeMu.Lock()
+	prober, exists := m.probers[name]
+	delete(m.probers, name)
+	m.probeMu.Unlock()
+
+	if exists {
+		prober.cancel()
+	}
+}
+
+// MARK: stopProbers
+// Stops the reachability probes for every tunnel
+func (m *Manager) stopProbers() {
+	m.probeMu.Lock()
+	probers := m.probers
+	m.probers = make(map[string]*tunnelProber)
+	m.probeMu.Unlock()
+
+	for _, prober := range probers {
+		prober.cancel()
+	}
+}
+
+// MARK: getProber
+func (m *Manager) getProber(name string) *tunnelProber {
+	m.probeMu.Lock()
+	defer m.probeMu.Unlock()
+	return m.probers[name]
+}
+
+// MARK: runProber
+// Runs every probe of a tunnel on its monitor interval until cancelled
+func (m *Manager) runProber(ctx context.Context, prober *tunnelProber) {
+	ticker := time.NewTicker(prober.interval)
+	defer ticker.Stop()
+
+	for {
+		for _, state := range prober.probes {
+			latency, err := m.runProbe(ctx, prober.tunnel, state.config)
+			if ctx.Err() != nil {
+				return
+			}
+
+			prober.record(state, latency, err)
+			if err != nil {
+				m.logger.Debug("Reachability probe failed", "tunnel", prober.tunnel, "probe", state.status.Name, "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Probe result functions
+
+// MARK: record
+// Stores a probe result and recomputes latency, loss and health over the sample window
+func (p *tunnelProber) record(state *probeState, latency time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	state.samples = append(state.samples, probeSample{ok: err == nil, latency: latency})
+	if len(state.samples) > probeWindow {
+		state.samples = state.samples[len(state.samples)-probeWindow:]
+	}
+
+	status := &state.status
+	status.LastRun = &now
+
+	if err == nil {
+		status.LatencyMs = durationMs(latency)
+		status.ConsecutiveFailures = 0
+		status.LastError = ""
+	} else {
+		status.ConsecutiveFailures++
+		status.LastError = err.Error()
+	}
+
+	var failures int
+	var total time.Duration
+	for _, sample := range state.samples {
+		if !sample.ok {
+			failures++
+			continue
+		}
+		total += sample.latency
+	}
+
+	status.LossPercent = float64(failures) * 100 / float64(len(state.samples))
+	status.AvgLatencyMs = 0
+	if successes := len(state.samples) - failures; successes > 0 {
+		status.AvgLatencyMs = durationMs(total / time.Duration(successes))
+	}
+
+	status.Healthy = status.ConsecutiveFailures < state.threshold
+}
+
+// MARK: statuses
+// Returns a snapshot of probe statuses and whether every probe that has run is healthy
+func (p *tunnelProber) statuses() ([]ProbeStatus, *bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	statuses := make([]ProbeStatus, 0, len(p.probes))
+	var reachable *bool
+
+	for _, state := range p.probes {
+		statuses = append(statuses, state.status)
+
+		if state.status.LastRun == nil {
+			continue
+		}
+		healthy := state.status.Healthy && (reachable == nil || *reachable)
+		reachable = &healthy
+	}
+
+	return statuses, reachable
+}
+
+// MARK: failing
+// Checks if any probe has reached its consecutive failure threshold
+func (p *tunnelProber) failing() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, state := range p.probes {
+		if state.status.ConsecutiveFailures >= state.threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// MARK: resetFailures
+// Clears consecutive failures so a restarted tunnel gets a full threshold before the next restart
+func (p *tunnelProber) resetFailures() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, state := range p.probes {
+		state.status.ConsecutiveFailures = 0
+	}
+}
+
+// MARK: attachProbeStatus
+// Adds probe results to a tunnel status
+func (m *Manager) attachProbeStatus(status *TunnelStatus) {
+	prober := m.getProber(status.Name)
+	if prober == nil {
+		return
+	}
+
+	status.Probes, status.Reachable = prober.statuses()
+}
+
+// MARK: durationMs
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// Probe execution functions
+
+// MARK: runProbe
+// Runs a single probe against its target through the tunnel and returns the round trip latency
+func (m *Manager) runProbe(ctx context.Context, tunnelName string, probe config.ProbeConfig) (time.Duration, error) {
+	timeout := time.Duration(probe.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = config.DefaultProbeTimeout * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	var err error
+	switch strings.ToLower(probe.Type) {
+	case config.ProbeTypeICMP:
+		err = m.probeICMP(ctx, tunnelName, probe.Target)
+	case config.ProbeTypeTCP:
+		err = m.probeTCP(ctx, tunnelName, probe.Target)
+	case config.ProbeTypeHTTP:
+		err = m.probeHTTP(ctx, tunnelName, probe.Target)
+	default:
+		err = fmt.Errorf("unsupported probe type: %s", probe.Type)
+	}
+
+	return time.Since(start), err
+}
+
+// MARK: probeTCP
+// Checks that a TCP connection to the target can be established
+func (m *Manager) probeTCP(ctx context.Context, tunnelName, target string) error {
+	conn, err := m.DialContext(ctx, tunnelName, "tcp", target)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// MARK: probeHTTP
+// Checks that the target answers an HTTP GET without a server error
+func (m *Manager) probeHTTP(ctx context.Context, tunnelName, target string) error {
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return m.DialContext(ctx, tunnelName, network, address)
+			},
+			// Probes only check reachability, services behind peers commonly use self-signed certificates
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "FinGuard-Probe")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, probeBodyLimit))
+
+	if resp.StatusCode >= probeHTTPMaxError {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// MARK: probeICMP
+// Sends an ICMP echo request to the target and waits for the matching reply
+func (m *Manager) probeICMP(ctx context.Context, tunnelName, target string) error {
+	addr, err := netip.ParseAddr(target)
+	if err != nil {
+		return fmt.Errorf("invalid icmp target %s: %w", target, err)
+	}
+	addr = addr.Unmap()
+
+	conn, dst, err := m.dialPing(tunnelName, addr)
+	if err != nil {
+		return fmt.Errorf("opening icmp socket: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+	}
+
+	token := make([]byte, probeTokenLength)
+	rand.Read(token)
+
+	request := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Body: &icmp.Echo{
+			ID:   os.Getpid() & 0xffff,
+			Seq:  int(atomic.AddUint32(&probeSequence, 1) & 0xffff),
+			Data: token,
+		},
+	}
+	protocol := icmpProtocolIPv4
+	var replyType icmp.Type = ipv4.ICMPTypeEchoReply
+	if addr.Is6() {
+		request.Type = ipv6.ICMPTypeEchoRequest
+		protocol = icmpProtocolIPv6
+		replyType = ipv6.ICMPTypeEchoReply
+	}
+
+	packet, err := request.Marshal(nil)
+	if err != nil {
+		return fmt.Errorf("building echo request: %w", err)
+	}
+
+	if _, err := conn.WriteTo(packet, dst); err != nil {
+		return fmt.Errorf("sending echo request: %w", err)
+	}
+
+	buf := make([]byte, probeReadBuffer)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("no echo reply: %w", ctx.Err())
+			}
+			return fmt.Errorf("reading echo reply: %w", err)
+		}
+
+		reply, err := icmp.ParseMessage(protocol, buf[:n])
+		if err != nil || reply.Type != replyType {
+			continue
+		}
+
+		// Kernel and netstack ping sockets rewrite the echo ID, so match on the payload instead
+		if echo, ok := reply.Body.(*icmp.Echo); ok && bytes.Equal(echo.Data, token) {
+			return nil
+		}
+	}
+}
+
+// MARK: dialPing
+// Opens an ICMP socket in the tunnel's network stack, or on the host when the tunnel uses host routing
+func (m *Manager) dialPing(tunnelName string, target netip.Addr) (net.PacketConn, net.Addr, error) {
+	ipAddr := &net.IPAddr{IP: target.AsSlice()}
+
+	if m.usesTunnelStack() {
+		m.mu.RLock()
+		tunnel, exists := m.tunnels[tunnelName]
+		m.mu.RUnlock()
+
+		if !exists {
+			return nil, nil, fmt.Errorf("tunnel %s not found", tunnelName)
+		}
+
+		pinger, ok := tunnel.(TunnelPinger)
+		if !ok {
+			return nil, nil, fmt.Errorf("tunnel %s does not support icmp", tunnelName)
+		}
+
+		conn, err := pinger.DialPing(target)
+		return conn, ipAddr, err
+	}
+
+	rawNetwork, rawAddress, udpNetwork := "ip4:icmp", "0.0.0.0", "udp4"
+	if target.Is6() {
+		rawNetwork, rawAddress, udpNetwork = "ip6:ipv6-icmp", "::", "udp6"
+	}
+
+	// Raw sockets need CAP_NET_RAW, unprivileged ping sockets need net.ipv4.ping_group_range
+	if conn, err := icmp.ListenPacket(rawNetwork, rawAddress); err == nil {
+		return conn, ipAddr, nil
+	}
+
+	conn, err := icmp.ListenPacket(udpNetwork, rawAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conn, &net.UDPAddr{IP: target.AsSlice()}, nil
+}
@@ -0,0 +1,79 @@
+package wireguard
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/JPKribs/FinGuard/config"
+)
+
+// Peer provisioning functions
+
+// MARK: ProvisionPeer
+// Generates keys and an address for a new client of a server-mode tunnel, returning the
+// server-side peer entry and the client's wg-quick config
+func ProvisionPeer(cfg config.TunnelConfig, name string) (config.PeerConfig, string, error) {
+	if cfg.Server == nil {
+		return config.PeerConfig{}, "", fmt.Errorf("tunnel %s is not in server mode", cfg.Name)
+	}
+
+	serverPublicKey, err := PublicKey(cfg.PrivateKey)
+	if err != nil {
+		return config.PeerConfig{}, "", err
+	}
+
+	address, err := AllocatePeerAddress(cfg)
+	if err != nil {
+		return config.PeerConfig{}, "", err
+	}
+
+	clientPrivateKey, clientPublicKey, err := GeneratePrivateKey()
+	if err != nil {
+		return config.PeerConfig{}, "", err
+	}
+
+	presharedKey, err := GeneratePresharedKey()
+	if err != nil {
+		return config.PeerConfig{}, "", err
+	}
+
+	peer := config.PeerConfig{
+		Name:       name,
+		PublicKey:  clientPublicKey,
+		AllowedIPs: []string{address},
+		Preshared:  presharedKey,
+	}
+
+	clientConfig := config.TunnelConfig{
+		Name:       name,
+		PrivateKey: clientPrivateKey,
+		MTU:        cfg.MTU,
+		Addresses:  []string{address},
+		DNS:        cfg.Server.ClientDNS,
+		Peers: []config.PeerConfig{{
+			Name:                   cfg.Name,
+			PublicKey:              serverPublicKey,
+			Preshared:              presharedKey,
+			AllowedIPs:             clientAllowedIPs(cfg.Server),
+			Endpoint:               cfg.Server.Endpoint,
+			Persistent:             true,
+			PersistentKeepaliveInt: cfg.Server.PersistentKeepalive,
+		}},
+	}
+
+	return peer, config.FormatWgQuickConfig(clientConfig), nil
+}
+
+// MARK: clientAllowedIPs
+// Returns the networks clients route through the tunnel, defaulting to the address pool
+func clientAllowedIPs(server *config.TunnelServerConfig) []string {
+	if len(server.ClientAllowedIPs) > 0 {
+		return server.ClientAllowedIPs
+	}
+
+	_, pool, err := net.ParseCIDR(server.AddressPool)
+	if err != nil {
+		return []string{server.AddressPool}
+	}
+	return []string{pool.String()}
+}
@@ -0,0 +1,341 @@
+package wireguard
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/JPKribs/FinGuard/config"
+)
+
+const (
+	StateStarting   SupervisorState = "starting"
+	StateUp         SupervisorState = "up"
+	StateDegraded   SupervisorState = "degraded"
+	StateBackingOff SupervisorState = "backing-off"
+	StateFailed     SupervisorState = "failed"
+
+	backoffBase       = 5 * time.Second
+	backoffMax        = 5 * time.Minute
+	backoffJitter     = 0.2
+	backoffResetAfter = 15 * time.Minute
+)
+
+// MARK: newSupervisor
+// Creates a supervisor in the starting state that is marked failed after budget attempts
+func newSupervisor(budget int) *supervisor {
+	return &supervisor{
+		state:  StateStarting,
+		budget: budget,
+		since:  time.Now(),
+	}
+}
+
+// MARK: setState
+// Moves to a new state, recording when the transition happened, called with the lock held
+func (s *supervisor) setState(state SupervisorState, now time.Time) {
+	if s.state != state {
+		s.state = state
+		s.since = now
+	}
+}
+
+// MARK: starting
+// Marks an attempt to bring the target up as in progress
+func (s *supervisor) starting(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setState(StateStarting, now)
+}
+
+// MARK: up
+// Marks the target healthy and clears its backoff
+func (s *supervisor) up(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.setState(StateUp, now)
+	s.attempts = 0
+	s.nextRetry = time.Time{}
+	s.lastError = ""
+}
+
+// MARK: degraded
+// Marks a running target as impaired without scheduling a retry
+func (s *supervisor) degraded(now time.Time, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Backoff states already describe a target that is being retried
+	if s.state == StateUp || s.state == StateStarting {
+		s.setState(StateDegraded, now)
+	}
+	s.lastError = reason
+}
+
+// MARK: failure
+// Records a failed or about-to-be-retried attempt and schedules the next retry with jittered exponential backoff
+func (s *supervisor) failure(now time.Time, reason string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Attempts decay once the target has gone a while without failing
+	if !s.lastFailure.IsZero() && now.Sub(s.lastFailure) > backoffResetAfter {
+		s.attempts = 0
+	}
+
+	s.attempts++
+	s.lastFailure = now
+	s.lastError = reason
+
+	delay := backoffDelay(s.attempts)
+	s.nextRetry = now.Add(delay)
+
+	if s.budget > 0 && s.attempts >= s.budget {
+		s.setState(StateFailed, now)
+	} else {
+		s.setState(StateBackingOff, now)
+	}
+
+	return delay
+}
+
+// MARK: setError
+// Updates the last error without changing the state or schedule
+func (s *supervisor) setError(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastError = reason
+}
+
+// MARK: setBudget
+// Updates the number of attempts after which the target is reported as failed
+func (s *supervisor) setBudget(budget int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.budget = budget
+}
+
+// MARK: due
+// Checks if the backoff delay has elapsed and another attempt may be made
+func (s *supervisor) due(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextRetry.IsZero() || !now.Before(s.nextRetry)
+}
+
+// MARK: current
+// Returns the current state
+func (s *supervisor) current() SupervisorState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// MARK: attemptCount
+// Returns the number of attempts since the target was last up
+func (s *supervisor) attemptCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts
+}
+
+// MARK: status
+// Returns a snapshot of the supervisor for the API
+func (s *supervisor) status() SupervisorStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := SupervisorStatus{
+		State:     s.state,
+		Attempts:  s.attempts,
+		LastError: s.lastError,
+		Since:     s.since,
+	}
+	if !s.nextRetry.IsZero() && (s.state == StateBackingOff || s.state == StateFailed) {
+		nextRetry := s.nextRetry
+		status.NextRetry = &nextRetry
+	}
+
+	return status
+}
+
+// MARK: backoffDelay
+// Returns the delay before the given attempt, doubling from the base up to the maximum with jitter applied
+func backoffDelay(attempt int) time.Duration {
+	delay := backoffMax
+	if attempt <= 16 {
+		delay = min(backoffBase<<(attempt-1), backoffMax)
+	}
+
+	jitter := 1 + backoffJitter*(2*rand.Float64()-1)
+	return min(time.Duration(float64(delay)*jitter), backoffMax)
+}
+
+// MARK: retryBudget
+// Returns the configured retry budget for a tunnel or the default
+func retryBudget(cfg config.TunnelConfig) int {
+	if cfg.ReconnectionRetries > 0 {
+		return cfg.ReconnectionRetries
+	}
+	return config.DefaultRetries
+}
+
+// MARK: peerRetryBudget
+// Returns the configured per-peer retry budget or the default
+func peerRetryBudget(cfg config.TunnelConfig) int {
+	if cfg.ReconnectionRetries > 0 {
+		return cfg.ReconnectionRetries
+	}
+	return maxReconnectAttempts
+}
+
+// MARK: peerSupervisor
+// Returns the supervisor for a peer, creating it on first use, called with the owner's lock held
+func peerSupervisor(supervisors map[string]*supervisor, peerKey string, budget int) *supervisor {
+	sup, exists := supervisors[peerKey]
+	if !exists {
+		sup = newSupervisor(budget)
+		supervisors[peerKey] = sup
+		return sup
+	}
+	sup.setBudget(budget)
+	return sup
+}
+
+// MARK: supervisorStatuses
+// Snapshots peer supervisors keyed by base64 public key, called with the owner's lock held
+func supervisorStatuses(supervisors map[string]*supervisor, toBase64 func(string) string) map[string]SupervisorStatus {
+	statuses := make(map[string]SupervisorStatus, len(supervisors))
+	for peerKey, sup := range supervisors {
+		if toBase64 != nil {
+			peerKey = toBase64(peerKey)
+		}
+		statuses[peerKey] = sup.status()
+	}
+	return statuses
+}
+
+// MARK: supervisorFor
+// Returns the supervisor for a tunnel, creating it on first use and applying the tunnel's retry budget
+func (m *Manager) supervisorFor(cfg config.TunnelConfig) *supervisor {
+	m.supervisorMu.Lock()
+	defer m.supervisorMu.Unlock()
+
+	sup, exists := m.supervisors[cfg.Name]
+	if !exists {
+		sup = newSupervisor(retryBudget(cfg))
+		m.supervisors[cfg.Name] = sup
+		return sup
+	}
+	sup.setBudget(retryBudget(cfg))
+	return sup
+}
+
+// MARK: getSupervisor
+// Returns the supervisor for a tunnel, or nil if the tunnel is not supervised
+func (m *Manager) getSupervisor(name string) *supervisor {
+	m.supervisorMu.Lock()
+	defer m.supervisorMu.Unlock()
+	return m.supervisors[name]
+}
+
+// MARK: forgetSupervisor
+// Stops supervising a tunnel, reporting whether it was still waiting to be created
+func (m *Manager) forgetSupervisor(name string) bool {
+	m.supervisorMu.Lock()
+	defer m.supervisorMu.Unlock()
+
+	_, pending := m.pending[name]
+	delete(m.supervisors, name)
+	delete(m.pending, name)
+	return pending
+}
+
+// MARK: deferCreate
+// Hands a tunnel that could not be created to the health monitor so creation keeps being retried
+func (m *Manager) deferCreate(cfg config.TunnelConfig, sup *supervisor, err error) {
+	// On-demand tunnels are only started by requests, never in the background
+	if cfg.OnDemand {
+		sup.setError(err.Error())
+		return
+	}
+
+	delay := sup.failure(time.Now(), err.Error())
+
+	m.supervisorMu.Lock()
+	m.pending[cfg.Name] = cfg
+	m.supervisorMu.Unlock()
+
+	m.logger.Warn("Tunnel creation failed, retrying in background", "name", cfg.Name, "next_retry_in", delay)
+}
+
+// MARK: clearPending
+// Removes a tunnel from the set awaiting creation once it is running
+func (m *Manager) clearPending(name string) {
+	m.supervisorMu.Lock()
+	defer m.supervisorMu.Unlock()
+	delete(m.pending, name)
+}
+
+// MARK: pendingConfigs
+// Returns the configurations of tunnels still awaiting creation
+func (m *Manager) pendingConfigs() []config.TunnelConfig {
+	m.supervisorMu.Lock()
+	defer m.supervisorMu.Unlock()
+
+	configs := make([]config.TunnelConfig, 0, len(m.pending))
+	for _, cfg := range m.pending {
+		configs = append(configs, cfg)
+	}
+	return configs
+}
+
+// MARK: pendingStatus
+// Builds the status of a tunnel that is awaiting creation
+func (m *Manager) pendingStatus(name string) (TunnelStatus, bool) {
+	m.supervisorMu.Lock()
+	cfg, pending := m.pending[name]
+	sup := m.supervisors[name]
+	m.supervisorMu.Unlock()
+
+	if !pending {
+		return TunnelStatus{}, false
+	}
+
+	status := TunnelStatus{
+		Name:       cfg.Name,
+		State:      "stopped",
+		MTU:        cfg.MTU,
+		Peers:      len(cfg.Peers),
+		ServerMode: cfg.Server != nil,
+	}
+	status.PublicKey, _ = PublicKey(cfg.PrivateKey)
+	if sup != nil {
+		supervisorStatus := sup.status()
+		status.Supervisor = &supervisorStatus
+		status.Error = supervisorStatus.LastError
+	}
+
+	return status, true
+}
+
+// MARK: pendingStatuses
+// Builds the statuses of all tunnels awaiting creation
+func (m *Manager) pendingStatuses() []TunnelStatus {
+	var statuses []TunnelStatus
+	for _, cfg := range m.pendingConfigs() {
+		if status, pending := m.pendingStatus(cfg.Name); pending {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// MARK: attachSupervisorStatus
+// Adds the supervisor state of a tunnel to its status
+func (m *Manager) attachSupervisorStatus(status *TunnelStatus) {
+	if sup := m.getSupervisor(status.Name); sup != nil {
+		supervisorStatus := sup.status()
+		status.Supervisor = &supervisorStatus
+	}
+}
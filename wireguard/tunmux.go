@@ -0,0 +1,333 @@
+package wireguard
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"golang.zx2c4.com/wireguard/tun"
+)
+
+const (
+	muxQueueSize      = 1024
+	flowIdleTimeout   = 10 * time.Minute
+	flowSweepInterval = time.Minute
+	ipProtoICMP       = 1
+	ipProtoTCP        = 6
+	ipProtoUDP        = 17
+	ipProtoICMPv6     = 58
+	ipv6HopByHop      = 0
+	ipv6Routing       = 43
+	ipv6Fragment      = 44
+	ipv6AuthHeader    = 51
+	ipv6NoNextHeader  = 59
+	ipv6DestOptions   = 60
+)
+
+// TUN multiplexer lifecycle functions
+
+// MARK: NewTUNMux
+// Combines the kernel TUN device and the in-process network stack behind a single tun.Device
+func NewTUNMux(primary, stack tun.Device, mtu int, bufferPool *PacketBufferPool) *TUNMux {
+	if bufferPool == nil {
+		bufferPool = NewPacketBufferPool(bufferPoolSize)
+	}
+
+	m := &TUNMux{
+		primary:    primary,
+		stack:      stack,
+		mtu:        mtu,
+		packets:    make(chan *PacketBuffer, muxQueueSize),
+		events:     make(chan tun.Event, 1),
+		bufferPool: bufferPool,
+		done:       make(chan struct{}),
+	}
+	m.events <- tun.EventUp
+
+	m.wg.Add(4)
+	go m.pump(primary, false)
+	go m.pump(stack, true)
+	go m.forwardEvents()
+	go m.sweepFlows()
+
+	return m
+}
+
+// MARK: Close
+// Closes both underlying devices and stops the packet pumps
+func (m *TUNMux) Close() error {
+	if !atomic.CompareAndSwapInt64(&m.closed, 0, 1) {
+		return nil
+	}
+
+	var errs []error
+	if err := m.stack.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := m.primary.Close(); err != nil {
+		errs = append(errs, err)
+	}
+
+	close(m.done)
+	m.wg.Wait()
+	close(m.events)
+
+	return errors.Join(errs...)
+}
+
+// Packet I/O functions
+
+// MARK: Read
+// Returns packets queued from either device, draining up to len(bufs) without blocking
+func (m *TUNMux) Read(bufs [][]byte, sizes []int, offset int) (int, error) {
+	if atomic.LoadInt64(&m.closed) != 0 || len(bufs) == 0 {
+		return 0, net.ErrClosed
+	}
+
+	var pkt *PacketBuffer
+	select {
+	case pkt = <-m.packets:
+	case <-m.done:
+		return 0, net.ErrClosed
+	}
+
+	count := 0
+	for {
+		sizes[count] = copy(bufs[count][offset:], pkt.data[:pkt.length])
+		m.bufferPool.Put(pkt)
+		count++
+
+		if count == len(bufs) {
+			return count, nil
+		}
+
+		select {
+		case pkt = <-m.packets:
+		default:
+			return count, nil
+		}
+	}
+}
+
+// MARK: Write
+// Delivers packets belonging to stack-owned flows to the network stack and everything else to the TUN device
+func (m *TUNMux) Write(bufs [][]byte, offset int) (int, error) {
+	if atomic.LoadInt64(&m.closed) != 0 {
+		return 0, net.ErrClosed
+	}
+
+	var toStack, toPrimary [][]byte
+	for _, buf := range bufs {
+		if len(buf) <= offset {
+			continue
+		}
+
+		if m.isStackFlow(buf[offset:]) {
+			toStack = append(toStack, buf)
+		} else {
+			toPrimary = append(toPrimary, buf)
+		}
+	}
+
+	if len(toStack) > 0 {
+		if _, err := m.stack.Write(toStack, offset); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(toPrimary) > 0 {
+		if _, err := m.primary.Write(toPrimary, offset); err != nil {
+			return len(toStack), err
+		}
+	}
+
+	return len(bufs), nil
+}
+
+// MARK: pump
+// Continuously reads batches of packets from a device into the shared queue
+func (m *TUNMux) pump(dev tun.Device, fromStack bool) {
+	defer m.wg.Done()
+
+	// A single read from an offload-capable device can split into a full batch of segments
+	batchSize := max(dev.BatchSize(), 1)
+	pkts := make([]*PacketBuffer, batchSize)
+	bufs := make([][]byte, batchSize)
+	sizes := make([]int, batchSize)
+
+	defer func() {
+		for _, pkt := range pkts {
+			m.bufferPool.Put(pkt)
+		}
+	}()
+
+	for {
+		for i := range pkts {
+			if pkts[i] == nil {
+				pkts[i] = m.packetBuffer()
+			}
+			bufs[i] = pkts[i].data
+		}
+
+		n, err := dev.Read(bufs, sizes, 0)
+		if err != nil || n == 0 {
+			if atomic.LoadInt64(&m.closed) != 0 || errors.Is(err, os.ErrClosed) || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		for i := 0; i < n; i++ {
+			pkt := pkts[i]
+			pkts[i] = nil
+			pkt.length = sizes[i]
+
+			if fromStack {
+				m.trackFlow(pkt.data[:pkt.length])
+			}
+
+			select {
+			case m.packets <- pkt:
+			case <-m.done:
+				m.bufferPool.Put(pkt)
+				return
+			}
+		}
+	}
+}
+
+// MARK: packetBuffer
+// Returns a pooled buffer large enough for a packet at the device MTU
+func (m *TUNMux) packetBuffer() *PacketBuffer {
+	pkt := m.bufferPool.Get()
+	if len(pkt.data) < m.mtu {
+		pkt.data = make([]byte, m.mtu)
+	}
+	return pkt
+}
+
+// MARK: forwardEvents
+// Relays link state and MTU changes from the kernel TUN device to the WireGuard device
+func (m *TUNMux) forwardEvents() {
+	defer m.wg.Done()
+
+	events := m.primary.Events()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			select {
+			case m.events <- event:
+			case <-m.done:
+				return
+			}
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// Flow tracking functions
+
+// MARK: trackFlow
+// Records the flow of a packet sent by the network stack so replies are routed back to it
+func (m *TUNMux) trackFlow(packet []byte) {
+	if key, ok := flowKey(packet, true); ok {
+		m.flows.Store(key, time.Now().UnixNano())
+	}
+}
+
+// MARK: isStackFlow
+// Checks if an inbound packet is addressed to a flow opened by the network stack
+func (m *TUNMux) isStackFlow(packet []byte) bool {
+	key, ok := flowKey(packet, false)
+	if !ok {
+		return false
+	}
+
+	_, exists := m.flows.Load(key)
+	return exists
+}
+
+// MARK: sweepFlows
+// Periodically forgets flows that have been idle longer than the flow timeout
+func (m *TUNMux) sweepFlows() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(flowSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case now := <-ticker.C:
+			threshold := now.Add(-flowIdleTimeout).UnixNano()
+			m.flows.Range(func(key, value interface{}) bool {
+				if value.(int64) < threshold {
+					m.flows.Delete(key)
+				}
+				return true
+			})
+		}
+	}
+}
+
+// MARK: flowKey
+// Keys a packet by its protocol, addresses and ports (or ICMP echo identifier) as seen from the local end
+func flowKey(packet []byte, outbound bool) (packetFlow, bool) {
+	header, ok := parsePacketHeader(packet)
+	if !ok {
+		return packetFlow{}, false
+	}
+
+	if outbound {
+		return outboundFlow(header)
+	}
+	return inboundFlow(header)
+}
+
+// MARK: isEchoMessage
+// Checks if an ICMP type is an echo request or reply
+func isEchoMessage(proto, icmpType byte) bool {
+	if proto == ipProtoICMP {
+		return icmpType == 0 || icmpType == 8
+	}
+	return icmpType == 128 || icmpType == 129
+}
+
+// Device property accessor functions
+
+// MARK: File
+// Returns the underlying file for the multiplexed device
+func (m *TUNMux) File() *os.File {
+	return nil
+}
+
+// MARK: MTU
+// Returns the MTU shared by both devices
+func (m *TUNMux) MTU() (int, error) {
+	return m.mtu, nil
+}
+
+// MARK: Name
+// Returns the name of the kernel TUN interface
+func (m *TUNMux) Name() (string, error) {
+	return m.primary.Name()
+}
+
+// MARK: Events
+// Returns a channel for TUN events
+func (m *TUNMux) Events() <-chan tun.Event {
+	return m.events
+}
+
+// MARK: BatchSize
+// Returns the batch size of the kernel TUN device
+func (m *TUNMux) BatchSize() int {
+	return m.primary.BatchSize()
+}
@@ -0,0 +1,49 @@
+//go:build linux
+
+package wireguard
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const capNetAdmin = 12
+
+// MARK: isTUNAvailable
+// Checks if /dev/net/tun can be opened and the process holds CAP_NET_ADMIN, without creating an interface
+func isTUNAvailable() bool {
+	f, err := os.OpenFile("/dev/net/tun", os.O_RDWR, 0)
+	if err != nil {
+		return false
+	}
+	f.Close()
+
+	return hasEffectiveCapability(capNetAdmin)
+}
+
+// MARK: hasEffectiveCapability
+// Reads the effective capability set of the process from /proc
+func hasEffectiveCapability(capability uint) bool {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return os.Geteuid() == 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "CapEff:")
+		if !ok {
+			continue
+		}
+		caps, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		if err != nil {
+			return false
+		}
+		return caps&(1<<capability) != 0
+	}
+
+	return os.Geteuid() == 0
+}
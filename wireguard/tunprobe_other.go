@@ -0,0 +1,11 @@
+//go:build !linux && !windows
+
+package wireguard
+
+import "os"
+
+// MARK: isTUNAvailable
+// Checks if utun interfaces can be created, which requires root
+func isTUNAvailable() bool {
+	return os.Geteuid() == 0
+}
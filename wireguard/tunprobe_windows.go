@@ -0,0 +1,11 @@
+//go:build windows
+
+package wireguard
+
+import "golang.org/x/sys/windows"
+
+// MARK: isTUNAvailable
+// Checks if Wintun adapters can be created, which requires an elevated process
+func isTUNAvailable() bool {
+	return windows.GetCurrentProcessToken().IsElevated()
+}
@@ -9,34 +9,31 @@ import (
 	"strings"
 	"time"
 
-	"github.com/songgao/water"
 	"github.com/vishvananda/netlink"
+	"golang.zx2c4.com/wireguard/tun"
 )
 
 const (
-	configTimeout    = 10 * time.Second
-	maxConfigRetries = 3
-	tunRetryDelay    = 2 * time.Second
+	configTimeout       = 10 * time.Second
+	maxConfigRetries    = 3
+	tunRetryDelay       = 2 * time.Second
+	maxInterfaceNameLen = 15
 )
 
 // TUN device creation and basic configuration
 
 // MARK: CreateTUN
-// Creates and configures a TUN device with the specified name and MTU
+// Creates and configures a TUN device with the specified name and MTU, using vnet headers and offloads where supported
 func CreateTUN(name string, mtu int) (*TUNDevice, error) {
 	if mtu <= 0 || mtu > 65536 {
 		mtu = 1420
 	}
 
-	config := water.Config{
-		DeviceType: water.TUN,
-	}
-
-	var iface *water.Interface
+	var dev tun.Device
 	var err error
 
 	for attempt := 1; attempt <= maxConfigRetries; attempt++ {
-		iface, err = water.New(config)
+		dev, err = tun.CreateTUN(tunInterfaceName(name), mtu)
 		if err != nil {
 			if attempt < maxConfigRetries {
 				time.Sleep(tunRetryDelay)
@@ -47,26 +44,39 @@ func CreateTUN(name string, mtu int) (*TUNDevice, error) {
 		break
 	}
 
-	actualName := iface.Name()
-	if actualName == "" {
-		iface.Close()
-		return nil, fmt.Errorf("failed to get interface name")
+	actualName, err := dev.Name()
+	if err != nil || actualName == "" {
+		dev.Close()
+		return nil, fmt.Errorf("failed to get interface name: %w", err)
 	}
 
 	device := &TUNDevice{
-		iface: iface,
-		name:  actualName,
-		mtu:   mtu,
+		dev:  dev,
+		name: actualName,
+		mtu:  mtu,
 	}
 
 	if err := device.configure(); err != nil {
-		iface.Close()
+		dev.Close()
 		return nil, fmt.Errorf("configuring TUN device: %w", err)
 	}
 
 	return device, nil
 }
 
+// MARK: tunInterfaceName
+// Returns the interface name to request for a tunnel, leaving it to the system when the tunnel name is not a valid interface name
+func tunInterfaceName(name string) string {
+	if runtime.GOOS == "darwin" {
+		return "utun"
+	}
+
+	if len(name) > maxInterfaceNameLen || name == "." || name == ".." || strings.ContainsAny(name, "/: \t\n") {
+		return ""
+	}
+	return name
+}
+
 // Platform-specific configuration functions
 
 // MARK: configure
@@ -317,6 +327,7 @@ func (t *TUNDevice) addRouteLinux(destination string) error {
 	route := &netlink.Route{
 		LinkIndex: link.Attrs().Index,
 		Dst:       destNet,
+		Table:     t.table,
 	}
 
 	if err := netlink.RouteAdd(route); err != nil {
@@ -372,6 +383,7 @@ func (t *TUNDevice) removeRouteLinux(destination string) error {
 	route := &netlink.Route{
 		LinkIndex: link.Attrs().Index,
 		Dst:       destNet,
+		Table:     t.table,
 	}
 
 	if err := netlink.RouteDel(route); err != nil {
@@ -384,6 +396,12 @@ func (t *TUNDevice) removeRouteLinux(destination string) error {
 	return nil
 }
 
+// MARK: SetRouteTable
+// Sets the routing table used for routes added through this interface, zero meaning the main table
+func (t *TUNDevice) SetRouteTable(table int) {
+	t.table = table
+}
+
 // Device property accessor functions
 
 // MARK: Name
@@ -404,21 +422,21 @@ func (t *TUNDevice) MTU() int {
 	return t.mtu
 }
 
-// MARK: File
-// Returns the underlying water interface for direct access
-func (t *TUNDevice) File() *water.Interface {
+// MARK: Device
+// Returns the underlying TUN device for the WireGuard data path
+func (t *TUNDevice) Device() tun.Device {
 	if t == nil {
 		return nil
 	}
-	return t.iface
+	return t.dev
 }
 
 // MARK: Close
 // Safely closes the TUN device and cleans up resources
 func (t *TUNDevice) Close() error {
-	if t == nil || t.iface == nil {
+	if t == nil || t.dev == nil {
 		return nil
 	}
 
-	return t.iface.Close()
+	return t.dev.Close()
 }
@@ -1,43 +1,59 @@
 package wireguard
 
 import (
+	"bufio"
 	"context"
+	"io"
 	"net"
+	"net/netip"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
 	"github.com/JPKribs/FinGuard/config"
 	"github.com/JPKribs/FinGuard/internal"
-	"github.com/songgao/water"
 	"golang.zx2c4.com/wireguard/device"
 	"golang.zx2c4.com/wireguard/tun"
+	"golang.zx2c4.com/wireguard/tun/netstack"
+	"golang.zx2c4.com/wireguard/wgctrl"
 )
 
 // MARK: Manager
 type Manager struct {
-	logger        *internal.Logger
-	tunnels       map[string]TunnelInterface
-	mode          TunnelMode
-	paths         config.WireGuardPaths
-	resolver      *AsyncResolver
-	mu            sync.RWMutex
-	running       int64
-	lastError     error
-	retryAttempts int32
-	ctx           context.Context
-	cancel        context.CancelFunc
-	wg            sync.WaitGroup
+	logger       *internal.Logger
+	tunnels      map[string]TunnelInterface
+	mode         TunnelMode
+	paths        config.WireGuardPaths
+	resolver     *AsyncResolver
+	mu           sync.RWMutex
+	running      int64
+	lastError    error
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+	probers      map[string]*tunnelProber
+	probeMu      sync.Mutex
+	onDemand     map[string]*onDemandTunnel
+	onDemandMu   sync.Mutex
+	supervisors  map[string]*supervisor
+	pending      map[string]config.TunnelConfig
+	supervisorMu sync.Mutex
+	peerHandler  PeerChangeHandler
 }
 
 type TunnelMode string
 
+// MARK: PeerChangeHandler
+type PeerChangeHandler func(tunnel string, peers []config.PeerConfig)
+
 // MARK: TunnelInterface
 type TunnelInterface interface {
 	Start(ctx context.Context) error
 	Stop(ctx context.Context) error
 	Update(ctx context.Context, cfg config.TunnelConfig) error
 	Status(ctx context.Context) TunnelStatus
+	IsRunning() bool
 }
 
 // MARK: TunnelManager
@@ -46,29 +62,119 @@ type TunnelManager interface {
 	Stop(ctx context.Context) error
 	CreateTunnel(ctx context.Context, cfg config.TunnelConfig) error
 	UpdateTunnel(ctx context.Context, cfg config.TunnelConfig) error
+	StopTunnel(ctx context.Context, name string) error
 	DeleteTunnel(ctx context.Context, name string) error
 	Status(ctx context.Context, name string) (TunnelStatus, error)
 	ListTunnels(ctx context.Context) ([]TunnelStatus, error)
 	IsReady() bool
 	Recover(ctx context.Context) error
+	DialContext(ctx context.Context, tunnelName, network, address string) (net.Conn, error)
+	TunnelRunning(name string) bool
+	ApplyKillSwitch(cfg config.TunnelConfig) error
+	AcquireTunnel(ctx context.Context, cfg config.TunnelConfig) (func(), error)
+	CaptureTunnel(ctx context.Context, name string, opts CaptureOptions, w io.Writer) error
+	SetPeerChangeHandler(handler PeerChangeHandler)
+}
+
+// MARK: TunnelDialer
+type TunnelDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// MARK: TunnelPinger
+type TunnelPinger interface {
+	DialPing(target netip.Addr) (net.PacketConn, error)
 }
 
 // MARK: TunnelStatus
 type TunnelStatus struct {
-	Name      string   `json:"name"`
-	State     string   `json:"state"`
-	Interface string   `json:"interface"`
-	MTU       int      `json:"mtu"`
-	Peers     int      `json:"peers"`
-	Routes    []string `json:"routes,omitempty"`
-	Error     string   `json:"error,omitempty"`
+	Name       string            `json:"name"`
+	State      string            `json:"state"`
+	Interface  string            `json:"interface"`
+	PublicKey  string            `json:"public_key,omitempty"`
+	ServerMode bool              `json:"server_mode,omitempty"`
+	MTU        int               `json:"mtu"`
+	Peers      int               `json:"peers"`
+	PeerStats  []PeerStatus      `json:"peer_stats,omitempty"`
+	Reachable  *bool             `json:"reachable,omitempty"`
+	Probes     []ProbeStatus     `json:"probes,omitempty"`
+	ACL        *ACLStatus        `json:"acl,omitempty"`
+	Routes     []string          `json:"routes,omitempty"`
+	Supervisor *SupervisorStatus `json:"supervisor,omitempty"`
+	UAPIError  string            `json:"uapi_error,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// MARK: SupervisorState
+type SupervisorState string
+
+// MARK: SupervisorStatus
+type SupervisorStatus struct {
+	State     SupervisorState `json:"state"`
+	Attempts  int             `json:"attempts"`
+	NextRetry *time.Time      `json:"next_retry,omitempty"`
+	LastError string          `json:"last_error,omitempty"`
+	Since     time.Time       `json:"since"`
+}
+
+// MARK: ProbeStatus
+type ProbeStatus struct {
+	Name                string     `json:"name"`
+	Type                string     `json:"type"`
+	Target              string     `json:"target"`
+	Healthy             bool       `json:"healthy"`
+	LastRun             *time.Time `json:"last_run,omitempty"`
+	LatencyMs           float64    `json:"latency_ms"`
+	AvgLatencyMs        float64    `json:"avg_latency_ms"`
+	LossPercent         float64    `json:"loss_percent"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+// MARK: tunnelProber
+type tunnelProber struct {
+	tunnel   string
+	interval time.Duration
+	probes   []*probeState
+	mu       sync.RWMutex
+	cancel   context.CancelFunc
+}
+
+// MARK: probeState
+type probeState struct {
+	config    config.ProbeConfig
+	threshold int
+	samples   []probeSample
+	status    ProbeStatus
+}
+
+// MARK: probeSample
+type probeSample struct {
+	ok      bool
+	latency time.Duration
+}
+
+// MARK: PeerStatus
+type PeerStatus struct {
+	Name                string            `json:"name"`
+	PublicKey           string            `json:"public_key"`
+	Endpoint            string            `json:"endpoint,omitempty"`
+	AllowedIPs          []string          `json:"allowed_ips,omitempty"`
+	LastHandshake       *time.Time        `json:"last_handshake,omitempty"`
+	RxBytes             int64             `json:"rx_bytes"`
+	TxBytes             int64             `json:"tx_bytes"`
+	PersistentKeepalive int               `json:"persistent_keepalive"`
+	ReconnectAttempts   int               `json:"reconnect_attempts"`
+	Health              string            `json:"health"`
+	Supervisor          *SupervisorStatus `json:"supervisor,omitempty"`
 }
 
 // MARK: TUNDevice
 type TUNDevice struct {
-	iface *water.Interface
+	dev   tun.Device
 	name  string
 	mtu   int
+	table int
 }
 
 // MARK: Tunnel
@@ -84,24 +190,75 @@ type Tunnel struct {
 	stopMonitoring   chan struct{}
 	monitoringActive int64
 	lastError        error
-	reconnectCount   map[string]int
+	peerSupervisors  map[string]*supervisor
+	reconnectMu      sync.Mutex
 	endpointCache    map[string]string
 	bufferPool       *PacketBufferPool
+	stackNet         *netstack.Net
+	stackOnly        bool
+	uapi             net.Listener
+	uapiError        error
+	capture          *captureDevice
+	filter           *filterDevice
+	peerClock        peerClock
+	peersChanged     PeerChangeHandler
 }
 
 type WgQuickTunnel struct {
-	name           string
-	config         config.TunnelConfig
-	paths          config.WireGuardPaths
-	logger         *internal.Logger
-	resolver       *AsyncResolver
-	running        int64
-	lastError      error
-	mu             sync.RWMutex
-	configPath     string
-	stopMonitoring chan struct{}
-	reconnectCount map[string]int
-	endpointCache  map[string]string
+	name            string
+	config          config.TunnelConfig
+	paths           config.WireGuardPaths
+	logger          *internal.Logger
+	resolver        *AsyncResolver
+	running         int64
+	lastError       error
+	mu              sync.RWMutex
+	configPath      string
+	stopMonitoring  chan struct{}
+	peerSupervisors map[string]*supervisor
+	endpointCache   map[string]string
+	peerClock       peerClock
+}
+
+// MARK: KernelTunnel
+type KernelTunnel struct {
+	name            string
+	config          config.TunnelConfig
+	logger          *internal.Logger
+	resolver        *AsyncResolver
+	client          *wgctrl.Client
+	running         int64
+	lastError       error
+	mu              sync.RWMutex
+	cancelMonitor   context.CancelFunc
+	peerSupervisors map[string]*supervisor
+	peerClock       peerClock
+}
+
+// MARK: peerClock
+type peerClock struct {
+	mu    sync.Mutex
+	since map[string]time.Time
+}
+
+// MARK: supervisor
+type supervisor struct {
+	mu          sync.Mutex
+	state       SupervisorState
+	attempts    int
+	budget      int
+	nextRetry   time.Time
+	lastFailure time.Time
+	lastError   string
+	since       time.Time
+}
+
+// MARK: onDemandTunnel
+type onDemandTunnel struct {
+	mu          sync.Mutex
+	idleTimeout int64
+	active      int64
+	lastActive  int64
 }
 
 // MARK: AsyncResolver
@@ -158,16 +315,143 @@ type PacketBufferPool struct {
 	max  int32
 }
 
-// MARK: TUNWrapper
-type TUNWrapper struct {
-	iface      *water.Interface
+// MARK: TUNMux
+type TUNMux struct {
+	primary    tun.Device
+	stack      tun.Device
 	mtu        int
-	name       string
+	packets    chan *PacketBuffer
 	events     chan tun.Event
-	closed     int64
+	flows      sync.Map
 	bufferPool *PacketBufferPool
-	batchSize  int
+	closed     int64
+	done       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// MARK: packetHeader
+type packetHeader struct {
+	src       netip.Addr
+	dst       netip.Addr
+	proto     byte
+	srcPort   uint16
+	dstPort   uint16
+	transport []byte
+	fragment  bool
+	opaque    bool
+}
+
+// MARK: packetFlow
+type packetFlow struct {
+	proto      byte
+	local      netip.Addr
+	remote     netip.Addr
+	localPort  uint16
+	remotePort uint16
+}
+
+// MARK: CaptureDirection
+type CaptureDirection int
+
+const (
+	CaptureOutbound CaptureDirection = iota
+	CaptureInbound
+)
+
+// MARK: CaptureOptions
+type CaptureOptions struct {
+	Duration   time.Duration
+	MaxPackets int
+	Host       netip.Addr
+	Port       uint16
+}
+
+// MARK: TunnelCapturer
+type TunnelCapturer interface {
+	Capture(ctx context.Context, opts CaptureOptions, w io.Writer) error
+}
+
+// MARK: captureDevice
+type captureDevice struct {
+	tun.Device
+	active atomic.Pointer[packetCapture]
+}
+
+// MARK: packetCapture
+type packetCapture struct {
+	options CaptureOptions
+	packets chan capturedPacket
+	matched atomic.Int64
+	dropped atomic.Uint64
+	full    chan struct{}
+	once    sync.Once
+}
+
+// MARK: capturedPacket
+type capturedPacket struct {
+	data      []byte
+	length    int
+	direction CaptureDirection
+	timestamp time.Time
+}
+
+// MARK: filterDevice
+type filterDevice struct {
+	tun.Device
+	acl       atomic.Pointer[aclFilter]
+	flows     sync.Map
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// MARK: aclFilter
+type aclFilter struct {
+	rules        []*aclRule
+	peers        []aclPeerPrefix
+	defaultAllow bool
+	defaultHits  atomic.Uint64
+	established  atomic.Uint64
+}
+
+// MARK: aclRule
+type aclRule struct {
+	config   config.ACLRule
+	allow    bool
+	dest     netip.Prefix
+	proto    string
+	portLow  uint16
+	portHigh uint16
+	hits     atomic.Uint64
+}
+
+// MARK: aclPeerPrefix
+type aclPeerPrefix struct {
+	prefix    netip.Prefix
+	name      string
+	publicKey string
+}
+
+// MARK: ACLStatus
+type ACLStatus struct {
+	Default     string          `json:"default"`
+	DefaultHits uint64          `json:"default_hits"`
+	Established uint64          `json:"established"`
+	Rules       []ACLRuleStatus `json:"rules"`
+}
+
+// MARK: ACLRuleStatus
+type ACLRuleStatus struct {
+	Name        string `json:"name,omitempty"`
+	Action      string `json:"action"`
+	Peer        string `json:"peer,omitempty"`
+	Destination string `json:"destination,omitempty"`
+	Protocol    string `json:"protocol"`
+	Port        string `json:"port,omitempty"`
+	Hits        uint64 `json:"hits"`
+}
 
-	cancel context.CancelFunc
-	wg     sync.WaitGroup
+// MARK: pcapngWriter
+type pcapngWriter struct {
+	out  *bufio.Writer
+	dest io.Writer
 }
@@ -0,0 +1,231 @@
+package wireguard
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/JPKribs/FinGuard/config"
+	"golang.zx2c4.com/wireguard/device"
+)
+
+const externalPeerPrefix = "wg-"
+
+var errUAPIUnsupported = errors.New("UAPI sockets are not supported on this platform")
+
+// UAPI socket functions
+
+// MARK: startUAPI
+// Opens the standard UAPI socket for the device so `wg show` and `wg set` can manage it
+func (t *Tunnel) startUAPI() error {
+	listener, err := openUAPI(t.tunDev.Name())
+	if err != nil {
+		return err
+	}
+
+	t.uapi = listener
+	go t.serveUAPI(listener, t.device)
+
+	t.logger.Info("UAPI socket listening", "name", t.name, "path", listener.Addr().String())
+	return nil
+}
+
+// MARK: serveUAPI
+// Hands each connection on the UAPI socket to the device until the listener is closed
+func (t *Tunnel) serveUAPI(listener net.Listener, dev *device.Device) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go dev.IpcHandle(conn)
+	}
+}
+
+// MARK: stopUAPI
+// Closes the UAPI listener and removes its socket file
+func (t *Tunnel) stopUAPI() {
+	if t.uapi == nil {
+		return
+	}
+
+	socketPath := t.uapi.Addr().String()
+	if err := t.uapi.Close(); err != nil {
+		t.logger.Debug("Failed to close UAPI listener", "name", t.name, "error", err)
+	}
+	t.uapi = nil
+
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		t.logger.Warn("Failed to remove UAPI socket", "name", t.name, "path", socketPath, "error", err)
+	}
+}
+
+// Out-of-band change reconciliation functions
+
+// MARK: reconcileDevicePeers
+// Folds peers added, removed or changed through the UAPI socket into the tunnel's view of its peers
+func (t *Tunnel) reconcileDevicePeers(lastHandshakes map[string]time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.device == nil || t.uapi == nil {
+		return
+	}
+
+	var statusBuf strings.Builder
+	if err := t.device.IpcGetOperation(&statusBuf); err != nil {
+		t.logger.Debug("Failed to read device peers for reconciliation", "name", t.name, "error", err)
+		return
+	}
+
+	live := parseUAPIPeerStats(statusBuf.String())
+	peers, changes := reconcilePeers(t.config.Peers, live)
+	if len(changes) == 0 {
+		return
+	}
+
+	for _, change := range changes {
+		t.logger.Info("Reconciled out-of-band peer change", "tunnel", t.name, "change", change)
+	}
+	t.config.Peers = peers
+	t.applyACL()
+
+	// Saved asynchronously so the handler never runs under the tunnel's locks
+	if t.peersChanged != nil {
+		go t.peersChanged(t.name, slices.Clone(peers))
+	}
+
+	// Monitor state is keyed by hex public key, so drop entries for peers that no longer exist
+	current := make(map[string]bool, len(peers))
+	for _, peer := range peers {
+		if publicKeyHex, err := t.base64ToHex(peer.PublicKey); err == nil {
+			current[publicKeyHex] = true
+		}
+	}
+
+	for peerKey := range lastHandshakes {
+		if !current[peerKey] {
+			delete(lastHandshakes, peerKey)
+		}
+	}
+
+	t.reconnectMu.Lock()
+	for peerKey := range t.peerSupervisors {
+		if !current[peerKey] {
+			delete(t.peerSupervisors, peerKey)
+		}
+	}
+	t.reconnectMu.Unlock()
+}
+
+// MARK: reconcilePeers
+// Returns the configured peers updated to match the device, describing each difference found
+func reconcilePeers(configured []config.PeerConfig, live map[string]PeerStatus) ([]config.PeerConfig, []string) {
+	peers := make([]config.PeerConfig, 0, len(live))
+	var changes []string
+	known := make(map[string]bool, len(configured))
+
+	for _, peer := range configured {
+		key := strings.TrimSpace(peer.PublicKey)
+		known[key] = true
+
+		status, exists := live[key]
+		if !exists {
+			changes = append(changes, fmt.Sprintf("peer %s removed", peer.Name))
+			continue
+		}
+
+		if !sameAllowedIPs(peer.AllowedIPs, status.AllowedIPs) {
+			peer.AllowedIPs = status.AllowedIPs
+			changes = append(changes, fmt.Sprintf("peer %s allowed IPs changed", peer.Name))
+		}
+
+		if configuredKeepalive(peer) != status.PersistentKeepalive {
+			peer.Persistent = status.PersistentKeepalive > 0
+			peer.PersistentKeepaliveInt = status.PersistentKeepalive
+			changes = append(changes, fmt.Sprintf("peer %s keepalive changed", peer.Name))
+		}
+
+		peers = append(peers, peer)
+	}
+
+	added := make([]string, 0)
+	for key := range live {
+		if !known[key] {
+			added = append(added, key)
+		}
+	}
+	slices.Sort(added)
+
+	for _, key := range added {
+		status := live[key]
+		peer := config.PeerConfig{
+			Name:                   externalPeerName(key),
+			PublicKey:              key,
+			AllowedIPs:             status.AllowedIPs,
+			Endpoint:               status.Endpoint,
+			Persistent:             status.PersistentKeepalive > 0,
+			PersistentKeepaliveInt: status.PersistentKeepalive,
+		}
+		peers = append(peers, peer)
+		changes = append(changes, fmt.Sprintf("peer %s added", peer.Name))
+	}
+
+	return peers, changes
+}
+
+// MARK: configuredKeepalive
+// Returns the keepalive interval the device was given for a peer
+func configuredKeepalive(peer config.PeerConfig) int {
+	if !peer.Persistent && peer.PersistentKeepaliveInt <= 0 {
+		return 0
+	}
+	if peer.PersistentKeepaliveInt <= 0 {
+		return defaultKeepalive
+	}
+	return peer.PersistentKeepaliveInt
+}
+
+// MARK: sameAllowedIPs
+// Compares two allowed IP lists by their canonical prefixes regardless of order
+func sameAllowedIPs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	canonicalA := canonicalPrefixes(a)
+	canonicalB := canonicalPrefixes(b)
+
+	return slices.Equal(canonicalA, canonicalB)
+}
+
+// MARK: canonicalPrefixes
+// Returns prefixes masked the way the device reports them, sorted
+func canonicalPrefixes(prefixes []string) []string {
+	canonical := make([]string, 0, len(prefixes))
+	for _, value := range prefixes {
+		if prefix, err := netip.ParsePrefix(strings.TrimSpace(value)); err == nil {
+			value = prefix.Masked().String()
+		}
+		canonical = append(canonical, value)
+	}
+	slices.Sort(canonical)
+	return canonical
+}
+
+// MARK: externalPeerName
+// Names a peer that was added outside FinGuard after the start of its public key
+func externalPeerName(publicKey string) string {
+	decoded, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(decoded) < 4 {
+		return externalPeerPrefix + publicKey
+	}
+	return externalPeerPrefix + hex.EncodeToString(decoded[:4])
+}
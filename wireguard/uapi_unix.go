@@ -0,0 +1,28 @@
+//go:build !windows
+
+package wireguard
+
+import (
+	"fmt"
+	"net"
+
+	"golang.zx2c4.com/wireguard/ipc"
+)
+
+// MARK: openUAPI
+// Listens on /var/run/wireguard/<iface>.sock, replacing a stale socket left by a previous run
+func openUAPI(iface string) (net.Listener, error) {
+	file, err := ipc.UAPIOpen(iface)
+	if err != nil {
+		return nil, fmt.Errorf("opening UAPI socket (/var/run/wireguard must be writable): %w", err)
+	}
+	// The listener holds its own copy of the descriptor
+	defer file.Close()
+
+	listener, err := ipc.UAPIListen(iface, file)
+	if err != nil {
+		return nil, fmt.Errorf("listening on UAPI socket: %w", err)
+	}
+
+	return listener, nil
+}
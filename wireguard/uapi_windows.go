@@ -0,0 +1,11 @@
+//go:build windows
+
+package wireguard
+
+import "net"
+
+// MARK: openUAPI
+// UAPI sockets are not supported on Windows
+func openUAPI(iface string) (net.Listener, error) {
+	return nil, errUAPIUnsupported
+}
@@ -3,6 +3,7 @@ package wireguard
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net"
 	"strings"
@@ -23,8 +24,8 @@ const (
 	defaultKeepalive       = 25
 	maxReconnectAttempts   = 5
 	deviceStartTimeout     = 30 * time.Second
-	maxBatchSize           = 32
-	bufferPoolSize         = 256
+	bufferPoolSize         = 1024
+	netstackInterfaceName  = "netstack"
 )
 
 // Tunnel creation and lifecycle functions
@@ -45,17 +46,29 @@ func NewTunnel(cfg config.TunnelConfig, logger *internal.Logger, resolver *Async
 	}
 
 	return &Tunnel{
-		name:           cfg.Name,
-		config:         cfg,
-		logger:         logger,
-		resolver:       resolver,
-		stopMonitoring: make(chan struct{}),
-		reconnectCount: make(map[string]int),
-		endpointCache:  make(map[string]string),
-		bufferPool:     NewPacketBufferPool(bufferPoolSize),
+		name:            cfg.Name,
+		config:          cfg,
+		logger:          logger,
+		resolver:        resolver,
+		stopMonitoring:  make(chan struct{}),
+		peerSupervisors: make(map[string]*supervisor),
+		endpointCache:   make(map[string]string),
+		bufferPool:      NewPacketBufferPool(bufferPoolSize),
 	}, nil
 }
 
+// MARK: NewNetstackTunnel
+// Creates a tunnel that runs entirely on an in-process network stack without a TUN device
+func NewNetstackTunnel(cfg config.TunnelConfig, logger *internal.Logger, resolver *AsyncResolver) (*Tunnel, error) {
+	tunnel, err := NewTunnel(cfg, logger, resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	tunnel.stackOnly = true
+	return tunnel, nil
+}
+
 // MARK: Start
 // Starts the tunnel by creating TUN device and WireGuard configuration
 func (t *Tunnel) Start(ctx context.Context) error {
@@ -71,13 +84,19 @@ func (t *Tunnel) Start(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, deviceStartTimeout)
 	defer cancel()
 
-	if err := t.startTUNDevice(); err != nil {
-		return fmt.Errorf("starting TUN device: %w", err)
+	if err := runHooks(ctx, t.logger, t.name, t.name, "PreUp", t.config.PreUp); err != nil {
+		return err
 	}
 
-	if err := t.addAddresses(); err != nil {
-		t.cleanupOnFailure()
-		return fmt.Errorf("adding addresses: %w", err)
+	if !t.stackOnly {
+		if err := t.startTUNDevice(); err != nil {
+			return fmt.Errorf("starting TUN device: %w", err)
+		}
+
+		if err := t.addAddresses(); err != nil {
+			t.cleanupOnFailure()
+			return fmt.Errorf("adding addresses: %w", err)
+		}
 	}
 
 	if err := t.createWireGuardDevice(); err != nil {
@@ -95,12 +114,32 @@ func (t *Tunnel) Start(ctx context.Context) error {
 		return fmt.Errorf("bringing device up: %w", err)
 	}
 
-	if err := t.addRoutes(); err != nil {
+	if !t.stackOnly {
+		t.uapiError = t.startUAPI()
+		if errors.Is(t.uapiError, errUAPIUnsupported) {
+			t.uapiError = nil
+		} else if t.uapiError != nil {
+			// Reported in the tunnel status too, since the tunnel works but wg show and wg set do not
+			t.logger.Error("UAPI socket unavailable, wg tools cannot manage this tunnel", "name", t.name, "error", t.uapiError)
+		}
+	}
+
+	if t.stackOnly {
+		if len(t.config.Routes) > 0 {
+			t.logger.Debug("Skipping host routes in netstack mode", "name", t.name, "routes", len(t.config.Routes))
+		}
+	} else if err := t.addRoutes(); err != nil {
 		t.logger.Error("Failed to add some routes", "name", t.name, "error", err)
 	}
 
+	if err := runHooks(ctx, t.logger, t.name, t.interfaceName(), "PostUp", t.config.PostUp); err != nil {
+		t.cleanupOnFailure()
+		return err
+	}
+
+	t.peerClock.reset()
 	atomic.StoreInt64(&t.running, 1)
-	t.logger.Info("Tunnel started", "name", t.name, "interface", t.tunDev.Name())
+	t.logger.Info("Tunnel started", "name", t.name, "interface", t.interfaceName())
 
 	t.startMonitoring(ctx)
 	return nil
@@ -125,10 +164,20 @@ func (t *Tunnel) Stop(ctx context.Context) error {
 		t.mu.Lock()
 		defer t.mu.Unlock()
 
+		iface := t.interfaceName()
+		if err := runHooks(ctx, t.logger, t.name, iface, "PreDown", t.config.PreDown); err != nil {
+			t.logger.Error("PreDown hook failed", "name", t.name, "error", err)
+		}
+
+		t.stopUAPI()
+
 		if t.device != nil {
 			t.device.Close()
 			t.device = nil
 		}
+		t.stackNet = nil
+		t.capture = nil
+		t.filter = nil
 
 		if t.tunDev != nil {
 			t.cleanupRoutes()
@@ -136,7 +185,12 @@ func (t *Tunnel) Stop(ctx context.Context) error {
 			t.tunDev = nil
 		}
 
+		if err := runHooks(ctx, t.logger, t.name, iface, "PostDown", t.config.PostDown); err != nil {
+			t.logger.Error("PostDown hook failed", "name", t.name, "error", err)
+		}
+
 		t.lastError = nil
+		t.uapiError = nil
 		close(done)
 	}()
 
@@ -164,18 +218,25 @@ func (t *Tunnel) Update(ctx context.Context, cfg config.TunnelConfig) error {
 	t.config = cfg
 
 	if atomic.LoadInt64(&t.running) == 1 {
-		if err := t.applyConfiguration(); err != nil {
+		if err := t.applyUpdate(oldConfig); err != nil {
 			t.config = oldConfig
 			atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&t.lastError)), unsafe.Pointer(&err))
 			return fmt.Errorf("applying updated config: %w", err)
 		}
 
+		t.applyACL()
 		t.logger.Info("Applied configuration update", "name", t.name)
 	}
 
 	return nil
 }
 
+// MARK: IsRunning
+// Reports whether the tunnel is up without querying device statistics
+func (t *Tunnel) IsRunning() bool {
+	return atomic.LoadInt64(&t.running) == 1
+}
+
 // MARK: Status
 // Returns current tunnel status information
 func (t *Tunnel) Status(ctx context.Context) TunnelStatus {
@@ -184,40 +245,93 @@ func (t *Tunnel) Status(ctx context.Context) TunnelStatus {
 		state = "running"
 	}
 
-	ifaceName := ""
-	if t.tunDev != nil {
-		ifaceName = t.tunDev.Name()
+	status := TunnelStatus{
+		Name:       t.name,
+		State:      state,
+		Interface:  t.interfaceName(),
+		MTU:        t.config.MTU,
+		Peers:      len(t.config.Peers),
+		ServerMode: t.config.Server != nil,
 	}
+	status.PublicKey, _ = PublicKey(t.config.PrivateKey)
 
-	status := TunnelStatus{
-		Name:      t.name,
-		State:     state,
-		Interface: ifaceName,
-		MTU:       t.config.MTU,
-		Peers:     len(t.config.Peers),
+	if state == "running" {
+		status.PeerStats = t.peerStatuses()
+		status.ACL = t.aclStatus()
 	}
 
 	if t.lastError != nil {
 		status.Error = t.lastError.Error()
 	}
+	if state == "running" && t.uapiError != nil {
+		status.UAPIError = t.uapiError.Error()
+	}
 
 	return status
 }
 
+// MARK: peerStatuses
+// Reads live per-peer statistics from the WireGuard device
+func (t *Tunnel) peerStatuses() []PeerStatus {
+	t.mu.RLock()
+	dev := t.device
+	cfg := t.config
+	t.mu.RUnlock()
+
+	var live map[string]PeerStatus
+	if dev != nil {
+		var statusBuf strings.Builder
+		if err := dev.IpcGetOperation(&statusBuf); err != nil {
+			t.logger.Debug("Failed to read peer statistics", "name", t.name, "error", err)
+		} else {
+			live = parseUAPIPeerStats(statusBuf.String())
+		}
+	}
+
+	t.reconnectMu.Lock()
+	supervisors := supervisorStatuses(t.peerSupervisors, hexToBase64)
+	t.reconnectMu.Unlock()
+
+	return mergePeerStatuses(cfg.Peers, live, supervisors, &t.peerClock, staleTimeoutFor(cfg))
+}
+
 // Device setup and configuration functions
 
+// MARK: interfaceName
+// Returns the TUN interface name, or the netstack marker for stack-only tunnels
+func (t *Tunnel) interfaceName() string {
+	if t.tunDev != nil {
+		return t.tunDev.Name()
+	}
+	if t.stackOnly && t.stackNet != nil {
+		return netstackInterfaceName
+	}
+	return ""
+}
+
+// MARK: deviceMTU
+// Returns the configured MTU or the WireGuard default
+func (t *Tunnel) deviceMTU() int {
+	if t.config.MTU <= 0 {
+		return 1420
+	}
+	return t.config.MTU
+}
+
 // MARK: startTUNDevice
 // Creates and configures the TUN device
 func (t *Tunnel) startTUNDevice() error {
-	mtu := t.config.MTU
-	if mtu <= 0 {
-		mtu = 1420
+	tunDev, err := CreateTUN(t.name, t.deviceMTU())
+	if err != nil {
+		return fmt.Errorf("creating TUN device: %w", err)
 	}
 
-	tunDev, err := CreateTUN(t.name, mtu)
+	table, _, err := t.config.RouteTable()
 	if err != nil {
-		return fmt.Errorf("creating TUN device: %w", err)
+		tunDev.Close()
+		return err
 	}
+	tunDev.SetRouteTable(table)
 
 	t.tunDev = tunDev
 	return nil
@@ -241,8 +355,13 @@ func (t *Tunnel) addAddresses() error {
 }
 
 // MARK: addRoutes
-// Adds all configured routes through the TUN device
+// Adds all configured routes through the TUN device, unless the tunnel's table is off
 func (t *Tunnel) addRoutes() error {
+	if _, manageRoutes, _ := t.config.RouteTable(); !manageRoutes {
+		t.logger.Debug("Route management disabled by table setting", "name", t.name)
+		return nil
+	}
+
 	var errors []string
 
 	for _, route := range t.config.Routes {
@@ -272,23 +391,30 @@ func (t *Tunnel) cleanupRoutes() {
 }
 
 // MARK: createWireGuardDevice
-// Creates the WireGuard device with TUN wrapper
+// Creates the WireGuard device on the TUN wrapper and network stack, or the stack alone
 func (t *Tunnel) createWireGuardDevice() error {
-	tunWrapper := &TUNWrapper{
-		iface:      t.tunDev.File(),
-		mtu:        t.config.MTU,
-		name:       t.tunDev.Name(),
-		events:     make(chan tun.Event, 1),
-		bufferPool: t.bufferPool,
-		batchSize:  maxBatchSize,
+	stackDev, stackNet, err := t.createNetstack(t.deviceMTU())
+	if err != nil {
+		return fmt.Errorf("creating network stack: %w", err)
 	}
-	tunWrapper.events <- tun.EventUp
+	t.stackNet = stackNet
 
 	logLevel := device.LogLevelError
 	logger := device.NewLogger(logLevel, fmt.Sprintf("[%s] ", t.name))
 
 	bind := conn.NewDefaultBind()
-	t.device = device.NewDevice(tunWrapper, bind, logger)
+
+	var dataPath tun.Device = stackDev
+	if !t.stackOnly {
+		dataPath = NewTUNMux(t.tunDev.Device(), stackDev, t.deviceMTU(), t.bufferPool)
+	}
+
+	// Captures sit outside the ACL so they also show the packets it drops
+	t.filter = newFilterDevice(dataPath)
+	t.filter.SetACL(compileACL(t.config))
+	t.capture = newCaptureDevice(t.filter)
+
+	t.device = device.NewDevice(t.capture, bind, logger)
 
 	return nil
 }
@@ -296,10 +422,15 @@ func (t *Tunnel) createWireGuardDevice() error {
 // MARK: cleanupOnFailure
 // Cleans up resources when startup fails
 func (t *Tunnel) cleanupOnFailure() {
+	t.stopUAPI()
+
 	if t.device != nil {
 		t.device.Close()
 		t.device = nil
 	}
+	t.stackNet = nil
+	t.capture = nil
+	t.filter = nil
 
 	if t.tunDev != nil {
 		t.tunDev.Close()
@@ -370,6 +501,7 @@ func (t *Tunnel) monitorConnections(ctx context.Context) {
 			if atomic.LoadInt64(&t.running) == 0 {
 				return
 			}
+			t.reconcileDevicePeers(lastHandshakes)
 			t.performConnectivityCheck(lastHandshakes, resolvedEndpoints, staleTimeout)
 		}
 	}
@@ -425,7 +557,7 @@ func (t *Tunnel) processHandshakeTime(line, currentPeer, currentEndpoint string,
 			lastHandshakes[currentPeer] = handshakeTime
 			activePeers[currentPeer] = true
 
-			delete(t.reconnectCount, currentPeer)
+			t.resetReconnects(currentPeer)
 
 			t.logger.Debug("Peer active", "tunnel", t.name, "peer", currentPeer[:8]+"...", "last_handshake", handshakeTime.Format(time.RFC3339))
 		}
@@ -437,36 +569,53 @@ func (t *Tunnel) processHandshakeTime(line, currentPeer, currentEndpoint string,
 // MARK: checkStaleConnections
 // Checks for stale connections and triggers reconnection attempts
 func (t *Tunnel) checkStaleConnections(lastHandshakes map[string]time.Time, activePeers map[string]bool, resolvedEndpoints map[string]string, staleTimeout time.Duration) {
-	staleThreshold := time.Now().Add(-staleTimeout)
+	now := time.Now()
+	staleThreshold := now.Add(-staleTimeout)
 
 	for peerKey, lastHandshake := range lastHandshakes {
-		if !activePeers[peerKey] && lastHandshake.Before(staleThreshold) {
-			attempts := t.reconnectCount[peerKey]
-			maxRetries := t.config.ReconnectionRetries
-			if maxRetries <= 0 {
-				maxRetries = maxReconnectAttempts
-			}
+		if activePeers[peerKey] || !lastHandshake.Before(staleThreshold) {
+			continue
+		}
 
-			if attempts < maxRetries {
-				t.logger.Info("Peer connection stale, attempting reconnection",
-					"tunnel", t.name,
-					"peer", peerKey[:8]+"...",
-					"attempt", attempts+1,
-					"max_retries", maxRetries,
-					"last_handshake", lastHandshake.Format(time.RFC3339))
-
-				t.reconnectCount[peerKey] = attempts + 1
-				t.attemptPeerReconnection(peerKey, resolvedEndpoints)
-			} else {
-				t.logger.Error("Peer reconnection failed after maximum retries",
-					"tunnel", t.name,
-					"peer", peerKey[:8]+"...",
-					"attempts", attempts)
-			}
+		sup := t.peerSupervisor(peerKey)
+		if !sup.due(now) {
+			continue
 		}
+
+		delay := sup.failure(now, "handshake stale")
+		t.logger.Info("Peer connection stale, attempting reconnection",
+			"tunnel", t.name,
+			"peer", peerKey[:8]+"...",
+			"attempt", sup.attemptCount(),
+			"state", sup.current(),
+			"next_retry_in", delay,
+			"last_handshake", lastHandshake.Format(time.RFC3339))
+
+		t.attemptPeerReconnection(peerKey, resolvedEndpoints)
 	}
 }
 
+// MARK: peerSupervisor
+// Returns the reconnection supervisor for a peer
+func (t *Tunnel) peerSupervisor(peerKey string) *supervisor {
+	t.reconnectMu.Lock()
+	defer t.reconnectMu.Unlock()
+
+	return peerSupervisor(t.peerSupervisors, peerKey, peerRetryBudget(t.config))
+}
+
+// MARK: recordReconnect
+// Records a reconnection attempt for a peer, pushing back its next retry
+func (t *Tunnel) recordReconnect(peerKey, reason string) {
+	t.peerSupervisor(peerKey).failure(time.Now(), reason)
+}
+
+// MARK: resetReconnects
+// Marks a peer up and clears its backoff once it completes a handshake
+func (t *Tunnel) resetReconnects(peerKey string) {
+	t.peerSupervisor(peerKey).up(time.Now())
+}
+
 // Parsing and utility functions
 
 // MARK: parseTimestamp
@@ -529,7 +678,7 @@ func (t *Tunnel) checkEndpointResolution(peer config.PeerConfig, currentEndpoint
 				"old_endpoint", currentEndpoint,
 				"new_endpoint", result.endpoint)
 
-			t.reconnectCount[peerKey]++
+			t.recordReconnect(peerKey, "endpoint changed")
 			t.updatePeerEndpoint(peer, result.endpoint, resolvedEndpoints)
 		}
 	case <-time.After(10 * time.Second):
@@ -621,6 +770,10 @@ func (t *Tunnel) applyConfiguration() error {
 		uapi += fmt.Sprintf("listen_port=%d\n", t.config.ListenPort)
 	}
 
+	if t.config.FwMark != 0 {
+		uapi += fmt.Sprintf("fwmark=%d\n", t.config.FwMark)
+	}
+
 	for _, peer := range t.config.Peers {
 		peerConfig, err := t.buildPeerConfig(peer)
 		if err != nil {
@@ -632,6 +785,90 @@ func (t *Tunnel) applyConfiguration() error {
 	return t.device.IpcSetOperation(strings.NewReader(uapi))
 }
 
+// MARK: applyUpdate
+// Applies only changed interface settings and peers so unchanged peer sessions are left intact
+func (t *Tunnel) applyUpdate(oldConfig config.TunnelConfig) error {
+	if t.device == nil {
+		return fmt.Errorf("device not initialized")
+	}
+
+	var uapi strings.Builder
+
+	if strings.TrimSpace(t.config.PrivateKey) != strings.TrimSpace(oldConfig.PrivateKey) {
+		privateKeyHex, err := t.base64ToHex(t.config.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("invalid private key format: %w", err)
+		}
+		uapi.WriteString(fmt.Sprintf("private_key=%s\n", privateKeyHex))
+	}
+
+	if t.config.ListenPort != oldConfig.ListenPort {
+		uapi.WriteString(fmt.Sprintf("listen_port=%d\n", t.config.ListenPort))
+	}
+
+	if t.config.FwMark != oldConfig.FwMark {
+		uapi.WriteString(fmt.Sprintf("fwmark=%d\n", t.config.FwMark))
+	}
+
+	oldPeers := make(map[string]config.PeerConfig, len(oldConfig.Peers))
+	for _, peer := range oldConfig.Peers {
+		oldPeers[strings.TrimSpace(peer.PublicKey)] = peer
+	}
+
+	for _, peer := range t.config.Peers {
+		key := strings.TrimSpace(peer.PublicKey)
+		oldPeer, existed := oldPeers[key]
+		delete(oldPeers, key)
+
+		if existed && peerConfigEqual(oldPeer, peer) {
+			continue
+		}
+
+		peerConfig, err := t.buildPeerConfig(peer)
+		if err != nil {
+			return fmt.Errorf("building config for peer %s: %w", peer.Name, err)
+		}
+
+		if existed {
+			peerConfig = t.replacePeerSettings(peer, peerConfig)
+		}
+		uapi.WriteString(peerConfig)
+	}
+
+	for key, peer := range oldPeers {
+		publicKeyHex, err := t.base64ToHex(key)
+		if err != nil {
+			return fmt.Errorf("invalid public key for removed peer %s: %w", peer.Name, err)
+		}
+		uapi.WriteString(fmt.Sprintf("public_key=%s\nremove=true\n", publicKeyHex))
+	}
+
+	if uapi.Len() == 0 {
+		return nil
+	}
+
+	return t.device.IpcSetOperation(strings.NewReader(uapi.String()))
+}
+
+// MARK: replacePeerSettings
+// Rewrites an existing peer's config so allowed IPs, keepalive and preshared key replace the old values
+func (t *Tunnel) replacePeerSettings(peer config.PeerConfig, peerConfig string) string {
+	header, body, _ := strings.Cut(peerConfig, "\n")
+
+	var settings strings.Builder
+	settings.WriteString(header + "\nreplace_allowed_ips=true\n")
+
+	if peer.Preshared == "" {
+		settings.WriteString(fmt.Sprintf("preshared_key=%x\n", make([]byte, 32)))
+	}
+	if !peer.Persistent && peer.PersistentKeepaliveInt <= 0 {
+		settings.WriteString("persistent_keepalive_interval=0\n")
+	}
+
+	settings.WriteString(body)
+	return settings.String()
+}
+
 // MARK: buildPeerConfig
 // Builds WireGuard configuration string for a single peer
 func (t *Tunnel) buildPeerConfig(peer config.PeerConfig) (string, error) {
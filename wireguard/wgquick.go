@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -16,7 +17,8 @@ import (
 )
 
 const (
-	wgQuickConfigDir = "/tmp"
+	wgQuickConfigMode = 0600
+	wgQuickStateMode  = 0700
 )
 
 // MARK: NewWgQuickTunnel
@@ -37,18 +39,23 @@ func NewWgQuickTunnel(cfg config.TunnelConfig, paths config.WireGuardPaths, logg
 		resolver = NewAsyncResolver()
 	}
 
-	configPath := filepath.Join(wgQuickConfigDir, cfg.Name+".conf")
+	stateDir := paths.StateDir
+	if stateDir == "" {
+		stateDir = config.DefaultStateDir
+	}
+
+	configPath := filepath.Join(stateDir, cfg.Name+".conf")
 
 	return &WgQuickTunnel{
-		name:           cfg.Name,
-		config:         cfg,
-		paths:          paths,
-		logger:         logger,
-		resolver:       resolver,
-		configPath:     configPath,
-		stopMonitoring: make(chan struct{}),
-		reconnectCount: make(map[string]int),
-		endpointCache:  make(map[string]string),
+		name:            cfg.Name,
+		config:          cfg,
+		paths:           paths,
+		logger:          logger,
+		resolver:        resolver,
+		configPath:      configPath,
+		stopMonitoring:  make(chan struct{}),
+		peerSupervisors: make(map[string]*supervisor),
+		endpointCache:   make(map[string]string),
 	}, nil
 }
 
@@ -86,7 +93,7 @@ func (wq *WgQuickTunnel) Start(ctx context.Context) error {
 		return fmt.Errorf("ensuring config directory: %w", err)
 	}
 
-	if err := wq.generateConfig(); err != nil {
+	if _, err := wq.generateConfig(); err != nil {
 		return fmt.Errorf("generating config: %w", err)
 	}
 
@@ -95,6 +102,7 @@ func (wq *WgQuickTunnel) Start(ctx context.Context) error {
 		return fmt.Errorf("starting with wg-quick: %w", err)
 	}
 
+	wq.peerClock.reset()
 	atomic.StoreInt64(&wq.running, 1)
 	wq.logger.Info("WG-Quick tunnel started", "name", wq.name, "config", wq.configPath)
 
@@ -104,27 +112,68 @@ func (wq *WgQuickTunnel) Start(ctx context.Context) error {
 
 // MARK: ensureConfigDirectory
 func (wq *WgQuickTunnel) ensureConfigDirectory() error {
-	if err := os.MkdirAll(wgQuickConfigDir, 0755); err != nil {
+	dir := filepath.Dir(wq.configPath)
+
+	if err := os.MkdirAll(dir, wgQuickStateMode); err != nil {
 		return fmt.Errorf("creating wireguard config directory: %w", err)
 	}
+
+	// Tighten permissions on directories created before keys were stored here
+	if err := os.Chmod(dir, wgQuickStateMode); err != nil {
+		return fmt.Errorf("securing wireguard config directory: %w", err)
+	}
+
 	return nil
 }
 
 // MARK: generateConfig
-func (wq *WgQuickTunnel) generateConfig() error {
-	// Check if config file already exists
-	if _, err := os.Stat(wq.configPath); err == nil {
-		wq.logger.Info("Config file already exists", "path", wq.configPath)
-		return nil
-	}
+// Writes the desired config to disk, reporting whether it differed from the existing file
+func (wq *WgQuickTunnel) generateConfig() (bool, error) {
+	desired := wq.buildWgQuickConfig()
 
-	config := wq.buildWgQuickConfig()
+	if existing, err := os.ReadFile(wq.configPath); err == nil && string(existing) == desired {
+		return false, nil
+	}
 
-	if err := os.WriteFile(wq.configPath, []byte(config), 0600); err != nil {
-		return fmt.Errorf("writing config file: %w", err)
+	if err := writePrivateFile(wq.configPath, desired); err != nil {
+		return false, fmt.Errorf("writing config file: %w", err)
 	}
 
 	wq.logger.Info("Generated wg-quick config", "path", wq.configPath)
+	return true, nil
+}
+
+// MARK: writePrivateFile
+// Atomically writes content readable only by the owner
+func writePrivateFile(path, content string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if err := tmp.Chmod(wgQuickConfigMode); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
 	return nil
 }
 
@@ -149,12 +198,41 @@ func (wq *WgQuickTunnel) buildWgQuickConfig() string {
 	}
 	config.WriteString(fmt.Sprintf("MTU = %d\n", mtu))
 
-	if len(wq.config.Routes) > 0 {
+	if len(wq.config.DNS) > 0 {
+		config.WriteString(fmt.Sprintf("DNS = %s\n", strings.Join(wq.config.DNS, ", ")))
+	}
+
+	if wq.config.FwMark != 0 {
+		config.WriteString(fmt.Sprintf("FwMark = 0x%x\n", wq.config.FwMark))
+	}
+
+	if wq.config.SaveConfig {
+		config.WriteString("SaveConfig = true\n")
+	}
+
+	_, manageRoutes, _ := wq.config.RouteTable()
+	routeHooks := manageRoutes && len(wq.config.Routes) > 0
+
+	if wq.config.Table != "" {
+		config.WriteString(fmt.Sprintf("Table = %s\n", wq.config.Table))
+	} else if routeHooks {
 		config.WriteString("Table = auto\n")
+	}
+
+	writeHookLines(&config, "PreUp", wq.config.PreUp)
+
+	if routeHooks {
 		config.WriteString(fmt.Sprintf("PostUp = %s\n", wq.buildPostUpCommands()))
+	}
+	writeHookLines(&config, "PostUp", wq.config.PostUp)
+
+	writeHookLines(&config, "PreDown", wq.config.PreDown)
+	if routeHooks {
 		config.WriteString(fmt.Sprintf("PreDown = %s\n", wq.buildPreDownCommands()))
 	}
 
+	writeHookLines(&config, "PostDown", wq.config.PostDown)
+
 	for _, peer := range wq.config.Peers {
 		config.WriteString("\n[Peer]\n")
 		config.WriteString(fmt.Sprintf("PublicKey = %s\n", peer.PublicKey))
@@ -185,12 +263,19 @@ func (wq *WgQuickTunnel) buildWgQuickConfig() string {
 	return config.String()
 }
 
+// MARK: writeHookLines
+func writeHookLines(config *strings.Builder, key string, commands []string) {
+	for _, command := range commands {
+		config.WriteString(fmt.Sprintf("%s = %s\n", key, command))
+	}
+}
+
 // MARK: buildPostUpCommands
 func (wq *WgQuickTunnel) buildPostUpCommands() string {
 	var commands []string
 
 	for _, route := range wq.config.Routes {
-		cmd := fmt.Sprintf("%s route add %s dev %s", wq.paths.IpTool, route, wq.name)
+		cmd := fmt.Sprintf("%s route add %s dev %s%s", wq.paths.IpTool, route, wq.name, wq.routeTableSuffix())
 		commands = append(commands, cmd)
 	}
 
@@ -202,13 +287,29 @@ func (wq *WgQuickTunnel) buildPreDownCommands() string {
 	var commands []string
 
 	for _, route := range wq.config.Routes {
-		cmd := fmt.Sprintf("%s route del %s dev %s 2>/dev/null || true", wq.paths.IpTool, route, wq.name)
+		cmd := fmt.Sprintf("%s route del %s dev %s%s 2>/dev/null || true", wq.paths.IpTool, route, wq.name, wq.routeTableSuffix())
 		commands = append(commands, cmd)
 	}
 
 	return strings.Join(commands, "; ")
 }
 
+// MARK: routeTableSuffix
+func (wq *WgQuickTunnel) routeTableSuffix() string {
+	if args := wq.routeTableArgs(); len(args) > 0 {
+		return " " + strings.Join(args, " ")
+	}
+	return ""
+}
+
+// MARK: routeTableArgs
+func (wq *WgQuickTunnel) routeTableArgs() []string {
+	if table, _, _ := wq.config.RouteTable(); table > 0 {
+		return []string{"table", fmt.Sprint(table)}
+	}
+	return nil
+}
+
 // MARK: startWithWgQuick
 func (wq *WgQuickTunnel) startWithWgQuick() error {
 	wgQuickPath := wq.paths.WgQuick
@@ -298,16 +399,25 @@ func (wq *WgQuickTunnel) Update(ctx context.Context, cfg config.TunnelConfig) er
 	wq.config = cfg
 
 	if atomic.LoadInt64(&wq.running) == 1 {
-		if err := wq.generateConfig(); err != nil {
+		changed, err := wq.generateConfig()
+		if err != nil {
 			wq.config = oldConfig
 			atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&wq.lastError)), unsafe.Pointer(&err))
 			return fmt.Errorf("generating updated config: %w", err)
 		}
 
-		if err := wq.restartInterface(); err != nil {
+		if !changed {
+			wq.logger.Debug("Configuration unchanged", "name", wq.name)
+			return nil
+		}
+
+		if err := wq.applyUpdate(oldConfig); err != nil {
 			wq.config = oldConfig
+			if _, restoreErr := wq.generateConfig(); restoreErr != nil {
+				wq.logger.Error("Failed to restore previous config", "name", wq.name, "error", restoreErr)
+			}
 			atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&wq.lastError)), unsafe.Pointer(&err))
-			return fmt.Errorf("restarting interface: %w", err)
+			return err
 		}
 
 		wq.logger.Info("Applied configuration update", "name", wq.name)
@@ -316,6 +426,145 @@ func (wq *WgQuickTunnel) Update(ctx context.Context, cfg config.TunnelConfig) er
 	return nil
 }
 
+// MARK: applyUpdate
+// Syncs peer-only changes in place and restarts the interface for everything else
+func (wq *WgQuickTunnel) applyUpdate(oldConfig config.TunnelConfig) error {
+	if wgQuickNeedsRestart(oldConfig, wq.config) {
+		if err := wq.restartInterface(); err != nil {
+			return fmt.Errorf("restarting interface: %w", err)
+		}
+		return nil
+	}
+
+	if err := wq.syncConfig(); err != nil {
+		return fmt.Errorf("syncing config: %w", err)
+	}
+
+	wq.syncRoutes(oldConfig)
+	return nil
+}
+
+// MARK: wgQuickNeedsRestart
+// Reports whether interface-level fields changed that wg syncconf cannot apply. Routes are synced in place.
+func wgQuickNeedsRestart(oldConfig, newConfig config.TunnelConfig) bool {
+	_, oldDefault := allowedIPRoutes(oldConfig)
+	_, newDefault := allowedIPRoutes(newConfig)
+
+	return oldConfig.MTU != newConfig.MTU ||
+		oldConfig.FwMark != newConfig.FwMark ||
+		oldConfig.Table != newConfig.Table ||
+		oldConfig.SaveConfig != newConfig.SaveConfig ||
+		oldDefault != newDefault ||
+		!slices.Equal(oldConfig.Addresses, newConfig.Addresses) ||
+		!slices.Equal(oldConfig.DNS, newConfig.DNS) ||
+		!slices.Equal(oldConfig.PreUp, newConfig.PreUp) ||
+		!slices.Equal(oldConfig.PostUp, newConfig.PostUp) ||
+		!slices.Equal(oldConfig.PreDown, newConfig.PreDown) ||
+		!slices.Equal(oldConfig.PostDown, newConfig.PostDown)
+}
+
+// MARK: syncConfig
+// Applies the current config to the live interface with wg syncconf
+func (wq *WgQuickTunnel) syncConfig() error {
+	syncPath := wq.configPath + ".sync"
+	if err := writePrivateFile(syncPath, stripWgQuickConfig(wq.buildWgQuickConfig())); err != nil {
+		return fmt.Errorf("writing sync config: %w", err)
+	}
+	defer os.Remove(syncPath)
+
+	cmd := exec.Command(wq.paths.WgTool, "syncconf", wq.name, syncPath)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("wg syncconf failed: %w (output: %s)", err, string(output))
+	}
+
+	wq.logger.Info("Synced peer configuration", "name", wq.name, "peers", len(wq.config.Peers))
+	return nil
+}
+
+// MARK: stripWgQuickConfig
+// Removes wg-quick specific interface keys, matching `wg-quick strip`
+func stripWgQuickConfig(content string) string {
+	var stripped strings.Builder
+	inInterface := false
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "[") {
+			inInterface = strings.EqualFold(trimmed, "[Interface]")
+		} else if inInterface && trimmed != "" {
+			key, _, _ := strings.Cut(trimmed, "=")
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "privatekey", "listenport", "fwmark":
+			default:
+				continue
+			}
+		}
+
+		stripped.WriteString(line)
+		stripped.WriteString("\n")
+	}
+
+	return strings.TrimSuffix(stripped.String(), "\n")
+}
+
+// MARK: allowedIPRoutes
+// Returns the peer AllowedIPs that wg-quick routes directly and whether any is a default route
+func allowedIPRoutes(cfg config.TunnelConfig) ([]string, bool) {
+	var routes []string
+	hasDefault := false
+
+	for _, peer := range cfg.Peers {
+		for _, allowedIP := range peer.AllowedIPs {
+			allowedIP = strings.TrimSpace(allowedIP)
+			if strings.HasSuffix(allowedIP, "/0") {
+				hasDefault = true
+				continue
+			}
+			routes = append(routes, allowedIP)
+		}
+	}
+
+	return routes, hasDefault
+}
+
+// MARK: interfaceRoutes
+// Returns every route wg-quick installs for a config: the peer AllowedIPs plus the configured tunnel routes
+func interfaceRoutes(cfg config.TunnelConfig) []string {
+	routes, _ := allowedIPRoutes(cfg)
+	for _, route := range cfg.Routes {
+		if !slices.Contains(routes, route) {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// MARK: syncRoutes
+// Adds and removes AllowedIPs and tunnel routes with ip route, as wg-quick would have installed them at startup
+func (wq *WgQuickTunnel) syncRoutes(oldConfig config.TunnelConfig) {
+	if _, manageRoutes, _ := wq.config.RouteTable(); !manageRoutes {
+		return
+	}
+
+	added, removed := diffStrings(interfaceRoutes(oldConfig), interfaceRoutes(wq.config))
+
+	for _, route := range removed {
+		args := append([]string{"route", "del", route, "dev", wq.name}, wq.routeTableArgs()...)
+		if output, err := exec.Command(wq.paths.IpTool, args...).CombinedOutput(); err != nil {
+			wq.logger.Debug("Failed to remove route", "tunnel", wq.name, "route", route, "error", err, "output", string(output))
+		}
+	}
+
+	for _, route := range added {
+		args := append([]string{"route", "replace", route, "dev", wq.name}, wq.routeTableArgs()...)
+		if output, err := exec.Command(wq.paths.IpTool, args...).CombinedOutput(); err != nil {
+			wq.logger.Warn("Failed to add route", "tunnel", wq.name, "route", route, "error", err, "output", string(output))
+		}
+	}
+}
+
 // MARK: restartInterface
 func (wq *WgQuickTunnel) restartInterface() error {
 	if err := wq.stopWithWgQuick(); err != nil {
@@ -327,6 +576,12 @@ func (wq *WgQuickTunnel) restartInterface() error {
 	return wq.startWithWgQuick()
 }
 
+// MARK: IsRunning
+// Reports whether the tunnel is up without querying device statistics
+func (wq *WgQuickTunnel) IsRunning() bool {
+	return atomic.LoadInt64(&wq.running) == 1
+}
+
 // / MARK: Status
 func (wq *WgQuickTunnel) Status(ctx context.Context) TunnelStatus {
 	state := "stopped"
@@ -335,11 +590,17 @@ func (wq *WgQuickTunnel) Status(ctx context.Context) TunnelStatus {
 	}
 
 	status := TunnelStatus{
-		Name:      wq.name,
-		State:     state,
-		Interface: wq.name,
-		MTU:       wq.config.MTU,
-		Peers:     len(wq.config.Peers),
+		Name:       wq.name,
+		State:      state,
+		Interface:  wq.name,
+		MTU:        wq.config.MTU,
+		Peers:      len(wq.config.Peers),
+		ServerMode: wq.config.Server != nil,
+	}
+	status.PublicKey, _ = PublicKey(wq.config.PrivateKey)
+
+	if state == "running" {
+		status.PeerStats = wq.peerStatuses(ctx)
 	}
 
 	wq.mu.RLock()
@@ -351,6 +612,25 @@ func (wq *WgQuickTunnel) Status(ctx context.Context) TunnelStatus {
 	return status
 }
 
+// MARK: peerStatuses
+// Reads live per-peer statistics from `wg show dump`
+func (wq *WgQuickTunnel) peerStatuses(ctx context.Context) []PeerStatus {
+	wq.mu.RLock()
+	cfg := wq.config
+	supervisors := supervisorStatuses(wq.peerSupervisors, nil)
+	wq.mu.RUnlock()
+
+	var live map[string]PeerStatus
+	cmd := exec.CommandContext(ctx, wq.paths.WgTool, "show", wq.name, "dump")
+	if output, err := cmd.Output(); err != nil {
+		wq.logger.Debug("Failed to read peer statistics", "tunnel", wq.name, "error", err)
+	} else {
+		live = parseWgDump(string(output))
+	}
+
+	return mergePeerStatuses(cfg.Peers, live, supervisors, &wq.peerClock, staleTimeoutFor(cfg))
+}
+
 // MARK: startMonitoring
 func (wq *WgQuickTunnel) startMonitoring(ctx context.Context) {
 	go func() {
@@ -392,9 +672,12 @@ func (wq *WgQuickTunnel) monitorConnection() {
 	}
 
 	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	staleThreshold := time.Now().Add(-2 * time.Minute).Unix()
-	hasStaleConnections := false
+	now := time.Now()
+	staleThreshold := now.Add(-2 * time.Minute).Unix()
+	stalePeers := make(map[string]bool)
 
+	wq.mu.Lock()
+	budget := peerRetryBudget(wq.config)
 	for _, line := range lines {
 		if line == "" {
 			continue
@@ -405,23 +688,32 @@ func (wq *WgQuickTunnel) monitorConnection() {
 			continue
 		}
 
+		sup := peerSupervisor(wq.peerSupervisors, parts[0], budget)
 		lastHandshake := parseHandshakeTime(parts[1])
 		if lastHandshake > 0 && lastHandshake < staleThreshold {
-			hasStaleConnections = true
-			wq.logger.Warn("Stale connection detected", "tunnel", wq.name, "peer", parts[0])
+			if !sup.due(now) {
+				continue
+			}
+			delay := sup.failure(now, "handshake stale")
+			stalePeers[parts[0]] = true
+			wq.logger.Warn("Stale connection detected", "tunnel", wq.name, "peer", parts[0],
+				"attempt", sup.attemptCount(), "state", sup.current(), "next_retry_in", delay)
+		} else if lastHandshake > 0 {
+			sup.up(now)
 		}
 	}
+	wq.mu.Unlock()
 
-	if hasStaleConnections {
+	if len(stalePeers) > 0 {
 		wq.logger.Info("Attempting to refresh stale connections", "tunnel", wq.name)
-		wq.refreshEndpoints()
+		wq.refreshEndpoints(stalePeers)
 	}
 }
 
 // MARK: refreshEndpoints
-func (wq *WgQuickTunnel) refreshEndpoints() {
+func (wq *WgQuickTunnel) refreshEndpoints(publicKeys map[string]bool) {
 	for _, peer := range wq.config.Peers {
-		if peer.Endpoint == "" {
+		if peer.Endpoint == "" || !publicKeys[strings.TrimSpace(peer.PublicKey)] {
 			continue
 		}
 